@@ -21,6 +21,24 @@ type APIv1 struct {
 	Storer clients.Storer
 	Log    *yall.Logger
 	Signer hmac.Signer
+
+	// SecretKeyring encrypts the secrets of clients using
+	// client_secret_jwt. If unset, those clients can't be created.
+	SecretKeyring *clients.SecretKeyring
+
+	// SecretFormat is the format generated secrets are in, making them
+	// identifiable by secret scanners. If unset, the defaults described
+	// on clients.SecretFormat are used.
+	SecretFormat clients.SecretFormat
+
+	// SecretRotationPolicy decides which clients are listed as due for
+	// secret rotation, unless overridden by the request.
+	SecretRotationPolicy clients.SecretRotationPolicy
+
+	// authenticate, if set, is used in place of
+	// Signer.AuthenticateRequest, so tests can sign requests without
+	// depending on the signature format.
+	authenticate func(r *http.Request, contentHash string) error
 }
 
 // VerifyRequest calculates the HMAC signature of `r` and compares it to
@@ -32,7 +50,7 @@ type APIv1 struct {
 // be an authenticated request body.
 func (a APIv1) VerifyRequest(r *http.Request) (string, *Response) {
 	var payload string
-	if r.Method == "POST" || r.Method == "PUT" {
+	if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
 		defer func() {
 			err := r.Body.Close()
 			if err != nil {
@@ -50,7 +68,11 @@ func (a APIv1) VerifyRequest(r *http.Request) (string, *Response) {
 		payload = string(body)
 	}
 	hash := base64.StdEncoding.EncodeToString(sha256.New().Sum([]byte(payload)))
-	err := a.Signer.AuthenticateRequest(r, hash)
+	authenticate := a.Signer.AuthenticateRequest
+	if a.authenticate != nil {
+		authenticate = a.authenticate
+	}
+	err := authenticate(r, hash)
 	if err != nil {
 		a.Log.WithError(err).Debug("failed to authenticate request")
 		return "", &Response{
@@ -69,6 +91,10 @@ func (a APIv1) VerifyRequest(r *http.Request) (string, *Response) {
 type Response struct {
 	Clients      []Client           `json:"clients,omitempty"`
 	RedirectURIs []RedirectURI      `json:"redirectURIs,omitempty"`
+	Scopes       []Scope            `json:"scopes,omitempty"`
+	Secrets      []Secret           `json:"secrets,omitempty"`
+	Keys         []Key              `json:"keys,omitempty"`
 	Errors       []api.RequestError `json:"errors,omitempty"`
+	NextCursor   string             `json:"nextCursor,omitempty"`
 	Status       int                `json:"-"`
 }
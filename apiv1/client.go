@@ -1,40 +1,232 @@
 package apiv1
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	"darlinggo.co/api"
+
 	"lockbox.dev/clients"
 )
 
+// errNoSecretKeyring is returned when a client_secret_jwt client's secret
+// needs to be encrypted, but the APIv1 has no SecretKeyring.
+var errNoSecretKeyring = errors.New("no secret keyring configured")
+
 // Client is an API-specific representation of a client.
 type Client struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Confidential bool      `json:"confidential"`
-	CreatedAt    time.Time `json:"createdAt"`
-	CreatedBy    string    `json:"createdBy"`
-	CreatedByIP  string    `json:"createdByIP"`
-	Secret       string    `json:"secret,omitempty"`
+	ID                      string     `json:"id"`
+	Name                    string     `json:"name"`
+	Confidential            bool       `json:"confidential"`
+	GrantTypes              []string   `json:"grantTypes"`
+	ResponseTypes           []string   `json:"responseTypes"`
+	TokenEndpointAuthMethod string     `json:"tokenEndpointAuthMethod"`
+	JWKSURI                 string     `json:"jwksURI,omitempty"`
+	TLSClientAuthSubjectDN  string     `json:"tlsClientAuthSubjectDN,omitempty"`
+	TLSClientAuthSANDNS     string     `json:"tlsClientAuthSANDNS,omitempty"`
+	TLSClientAuthSANURI     string     `json:"tlsClientAuthSANURI,omitempty"`
+	TLSClientAuthSANIP      string     `json:"tlsClientAuthSANIP,omitempty"`
+	TLSClientAuthSANEmail   string     `json:"tlsClientAuthSANEmail,omitempty"`
+	TLSClientCertThumbprint string     `json:"tlsClientCertThumbprint,omitempty"`
+	SecretRotatedAt         *time.Time `json:"secretRotatedAt,omitempty"`
+	SecretExpiresAt         *time.Time `json:"secretExpiresAt,omitempty"`
+	Status                  string     `json:"status"`
+	StatusReason            string     `json:"statusReason,omitempty"`
+	StatusChangedAt         *time.Time `json:"statusChangedAt,omitempty"`
+	StatusChangedBy         string     `json:"statusChangedBy,omitempty"`
+	ClientURI               string     `json:"clientURI,omitempty"`
+	LogoURI                 string     `json:"logoURI,omitempty"`
+	PolicyURI               string     `json:"policyURI,omitempty"`
+	TOSURI                  string     `json:"tosURI,omitempty"`
+	Contacts                []string   `json:"contacts,omitempty"`
+	SoftwareID              string     `json:"softwareID,omitempty"`
+	SoftwareVersion         string     `json:"softwareVersion,omitempty"`
+	CreatedAt               time.Time  `json:"createdAt"`
+	CreatedBy               string     `json:"createdBy"`
+	CreatedByIP             string     `json:"createdByIP"`
+	Secret                  string     `json:"secret,omitempty"`
+	Version                 int64      `json:"version"`
 }
 
 func coreClient(client Client) clients.Client {
-	return clients.Client{
-		ID:           client.ID,
-		Name:         client.Name,
-		Confidential: client.Confidential,
-		CreatedAt:    client.CreatedAt,
-		CreatedBy:    client.CreatedBy,
-		CreatedByIP:  client.CreatedByIP,
+	res := clients.Client{
+		ID:                      client.ID,
+		Name:                    client.Name,
+		Confidential:            client.Confidential,
+		GrantTypes:              client.GrantTypes,
+		ResponseTypes:           client.ResponseTypes,
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		JWKSURI:                 client.JWKSURI,
+		TLSClientAuthSubjectDN:  client.TLSClientAuthSubjectDN,
+		TLSClientAuthSANDNS:     client.TLSClientAuthSANDNS,
+		TLSClientAuthSANURI:     client.TLSClientAuthSANURI,
+		TLSClientAuthSANIP:      client.TLSClientAuthSANIP,
+		TLSClientAuthSANEmail:   client.TLSClientAuthSANEmail,
+		TLSClientCertThumbprint: client.TLSClientCertThumbprint,
+		Status:                  client.Status,
+		StatusReason:            client.StatusReason,
+		StatusChangedBy:         client.StatusChangedBy,
+		ClientURI:               client.ClientURI,
+		LogoURI:                 client.LogoURI,
+		PolicyURI:               client.PolicyURI,
+		TOSURI:                  client.TOSURI,
+		Contacts:                client.Contacts,
+		SoftwareID:              client.SoftwareID,
+		SoftwareVersion:         client.SoftwareVersion,
+		CreatedAt:               client.CreatedAt,
+		CreatedBy:               client.CreatedBy,
+		CreatedByIP:             client.CreatedByIP,
 	}
+	if client.StatusChangedAt != nil {
+		res.StatusChangedAt = *client.StatusChangedAt
+	}
+	return res
 }
 
 func apiClient(client clients.Client) Client {
-	return Client{
-		ID:           client.ID,
-		Name:         client.Name,
-		Confidential: client.Confidential,
-		CreatedAt:    client.CreatedAt,
-		CreatedBy:    client.CreatedBy,
-		CreatedByIP:  client.CreatedByIP,
+	res := Client{
+		ID:                      client.ID,
+		Name:                    client.Name,
+		Confidential:            client.Confidential,
+		GrantTypes:              client.GrantTypes,
+		ResponseTypes:           client.ResponseTypes,
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		JWKSURI:                 client.JWKSURI,
+		TLSClientAuthSubjectDN:  client.TLSClientAuthSubjectDN,
+		TLSClientAuthSANDNS:     client.TLSClientAuthSANDNS,
+		TLSClientAuthSANURI:     client.TLSClientAuthSANURI,
+		TLSClientAuthSANIP:      client.TLSClientAuthSANIP,
+		TLSClientAuthSANEmail:   client.TLSClientAuthSANEmail,
+		TLSClientCertThumbprint: client.TLSClientCertThumbprint,
+		Status:                  client.Status,
+		StatusReason:            client.StatusReason,
+		StatusChangedBy:         client.StatusChangedBy,
+		ClientURI:               client.ClientURI,
+		LogoURI:                 client.LogoURI,
+		PolicyURI:               client.PolicyURI,
+		TOSURI:                  client.TOSURI,
+		Contacts:                client.Contacts,
+		SoftwareID:              client.SoftwareID,
+		SoftwareVersion:         client.SoftwareVersion,
+		CreatedAt:               client.CreatedAt,
+		CreatedBy:               client.CreatedBy,
+		CreatedByIP:             client.CreatedByIP,
+		Version:                 client.Version,
+	}
+	if res.Status == "" {
+		res.Status = clients.StatusActive
+	}
+	if !client.StatusChangedAt.IsZero() {
+		changedAt := client.StatusChangedAt
+		res.StatusChangedAt = &changedAt
+	}
+	if !client.SecretRotatedAt.IsZero() {
+		rotatedAt := client.SecretRotatedAt
+		res.SecretRotatedAt = &rotatedAt
+	}
+	if !client.SecretExpiresAt.IsZero() {
+		expiresAt := client.SecretExpiresAt
+		res.SecretExpiresAt = &expiresAt
+	}
+	return res
+}
+
+// grantTypeRequestError converts a clients.GrantTypeError into an
+// api.RequestError pointing at the offending grant type or response type.
+func grantTypeRequestError(client clients.Client, err clients.GrantTypeError) api.RequestError {
+	if err.GrantType != "" {
+		for pos, grantType := range client.GrantTypes {
+			if grantType == err.GrantType {
+				return api.RequestError{Field: fmt.Sprintf("/grantTypes/%d", pos), Slug: api.RequestErrInvalidValue}
+			}
+		}
+	}
+	if err.ResponseType != "" {
+		for pos, responseType := range client.ResponseTypes {
+			if responseType == err.ResponseType {
+				return api.RequestError{Field: fmt.Sprintf("/responseTypes/%d", pos), Slug: api.RequestErrInvalidValue}
+			}
+		}
+	}
+	return api.RequestError{Field: "/grantTypes", Slug: api.RequestErrInvalidValue}
+}
+
+// metadataRequestError converts a clients.MetadataError into an
+// api.RequestError pointing at the offending field.
+func metadataRequestError(err clients.MetadataError) api.RequestError {
+	field := map[string]string{
+		"client_uri":                 "/clientURI",
+		"logo_uri":                   "/logoURI",
+		"policy_uri":                 "/policyURI",
+		"tos_uri":                    "/tosURI",
+		"tls_client_auth_subject_dn": "/tlsClientAuthSubjectDN",
+		"tls_client_auth_san_dns":    "/tlsClientAuthSANDNS",
+		"tls_client_auth_san_uri":    "/tlsClientAuthSANURI",
+		"tls_client_auth_san_ip":     "/tlsClientAuthSANIP",
+		"tls_client_auth_san_email":  "/tlsClientAuthSANEmail",
+		"tls_client_cert_thumbprint": "/tlsClientCertThumbprint",
+	}[err.Field]
+	if err.Field == "contacts" {
+		field = fmt.Sprintf("/contacts/%d", err.Index)
+	}
+	return api.RequestError{Field: field, Slug: api.RequestErrInvalidValue}
+}
+
+// clientRequestErrors validates client, returning the api.RequestErrors
+// describing why it's invalid. If it can't be validated, an error is
+// returned.
+func clientRequestErrors(client clients.Client) ([]api.RequestError, error) {
+	err := client.ValidateGrantTypes()
+	if err != nil {
+		var grantErr clients.GrantTypeError
+		if errors.As(err, &grantErr) {
+			return []api.RequestError{grantTypeRequestError(client, grantErr)}, nil
+		}
+		return nil, fmt.Errorf("error validating grant types: %w", err)
+	}
+	err = client.ValidateAuthMethod()
+	if err != nil {
+		var authMethodErr clients.AuthMethodError
+		if errors.As(err, &authMethodErr) {
+			return []api.RequestError{{Field: "/tokenEndpointAuthMethod", Slug: api.RequestErrInvalidValue}}, nil
+		}
+		return nil, fmt.Errorf("error validating token endpoint authentication method: %w", err)
+	}
+	if client.JWKSURI != "" {
+		err = clients.ValidateJWKSURI(client.JWKSURI)
+		if err != nil {
+			return []api.RequestError{{Field: "/jwksURI", Slug: api.RequestErrInvalidValue}}, nil
+		}
+	}
+	err = client.ValidateMetadata()
+	if err != nil {
+		var metadataErr clients.MetadataError
+		if errors.As(err, &metadataErr) {
+			return []api.RequestError{metadataRequestError(metadataErr)}, nil
+		}
+		return nil, fmt.Errorf("error validating client metadata: %w", err)
+	}
+	err = client.ValidateTLSClientAuth()
+	if err != nil {
+		var metadataErr clients.MetadataError
+		if errors.As(err, &metadataErr) {
+			return []api.RequestError{metadataRequestError(metadataErr)}, nil
+		}
+		return nil, fmt.Errorf("error validating TLS client authentication: %w", err)
+	}
+	return nil, nil
+}
+
+// changeSecret returns a clients.Change setting client's secret to secret.
+// The secret is always hashed, and is encrypted using the SecretKeyring for
+// clients using client_secret_jwt.
+func (a APIv1) changeSecret(client clients.Client, secret []byte) (clients.Change, error) {
+	if client.AuthMethod() != clients.AuthMethodClientSecretJWT {
+		return clients.ChangeSecret(secret)
+	}
+	if a.SecretKeyring == nil {
+		return clients.Change{}, errNoSecretKeyring
 	}
+	return a.SecretKeyring.ChangeSecret(client.ID, secret)
 }
@@ -33,13 +33,26 @@ func (a APIv1) Server(baseURL string) http.Handler {
 	router.SetPrefix(baseURL)
 	router.Endpoint("/").Methods("POST").
 		Handler(logEndpoint(http.HandlerFunc(a.handleCreateClient)))
+	router.Endpoint("/").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(a.handleListClients)))
+	router.Endpoint("/secretRotation").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleListClientsDueForRotation)))
 	router.Endpoint("/{id}").Methods("GET").
 		Handler(logEndpoint(http.HandlerFunc(a.handleGetClient)))
+	router.Endpoint("/{id}").Methods("PATCH").
+		Handler(logEndpoint(http.HandlerFunc(a.handleUpdateClient)))
 	router.Endpoint("/{id}").Methods("DELETE").
 		Handler(logEndpoint(http.HandlerFunc(a.handleDeleteClient)))
 	router.Endpoint("/{id}/secret").Methods("POST").
 		Handler(logEndpoint(http.HandlerFunc(
 			a.handleResetClientSecret)))
+	router.Endpoint("/{id}/disable").Methods("POST").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleDisableClient)))
+	router.Endpoint("/{id}/enable").Methods("POST").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleEnableClient)))
 	router.Endpoint("/{id}/redirectURIs").Methods("GET").
 		Handler(logEndpoint(http.HandlerFunc(
 			a.handleListClientRedirectURIs)))
@@ -49,6 +62,33 @@ func (a APIv1) Server(baseURL string) http.Handler {
 	router.Endpoint("/{id}/redirectURIs/{uri}").Methods("DELETE").
 		Handler(logEndpoint(http.HandlerFunc(
 			a.handleDeleteClientRedirectURI)))
+	router.Endpoint("/{id}/scopes").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleListClientScopes)))
+	router.Endpoint("/{id}/scopes").Methods("POST").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleCreateClientScopes)))
+	router.Endpoint("/{id}/scopes/{scope}").Methods("DELETE").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleDeleteClientScope)))
+	router.Endpoint("/{id}/secrets").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleListClientSecrets)))
+	router.Endpoint("/{id}/secrets").Methods("POST").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleCreateClientSecret)))
+	router.Endpoint("/{id}/secrets/{secret}").Methods("DELETE").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleDeleteClientSecret)))
+	router.Endpoint("/{id}/keys").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleListClientKeys)))
+	router.Endpoint("/{id}/keys").Methods("POST").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleCreateClientKeys)))
+	router.Endpoint("/{id}/keys/{kid}").Methods("DELETE").
+		Handler(logEndpoint(http.HandlerFunc(
+			a.handleDeleteClientKey)))
 
 	return api.NegotiateMiddleware(router)
 }
@@ -0,0 +1,80 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"darlinggo.co/api"
+	yall "yall.in"
+
+	"lockbox.dev/clients"
+)
+
+// clientETag returns the entity tag identifying the current version of
+// client.
+func clientETag(client clients.Client) string {
+	return `"` + strconv.FormatInt(client.Version, 10) + `"`
+}
+
+// ifMatch returns true if the request has no If-Match header, or if its
+// If-Match header matches the current version of client. Weak entity tags
+// never match, as RFC 7232 requires strong comparison for If-Match.
+func ifMatch(r *http.Request, client clients.Client) bool {
+	values := r.Header.Values("If-Match")
+	if len(values) < 1 {
+		return true
+	}
+	current := clientETag(client)
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == current {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodePreconditionFailed writes the response for a request whose
+// If-Match header doesn't match the current version of the client.
+func encodePreconditionFailed(w http.ResponseWriter, r *http.Request) {
+	api.Encode(w, r, http.StatusPreconditionFailed, Response{Errors: []api.RequestError{{Header: "If-Match", Slug: api.RequestErrConflict}}})
+}
+
+// encodeVersionConflict writes the response for a request whose update
+// conflicted with another update to the same client. Requests with an
+// If-Match header fail their precondition; others just conflict.
+func encodeVersionConflict(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") != "" {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	api.Encode(w, r, http.StatusConflict, Response{Errors: []api.RequestError{{Field: "/version", Slug: api.RequestErrConflict}}})
+}
+
+// touchClient increments the version of client before one of its redirect
+// URIs, scopes, secrets, or keys is changed, so the change conflicts with
+// any other change based on the same version. It's called before the
+// change is stored, so a conflicting request stores nothing. If touching
+// the client fails, the response is written and false is returned.
+func (a APIv1) touchClient(w http.ResponseWriter, r *http.Request, client clients.Client) (clients.Client, bool) {
+	err := a.Storer.Update(r.Context(), client.ID, client.Version, clients.Change{})
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return client, false
+		}
+		if errors.Is(err, clients.ErrVersionConflict) {
+			encodeVersionConflict(w, r)
+			return client, false
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("Error updating client version")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return client, false
+	}
+	client.Version++
+	return client, true
+}
@@ -2,12 +2,11 @@ package apiv1
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -41,6 +40,11 @@ func (a APIv1) handleCreateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	body.ID = id
+	body.Status = clients.StatusActive
+	body.StatusReason = ""
+	body.StatusChangedAt = nil
+	body.StatusChangedBy = ""
+	body.Secret = ""
 	body.CreatedAt = time.Now()
 	body.CreatedBy = a.Signer.Key
 	body.CreatedByIP = userip.Get(r)
@@ -49,24 +53,52 @@ func (a APIv1) handleCreateClient(w http.ResponseWriter, r *http.Request) {
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	if body.Confidential {
-		secretBytes := make([]byte, 16) //nolint:gomnd // chosen arbitrarily, doesn't matter if it changes
-		_, err = rand.Read(secretBytes)
+	if body.TokenEndpointAuthMethod == "" {
+		body.TokenEndpointAuthMethod = clients.DefaultAuthMethod(body.Confidential)
+	}
+	if body.Confidential && coreClient(body).UsesSecret() {
+		body.Secret, err = a.SecretFormat.Generate()
 		if err != nil {
 			yall.FromContext(r.Context()).WithError(err).Error("Couldn't generate client secret")
 			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 			return
 		}
-		body.Secret = hex.EncodeToString(secretBytes)
+	}
+	if len(body.GrantTypes) < 1 && len(body.ResponseTypes) < 1 {
+		body.GrantTypes, body.ResponseTypes = clients.DefaultGrantTypes()
+	}
+	if body.SecretExpiresAt != nil && (body.Secret == "" || !body.SecretExpiresAt.After(body.CreatedAt)) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/secretExpiresAt", Slug: api.RequestErrInvalidValue}}})
+		return
 	}
 	client := coreClient(body)
-	change, err := clients.ChangeSecret([]byte(body.Secret))
+	// only clients that authenticate with a secret get one, so clients
+	// without a secret never have a hash stored for them
+	if client.UsesSecret() {
+		change, err := a.changeSecret(client, []byte(body.Secret))
+		if errors.Is(err, errNoSecretKeyring) {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/tokenEndpointAuthMethod", Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).Error("Error setting client secret")
+			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+			return
+		}
+		change.SecretRotatedAt = &body.CreatedAt
+		change.SecretExpiresAt = body.SecretExpiresAt
+		client = clients.Apply(change, client)
+	}
+	reqErrs, err := clientRequestErrors(client)
 	if err != nil {
-		yall.FromContext(r.Context()).WithError(err).Error("Error setting client secret")
+		yall.FromContext(r.Context()).WithError(err).Error("Error validating client")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	client = clients.Apply(change, client)
+	if len(reqErrs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: reqErrs})
+		return
+	}
 	err = a.Storer.Create(r.Context(), client)
 	if err != nil {
 		if errors.Is(err, clients.ErrClientAlreadyExists) {
@@ -80,6 +112,7 @@ func (a APIv1) handleCreateClient(w http.ResponseWriter, r *http.Request) {
 	yall.FromContext(r.Context()).WithField("client_id", client.ID).Debug("client created")
 	respClient := apiClient(client)
 	respClient.Secret = body.Secret
+	w.Header().Set("ETag", clientETag(client))
 	api.Encode(w, r, http.StatusCreated, Response{Clients: []Client{respClient}})
 }
 
@@ -105,20 +138,155 @@ func (a APIv1) handleGetClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	yall.FromContext(r.Context()).WithField("client_id", client.ID).Debug("Client retrieved")
+	w.Header().Set("ETag", clientETag(client))
 	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{apiClient(client)}})
 }
 
-func (a APIv1) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
+// listClientsOptions parses the query parameters of a request to list
+// clients into the options to pass to Storer.ListClients.
+func listClientsOptions(query url.Values) (clients.ListClientsOptions, []api.RequestError) {
+	opts := clients.ListClientsOptions{
+		CreatedBy:  query.Get("createdBy"),
+		NamePrefix: query.Get("namePrefix"),
+		Cursor:     query.Get("cursor"),
+	}
+	var errs []api.RequestError
+	if value := query.Get("confidential"); value != "" {
+		confidential, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, api.RequestError{Param: "confidential", Slug: api.RequestErrInvalidFormat})
+		} else {
+			opts.Confidential = &confidential
+		}
+	}
+	for param, dest := range map[string]*time.Time{
+		"createdSince":  &opts.CreatedSince,
+		"createdBefore": &opts.CreatedBefore,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errs = append(errs, api.RequestError{Param: param, Slug: api.RequestErrInvalidFormat})
+			continue
+		}
+		*dest = parsed
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > clients.MaxListClientsLimit {
+			errs = append(errs, api.RequestError{Param: "limit", Slug: api.RequestErrInvalidValue})
+		} else {
+			opts.Limit = limit
+		}
+	}
+	return opts, errs
+}
+
+func (a APIv1) handleListClients(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	opts, errs := listClientsOptions(r.URL.Query())
+	if len(errs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: errs})
+		return
+	}
+	list, cursor, err := a.Storer.ListClients(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, clients.ErrInvalidCursor) {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Param: "cursor", Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error listing clients")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	resp := Response{Clients: make([]Client, 0, len(list)), NextCursor: cursor}
+	for _, client := range list {
+		resp.Clients = append(resp.Clients, apiClient(client))
+	}
+	if cursor != "" {
+		// link to the next page with the same filters
+		query := r.URL.Query()
+		query.Set("cursor", cursor)
+		next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		w.Header().Set("Link", "<"+next.String()+`>; rel="next"`)
+	}
+	yall.FromContext(r.Context()).WithField("count", len(list)).Debug("clients listed")
+	api.Encode(w, r, http.StatusOK, resp)
+}
+
+func (a APIv1) handleListClientsDueForRotation(w http.ResponseWriter, r *http.Request) {
 	if _, resp := a.VerifyRequest(r); resp != nil {
 		api.Encode(w, r, resp.Status, resp)
 		return
 	}
+	// the policy can be overridden per request, in seconds
+	policy := a.SecretRotationPolicy
+	for param, dest := range map[string]*time.Duration{
+		"maxAge":        &policy.MaxAge,
+		"expiresWithin": &policy.ExpiryWindow,
+	} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds < 1 {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Param: param, Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+		*dest = time.Duration(seconds) * time.Second
+	}
+	due, err := policy.DueClients(r.Context(), a.Storer)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing clients due for secret rotation")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	resp := Response{Clients: make([]Client, 0, len(due))}
+	for _, client := range due {
+		resp.Clients = append(resp.Clients, apiClient(client))
+	}
+	yall.FromContext(r.Context()).WithField("count", len(due)).Debug("clients due for secret rotation retrieved")
+	api.Encode(w, r, http.StatusOK, resp)
+}
+
+func (a APIv1) handleUpdateClient(w http.ResponseWriter, r *http.Request) {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
 	vars := trout.RequestVars(r)
 	clientID := vars.Get("id")
 	if clientID == "" {
 		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
 		return
 	}
+	// the body is a JSON Merge Patch, which must be an object to patch a
+	// client's fields
+	var patch map[string]interface{}
+	err := json.Unmarshal([]byte(input), &patch)
+	if err != nil || patch == nil {
+		yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+		return
+	}
+	var reqErrs []api.RequestError
+	for _, field := range immutableClientFields {
+		if _, ok := patch[field]; ok {
+			reqErrs = append(reqErrs, api.RequestError{Field: "/" + field, Slug: api.RequestErrAccessDenied})
+		}
+	}
+	if len(reqErrs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: reqErrs})
+		return
+	}
 	client, err := a.Storer.Get(r.Context(), clientID)
 	if err != nil {
 		if errors.Is(err, clients.ErrClientNotFound) {
@@ -129,40 +297,138 @@ func (a APIv1) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	redirectURIs, err := a.Storer.ListRedirectURIs(r.Context(), clientID)
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	orig := apiClient(client)
+	patched, err := patchClient(orig, patch)
 	if err != nil {
-		yall.FromContext(r.Context()).WithError(err).Error("error listing redirect URIs")
+		// the patch can set fields to values of the wrong type
+		yall.FromContext(r.Context()).WithError(err).Debug("Error applying merge patch")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+		return
+	}
+	change := clientChange(orig, patched)
+	// switching to or from client_secret_jwt means creating or removing
+	// the encrypted copy of the secret, so it can only be done by
+	// resetting the secret
+	if change.TokenEndpointAuthMethod != nil {
+		before, after := client.AuthMethod(), clients.Apply(change, client).AuthMethod()
+		if before != after && (before == clients.AuthMethodClientSecretJWT || after == clients.AuthMethodClientSecretJWT) {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/tokenEndpointAuthMethod", Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+	}
+	if change.IsEmpty() {
+		w.Header().Set("ETag", clientETag(client))
+		api.Encode(w, r, http.StatusOK, Response{Clients: []Client{orig}})
+		return
+	}
+	client = clients.Apply(change, client)
+	reqErrs, err = clientRequestErrors(client)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error validating client")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	if len(redirectURIs) > 0 {
-		ids := make([]string, 0, len(redirectURIs))
-		for _, uri := range redirectURIs {
-			ids = append(ids, uri.ID)
+	if len(reqErrs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: reqErrs})
+		return
+	}
+	err = a.Storer.Update(r.Context(), clientID, client.Version, change)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
 		}
-		err = a.Storer.RemoveRedirectURIs(r.Context(), ids)
-		if err != nil {
-			yall.FromContext(r.Context()).WithError(err).Error("error removing reidrect URIs")
-			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		if errors.Is(err, clients.ErrVersionConflict) {
+			encodeVersionConflict(w, r)
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error updating client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	client.Version++
+	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("client updated")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{apiClient(client)}})
+}
+
+func (a APIv1) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
 			return
 		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	// list what's being deleted to include it in the response; the
+	// storer deletes it all with the client in one step, so anything
+	// added after this is still deleted
+	redirectURIs, err := a.Storer.ListRedirectURIs(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing redirect URIs")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
 	}
-	err = a.Storer.Delete(r.Context(), clientID)
+	scopes, err := a.Storer.ListScopes(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing scopes")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	keys, err := a.Storer.ListKeys(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing keys")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	secrets, err := a.Storer.ListSecrets(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing secrets")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	err = a.Storer.Delete(r.Context(), clientID, client.Version)
 	if err != nil {
 		if errors.Is(err, clients.ErrClientNotFound) {
 			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
 			return
 		}
+		if errors.Is(err, clients.ErrVersionConflict) {
+			encodeVersionConflict(w, r)
+			return
+		}
 		yall.FromContext(r.Context()).WithError(err).Error("error deleting client")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
 	yall.FromContext(r.Context()).WithField("client_id", client.ID).Debug("Client deleted")
-	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{apiClient(client)}, RedirectURIs: apiRedirectURIs(redirectURIs)})
+	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{apiClient(client)}, RedirectURIs: apiRedirectURIs(redirectURIs), Scopes: apiScopes(scopes), Secrets: apiSecrets(secrets), Keys: apiKeys(keys)})
 }
 
 func (a APIv1) handleResetClientSecret(w http.ResponseWriter, r *http.Request) {
-	if _, resp := a.VerifyRequest(r); resp != nil {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
 		api.Encode(w, r, resp.Status, resp)
 		return
 	}
@@ -182,33 +448,134 @@ func (a APIv1) handleResetClientSecret(w http.ResponseWriter, r *http.Request) {
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	secretBytes := make([]byte, 16) //nolint:gomnd // chosen arbitrarily, doesn't matter if it changes
-	_, err = rand.Read(secretBytes)
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	// optionally, the current secret can keep working until a
+	// specified time, so it can be rotated without an outage
+	var body struct {
+		PreviousSecretExpiresAt *time.Time `json:"previousSecretExpiresAt"`
+		SecretExpiresAt         *time.Time `json:"secretExpiresAt"`
+		TokenEndpointAuthMethod *string    `json:"tokenEndpointAuthMethod"`
+	}
+	if input != "" {
+		err = json.Unmarshal([]byte(input), &body)
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+			return
+		}
+	}
+	// the authentication method can be changed along with the secret,
+	// which is the only way to switch to or from client_secret_jwt, as
+	// the encrypted copy of the secret has to be created or removed
+	target := client
+	var methodChange *string
+	if body.TokenEndpointAuthMethod != nil && *body.TokenEndpointAuthMethod != client.AuthMethod() {
+		methodChange = body.TokenEndpointAuthMethod
+		target.TokenEndpointAuthMethod = *methodChange
+		target.EncryptedSecret = ""
+		if !target.UsesSecret() {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/tokenEndpointAuthMethod", Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+	}
+	now := time.Now()
+	// client_secret_jwt clients can only verify assertions using their
+	// current, encrypted secret, so previous secrets can't be kept
+	if body.PreviousSecretExpiresAt != nil && (!body.PreviousSecretExpiresAt.After(now) || target.AuthMethod() == clients.AuthMethodClientSecretJWT) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/previousSecretExpiresAt", Slug: api.RequestErrInvalidValue}}})
+		return
+	}
+	var expiresAt time.Time
+	if body.SecretExpiresAt != nil {
+		if !body.SecretExpiresAt.After(now) {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/secretExpiresAt", Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+		expiresAt = *body.SecretExpiresAt
+	}
+	var previous []clients.Secret
+	if body.PreviousSecretExpiresAt != nil {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).Error("Error creating secret ID")
+			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+			return
+		}
+		previous = append(previous, clients.Secret{
+			ID:          id,
+			ClientID:    clientID,
+			Hash:        client.SecretHash,
+			Scheme:      client.SecretScheme,
+			PepperID:    client.SecretPepperID,
+			Fingerprint: client.SecretFingerprint,
+			CreatedAt:   now,
+			ExpiresAt:   *body.PreviousSecretExpiresAt,
+			CreatedBy:   a.Signer.Key,
+			CreatedByIP: userip.Get(r),
+		})
+	}
+	secret, err := a.SecretFormat.Generate()
 	if err != nil {
 		yall.FromContext(r.Context()).WithError(err).Error("Couldn't generate client secret")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	respClient := apiClient(client)
-	respClient.Secret = hex.EncodeToString(secretBytes)
-	change, err := clients.ChangeSecret([]byte(respClient.Secret))
+	change, err := a.changeSecret(target, []byte(secret))
+	if errors.Is(err, errNoSecretKeyring) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/tokenEndpointAuthMethod", Slug: api.RequestErrInvalidValue}}})
+		return
+	}
 	if err != nil {
 		yall.FromContext(r.Context()).WithError(err).Error("Error setting client secret")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	err = a.Storer.Update(r.Context(), clientID, change)
+	change.TokenEndpointAuthMethod = methodChange
+	// leaving client_secret_jwt, the encrypted copy of the secret isn't
+	// needed anymore, so it shouldn't be kept around
+	if client.EncryptedSecret != "" && change.EncryptedSecret == nil {
+		var noEncryptedSecret string
+		change.EncryptedSecret = &noEncryptedSecret
+	}
+	if err = clients.Apply(change, client).ValidateAuthMethod(); err != nil {
+		var authMethodErr clients.AuthMethodError
+		if errors.As(err, &authMethodErr) {
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/tokenEndpointAuthMethod", Slug: api.RequestErrInvalidValue}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("Error validating token endpoint authentication method")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	change.SecretRotatedAt = &now
+	change.SecretExpiresAt = &expiresAt
+	updated := clients.Apply(change, client)
+	updated.Version++
+	respClient := apiClient(updated)
+	respClient.Secret = secret
+	// the new secret and the previous one are stored together, so a
+	// failed precondition stores neither, and the previous secret is
+	// never lost without the new one being stored
+	err = a.Storer.ResetSecret(r.Context(), clientID, client.Version, change, previous)
 	if err != nil {
 		if errors.Is(err, clients.ErrClientNotFound) {
 			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
 			return
 		}
+		if errors.Is(err, clients.ErrVersionConflict) {
+			encodeVersionConflict(w, r)
+			return
+		}
 		yall.FromContext(r.Context()).WithError(err).Error("Error updating client secret")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
 	yall.FromContext(r.Context()).WithField("client_id", client.ID).Debug("updated client secret")
-	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{respClient}})
+	w.Header().Set("ETag", clientETag(updated))
+	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{respClient}, Secrets: apiSecrets(previous)})
 }
 
 func (a APIv1) handleListClientRedirectURIs(w http.ResponseWriter, r *http.Request) {
@@ -254,7 +621,7 @@ func (a APIv1) handleCreateClientRedirectURIs(w http.ResponseWriter, r *http.Req
 		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
 		return
 	}
-	_, err := a.Storer.Get(r.Context(), clientID)
+	client, err := a.Storer.Get(r.Context(), clientID)
 	if err != nil {
 		if errors.Is(err, clients.ErrClientNotFound) {
 			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
@@ -264,6 +631,10 @@ func (a APIv1) handleCreateClientRedirectURIs(w http.ResponseWriter, r *http.Req
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
 
 	var body struct {
 		RedirectURIs []RedirectURI `json:"redirectURIs"`
@@ -308,8 +679,16 @@ func (a APIv1) handleCreateClientRedirectURIs(w http.ResponseWriter, r *http.Req
 		body.RedirectURIs[pos] = uri
 	}
 	redirectURIs := coreRedirectURIs(body.RedirectURIs)
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
 	err = a.Storer.AddRedirectURIs(r.Context(), redirectURIs)
 	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
 		var uriAlreadyExistsErr clients.RedirectURIAlreadyExistsError
 		if errors.As(err, &uriAlreadyExistsErr) {
 			pos := findRedirectURIErrorPos(r.Context(), redirectURIs, uriAlreadyExistsErr)
@@ -329,6 +708,7 @@ func (a APIv1) handleCreateClientRedirectURIs(w http.ResponseWriter, r *http.Req
 		return
 	}
 	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("redirect URIs added")
+	w.Header().Set("ETag", clientETag(client))
 	api.Encode(w, r, http.StatusCreated, Response{RedirectURIs: apiRedirectURIs(redirectURIs)})
 }
 
@@ -348,7 +728,7 @@ func (a APIv1) handleDeleteClientRedirectURI(w http.ResponseWriter, r *http.Requ
 		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "uri", Slug: api.RequestErrMissing}}})
 		return
 	}
-	_, err := a.Storer.Get(r.Context(), clientID)
+	client, err := a.Storer.Get(r.Context(), clientID)
 	if err != nil {
 		if errors.Is(err, clients.ErrClientNotFound) {
 			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
@@ -358,6 +738,10 @@ func (a APIv1) handleDeleteClientRedirectURI(w http.ResponseWriter, r *http.Requ
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
 	redirectURIs, err := a.Storer.ListRedirectURIs(r.Context(), clientID)
 	if err != nil {
 		yall.FromContext(r.Context()).WithError(err).Error("error listing redirect URIs")
@@ -376,6 +760,10 @@ func (a APIv1) handleDeleteClientRedirectURI(w http.ResponseWriter, r *http.Requ
 		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "uri", Slug: api.RequestErrNotFound}}})
 		return
 	}
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
 	err = a.Storer.RemoveRedirectURIs(r.Context(), []string{redirectURI.ID})
 	if err != nil {
 		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("redirect_uri_id", uriID).WithError(err).Error("error removing redirect URI")
@@ -383,6 +771,7 @@ func (a APIv1) handleDeleteClientRedirectURI(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("redirect_uri_id", uriID).Debug("redirect URI removed")
+	w.Header().Set("ETag", clientETag(client))
 	api.Encode(w, r, http.StatusOK, Response{RedirectURIs: []RedirectURI{redirectURI}})
 }
 
@@ -394,3 +783,705 @@ func findRedirectURIErrorPos(_ context.Context, uris []clients.RedirectURI, uriE
 	}
 	return -1
 }
+
+func (a APIv1) handleListClientScopes(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	_, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	scopes, err := a.Storer.ListScopes(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing scopes")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("scopes retrieved")
+	api.Encode(w, r, http.StatusOK, Response{Scopes: apiScopes(scopes)})
+}
+
+func (a APIv1) handleCreateClientScopes(w http.ResponseWriter, r *http.Request) {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+
+	var body struct {
+		Scopes []Scope `json:"scopes"`
+	}
+	err = json.Unmarshal([]byte(input), &body)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+		return
+	}
+
+	var reqErrs []api.RequestError
+	seen := map[string]struct{}{}
+	for pos, scope := range body.Scopes {
+		if scope.ID == "" {
+			reqErrs = append(reqErrs, api.RequestError{Field: fmt.Sprintf("/scopes/%d/ID", pos), Slug: api.RequestErrMissing})
+			continue
+		}
+		if !clients.IsValidScopeID(scope.ID) {
+			reqErrs = append(reqErrs, api.RequestError{Field: fmt.Sprintf("/scopes/%d/ID", pos), Slug: api.RequestErrInvalidValue})
+			continue
+		}
+		if _, ok := seen[scope.ID]; ok {
+			reqErrs = append(reqErrs, api.RequestError{Field: fmt.Sprintf("/scopes/%d/ID", pos), Slug: api.RequestErrConflict})
+			continue
+		}
+		seen[scope.ID] = struct{}{}
+	}
+	if len(reqErrs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: reqErrs})
+		return
+	}
+	createdAt := time.Now()
+	createdByIP := userip.Get(r)
+	if createdByIP == "" {
+		yall.FromContext(r.Context()).Error("Couldn't determine user's IP")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	for pos, scope := range body.Scopes {
+		scope.ClientID = clientID
+		scope.CreatedAt = createdAt
+		scope.CreatedBy = a.Signer.Key
+		scope.CreatedByIP = createdByIP
+		body.Scopes[pos] = scope
+	}
+	scopes := coreScopes(body.Scopes)
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
+	err = a.Storer.AddScopes(r.Context(), scopes)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		var scopeAlreadyExistsErr clients.ScopeAlreadyExistsError
+		if errors.As(err, &scopeAlreadyExistsErr) {
+			for pos, scope := range scopes {
+				if scope.ID == scopeAlreadyExistsErr.ID {
+					api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/scopes/" + strconv.Itoa(pos) + "/ID", Slug: api.RequestErrConflict}}})
+					return
+				}
+			}
+			yall.FromContext(r.Context()).WithField("err_scope", scopeAlreadyExistsErr.ID).WithField("passed_scopes", scopes).Error("source of ScopeAlreadyExistsError wasn't a passed scope")
+			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("Error creating scopes")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("scopes added")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusCreated, Response{Scopes: apiScopes(scopes)})
+}
+
+func (a APIv1) handleDeleteClientScope(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	scopeID := vars.Get("scope")
+	if scopeID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "scope", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	scopes, err := a.Storer.ListScopes(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing scopes")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	var scope Scope
+	for _, s := range scopes {
+		if s.ID == scopeID {
+			scope = apiScope(s)
+			break
+		}
+	}
+	if scope.ID == "" {
+		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("scope", scopeID).Debug("scope not found in client")
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "scope", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
+	err = a.Storer.RemoveScopes(r.Context(), clientID, []string{scope.ID})
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("scope", scopeID).WithError(err).Error("error removing scope")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("scope", scopeID).Debug("scope removed")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusOK, Response{Scopes: []Scope{scope}})
+}
+
+func (a APIv1) handleListClientSecrets(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	_, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	secrets, err := a.Storer.ListSecrets(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing secrets")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("secrets retrieved")
+	api.Encode(w, r, http.StatusOK, Response{Secrets: apiSecrets(secrets)})
+}
+
+func (a APIv1) handleCreateClientSecret(w http.ResponseWriter, r *http.Request) {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	// additional secrets are only checked by clients that authenticate
+	// with a plain secret; client_secret_jwt clients can only verify
+	// assertions using their current, encrypted secret
+	if !client.Confidential || !client.UsesSecret() || client.AuthMethod() == clients.AuthMethodClientSecretJWT {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrConflict}}})
+		return
+	}
+
+	var body struct {
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if input != "" {
+		err = json.Unmarshal([]byte(input), &body)
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+			return
+		}
+	}
+	now := time.Now()
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(now) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/expiresAt", Slug: api.RequestErrInvalidValue}}})
+		return
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error creating secret ID")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	secret := clients.Secret{
+		ID:          id,
+		ClientID:    clientID,
+		CreatedAt:   now,
+		CreatedBy:   a.Signer.Key,
+		CreatedByIP: userip.Get(r),
+	}
+	if secret.CreatedByIP == "" {
+		yall.FromContext(r.Context()).Error("Couldn't determine user's IP")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if body.ExpiresAt != nil {
+		secret.ExpiresAt = *body.ExpiresAt
+	}
+	value, err := a.SecretFormat.Generate()
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Couldn't generate client secret")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	secret, err = secret.HashSecret([]byte(value))
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error hashing client secret")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
+	err = a.Storer.AddSecrets(r.Context(), []clients.Secret{secret})
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("Error creating secret")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("secret_id", secret.ID).Debug("secret added")
+	respSecret := apiSecret(secret)
+	respSecret.Secret = value
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusCreated, Response{Secrets: []Secret{respSecret}})
+}
+
+func (a APIv1) handleDeleteClientSecret(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	secretID := vars.Get("secret")
+	if secretID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "secret", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	secrets, err := a.Storer.ListSecrets(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing secrets")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	var secret Secret
+	for _, s := range secrets {
+		if s.ID == secretID {
+			secret = apiSecret(s)
+			break
+		}
+	}
+	if secret.ID == "" {
+		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("secret_id", secretID).Debug("secret not found in client")
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "secret", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
+	err = a.Storer.RemoveSecrets(r.Context(), clientID, []string{secret.ID})
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("secret_id", secretID).WithError(err).Error("error removing secret")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("secret_id", secretID).Debug("secret removed")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusOK, Response{Secrets: []Secret{secret}})
+}
+
+func (a APIv1) handleDisableClient(w http.ResponseWriter, r *http.Request) {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	var body struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if input != "" {
+		err := json.Unmarshal([]byte(input), &body)
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+			return
+		}
+	}
+	if body.Status == "" {
+		body.Status = clients.StatusDisabled
+	}
+	if body.Status != clients.StatusDisabled && body.Status != clients.StatusSuspended {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/status", Slug: api.RequestErrInvalidValue}}})
+		return
+	}
+	a.setClientStatus(w, r, body.Status, body.Reason)
+}
+
+func (a APIv1) handleEnableClient(w http.ResponseWriter, r *http.Request) {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if input != "" {
+		err := json.Unmarshal([]byte(input), &body)
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+			return
+		}
+	}
+	a.setClientStatus(w, r, clients.StatusActive, body.Reason)
+}
+
+// setClientStatus updates the status of the client identified in the
+// request's URL and writes the updated client as the response. The request
+// must already have been verified.
+func (a APIv1) setClientStatus(w http.ResponseWriter, r *http.Request, status, reason string) {
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	change, err := clients.ChangeStatus(status, reason, a.Signer.Key, time.Now())
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).WithField("status", status).Error("Error changing client status")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	err = a.Storer.Update(r.Context(), clientID, client.Version, change)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		if errors.Is(err, clients.ErrVersionConflict) {
+			encodeVersionConflict(w, r)
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("Error updating client status")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	client = clients.Apply(change, client)
+	client.Version++
+	yall.FromContext(r.Context()).WithField("client_id", client.ID).WithField("status", status).Debug("updated client status")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusOK, Response{Clients: []Client{apiClient(client)}})
+}
+
+func (a APIv1) handleListClientKeys(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	_, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	keys, err := a.Storer.ListKeys(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing keys")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("keys retrieved")
+	api.Encode(w, r, http.StatusOK, Response{Keys: apiKeys(keys)})
+}
+
+func (a APIv1) handleCreateClientKeys(w http.ResponseWriter, r *http.Request) {
+	input, resp := a.VerifyRequest(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	if !client.Confidential {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrConflict}}})
+		return
+	}
+	// a client's keys either live at its jwks_uri or are registered
+	// here, never both
+	if client.JWKSURI != "" {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/keys", Slug: api.RequestErrConflict}}})
+		return
+	}
+
+	var body struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	err = json.Unmarshal([]byte(input), &body)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+		return
+	}
+
+	var reqErrs []api.RequestError
+	keys := make([]clients.Key, 0, len(body.Keys))
+	seen := map[string]struct{}{}
+	for pos, raw := range body.Keys {
+		key, err := clients.ParseKey(raw)
+		if err != nil {
+			yall.FromContext(r.Context()).WithError(err).WithField("position", pos).Debug("invalid key")
+			reqErrs = append(reqErrs, api.RequestError{Field: fmt.Sprintf("/keys/%d", pos), Slug: api.RequestErrInvalidValue})
+			continue
+		}
+		if _, ok := seen[key.ID]; ok {
+			reqErrs = append(reqErrs, api.RequestError{Field: fmt.Sprintf("/keys/%d/kid", pos), Slug: api.RequestErrConflict})
+			continue
+		}
+		seen[key.ID] = struct{}{}
+		keys = append(keys, key)
+	}
+	if len(reqErrs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: reqErrs})
+		return
+	}
+	createdAt := time.Now()
+	createdByIP := userip.Get(r)
+	if createdByIP == "" {
+		yall.FromContext(r.Context()).Error("Couldn't determine user's IP")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	for pos, key := range keys {
+		key.ClientID = clientID
+		key.CreatedAt = createdAt
+		key.CreatedBy = a.Signer.Key
+		key.CreatedByIP = createdByIP
+		keys[pos] = key
+	}
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
+	err = a.Storer.AddKeys(r.Context(), keys)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		var keyAlreadyExistsErr clients.KeyAlreadyExistsError
+		if errors.As(err, &keyAlreadyExistsErr) {
+			for pos, key := range keys {
+				if key.ID == keyAlreadyExistsErr.ID {
+					api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/keys/" + strconv.Itoa(pos) + "/kid", Slug: api.RequestErrConflict}}})
+					return
+				}
+			}
+			yall.FromContext(r.Context()).WithField("err_kid", keyAlreadyExistsErr.ID).Error("source of KeyAlreadyExistsError wasn't a passed key")
+			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("Error creating keys")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).Debug("keys added")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusCreated, Response{Keys: apiKeys(keys)})
+}
+
+func (a APIv1) handleDeleteClientKey(w http.ResponseWriter, r *http.Request) {
+	if _, resp := a.VerifyRequest(r); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	vars := trout.RequestVars(r)
+	clientID := vars.Get("id")
+	if clientID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrMissing}}})
+		return
+	}
+	keyID := vars.Get("kid")
+	if keyID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "kid", Slug: api.RequestErrMissing}}})
+		return
+	}
+	client, err := a.Storer.Get(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, clients.ErrClientNotFound) {
+			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+			return
+		}
+		yall.FromContext(r.Context()).WithError(err).Error("error retrieving client")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if !ifMatch(r, client) {
+		encodePreconditionFailed(w, r)
+		return
+	}
+	keys, err := a.Storer.ListKeys(r.Context(), clientID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("error listing keys")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	var key Key
+	for _, k := range keys {
+		if k.ID == keyID {
+			key = apiKey(k)
+			break
+		}
+	}
+	if key.ID == "" {
+		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("kid", keyID).Debug("key not found in client")
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "kid", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	client, ok := a.touchClient(w, r, client)
+	if !ok {
+		return
+	}
+	err = a.Storer.RemoveKeys(r.Context(), clientID, []string{key.ID})
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("kid", keyID).WithError(err).Error("error removing key")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("client_id", clientID).WithField("kid", keyID).Debug("key removed")
+	w.Header().Set("ETag", clientETag(client))
+	api.Encode(w, r, http.StatusOK, Response{Keys: []Key{key}})
+}
@@ -0,0 +1,338 @@
+package apiv1
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	yall "yall.in"
+
+	"lockbox.dev/clients"
+	"lockbox.dev/clients/storers/memory"
+	"lockbox.dev/hmac"
+)
+
+// errBadSignature is returned when a test request's signature doesn't
+// match its body.
+var errBadSignature = errors.New("bad signature")
+
+// testAPI returns an APIv1 backed by a new in-memory Storer. Requests to it
+// must be signed with signRequest.
+func testAPI(t *testing.T) (APIv1, *memory.Storer) {
+	t.Helper()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %s", err)
+	}
+	return APIv1{
+		Storer: storer,
+		Log:    yall.FromContext(context.Background()),
+		Signer: hmac.Signer{Key: "test"},
+		authenticate: func(r *http.Request, contentHash string) error {
+			if r.Header.Get("Authorization") != "Test "+contentHash {
+				return errBadSignature
+			}
+			return nil
+		},
+	}, storer
+}
+
+// signRequest signs r as though its body were body, the way the APIv1
+// returned by testAPI expects.
+func signRequest(r *http.Request, body string) {
+	r.Header.Set("Authorization", "Test "+base64.StdEncoding.EncodeToString(sha256.New().Sum([]byte(body))))
+}
+
+// serveRequest sends a request with the passed method, path, and body to
+// api, signed for signedBody, and returns the response. headers are added
+// to the request.
+func serveRequest(t *testing.T, api APIv1, method, path, body, signedBody string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	signRequest(req, signedBody)
+	w := httptest.NewRecorder()
+	api.Server("").ServeHTTP(w, req)
+	return w
+}
+
+// decodeResponseOrFail decodes the Response in w, failing the test if it
+// doesn't have the expected status.
+func decodeResponseOrFail(t *testing.T, w *httptest.ResponseRecorder, status int) Response {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("Expected status %d, got %d: %s", status, w.Code, w.Body.String())
+	}
+	var resp Response
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("Error decoding response %q: %s", w.Body.String(), err)
+	}
+	return resp
+}
+
+// createTestClient stores a confidential client with a secret in storer
+// and returns it.
+func createTestClient(t *testing.T, storer clients.Storer) clients.Client {
+	t.Helper()
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatalf("Error generating client ID: %s", err)
+	}
+	client := clients.Client{
+		ID:           id,
+		Name:         "Test Client",
+		Confidential: true,
+		CreatedAt:    time.Now().Round(time.Millisecond),
+		CreatedBy:    "test",
+		CreatedByIP:  "127.0.0.1",
+	}
+	client.GrantTypes, client.ResponseTypes = clients.DefaultGrantTypes()
+	change, err := clients.ChangeSecret([]byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error generating client secret: %s", err)
+	}
+	client = clients.Apply(change, client)
+	err = storer.Create(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err)
+	}
+	return client
+}
+
+func TestUpdateClientMergePatch(t *testing.T) {
+	t.Parallel()
+
+	api, storer := testAPI(t)
+	client := createTestClient(t, storer)
+	body := `{"name":"Patched Client","contacts":["admin@example.com"]}`
+	w := serveRequest(t, api, http.MethodPatch, "/"+client.ID, body, body, map[string]string{"If-Match": clientETag(client)})
+	resp := decodeResponseOrFail(t, w, http.StatusOK)
+	if len(resp.Clients) != 1 || resp.Clients[0].Name != "Patched Client" {
+		t.Errorf("Expected patched client in response, got %+v", resp.Clients)
+	}
+	stored, err := storer.Get(context.Background(), client.ID)
+	if err != nil {
+		t.Fatalf("Error retrieving client: %s", err)
+	}
+	if stored.Name != "Patched Client" || len(stored.Contacts) != 1 || stored.Contacts[0] != "admin@example.com" {
+		t.Errorf("Expected patch to be stored, got name %q and contacts %v", stored.Name, stored.Contacts)
+	}
+	if etag := w.Header().Get("ETag"); etag != clientETag(stored) {
+		t.Errorf("Expected ETag %s, got %s", clientETag(stored), etag)
+	}
+
+	// the client has changed, so the old ETag is stale
+	w = serveRequest(t, api, http.MethodPatch, "/"+client.ID, body, body, map[string]string{"If-Match": clientETag(client)})
+	decodeResponseOrFail(t, w, http.StatusPreconditionFailed)
+}
+
+func TestUpdateClientAuthenticatesBody(t *testing.T) {
+	t.Parallel()
+
+	api, storer := testAPI(t)
+	client := createTestClient(t, storer)
+	w := serveRequest(t, api, http.MethodPatch, "/"+client.ID, `{"name":"Tampered Client"}`, `{"name":"Patched Client"}`, nil)
+	decodeResponseOrFail(t, w, http.StatusUnauthorized)
+	stored, err := storer.Get(context.Background(), client.ID)
+	if err != nil {
+		t.Fatalf("Error retrieving client: %s", err)
+	}
+	if stored.Name != client.Name {
+		t.Errorf("Expected unauthenticated patch not to be stored, got name %q", stored.Name)
+	}
+}
+
+func TestResetClientSecret(t *testing.T) {
+	t.Parallel()
+
+	api, storer := testAPI(t)
+	client := createTestClient(t, storer)
+	body := `{"previousSecretExpiresAt":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`
+
+	// a stale If-Match stores neither the new secret nor the previous one
+	w := serveRequest(t, api, http.MethodPost, "/"+client.ID+"/secret", body, body, map[string]string{"If-Match": `"12"`})
+	decodeResponseOrFail(t, w, http.StatusPreconditionFailed)
+	secrets, err := storer.ListSecrets(context.Background(), client.ID)
+	if err != nil {
+		t.Fatalf("Error listing secrets: %s", err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("Expected failed reset not to keep the previous secret, got %+v", secrets)
+	}
+
+	w = serveRequest(t, api, http.MethodPost, "/"+client.ID+"/secret", body, body, map[string]string{"If-Match": clientETag(client)})
+	resp := decodeResponseOrFail(t, w, http.StatusOK)
+	if len(resp.Clients) != 1 || resp.Clients[0].Secret == "" {
+		t.Fatalf("Expected the new secret in the response, got %+v", resp.Clients)
+	}
+	stored, err := storer.Get(context.Background(), client.ID)
+	if err != nil {
+		t.Fatalf("Error retrieving client: %s", err)
+	}
+	if err = stored.CheckSecret(resp.Clients[0].Secret); err != nil {
+		t.Errorf("Expected the new secret to be stored, got %v", err)
+	}
+	secrets, err = storer.ListSecrets(context.Background(), client.ID)
+	if err != nil {
+		t.Fatalf("Error listing secrets: %s", err)
+	}
+	if len(secrets) != 1 || secrets[0].Check("test secret") != nil {
+		t.Errorf("Expected the previous secret to be kept, got %+v", secrets)
+	}
+}
+
+// countDependents returns the number of redirect URIs, scopes, secrets,
+// and keys stored in storer for the client with the passed ID.
+func countDependents(t *testing.T, storer clients.Storer, clientID string) int {
+	t.Helper()
+	ctx := context.Background()
+	uris, err := storer.ListRedirectURIs(ctx, clientID)
+	if err != nil {
+		t.Fatalf("Error listing redirect URIs: %s", err)
+	}
+	scopes, err := storer.ListScopes(ctx, clientID)
+	if err != nil {
+		t.Fatalf("Error listing scopes: %s", err)
+	}
+	secrets, err := storer.ListSecrets(ctx, clientID)
+	if err != nil {
+		t.Fatalf("Error listing secrets: %s", err)
+	}
+	keys, err := storer.ListKeys(ctx, clientID)
+	if err != nil {
+		t.Fatalf("Error listing keys: %s", err)
+	}
+	return len(uris) + len(scopes) + len(secrets) + len(keys)
+}
+
+func TestDependentEndpointsCheckIfMatch(t *testing.T) {
+	t.Parallel()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	jwk := `{"kty":"OKP","kid":"test-key","alg":"EdDSA","crv":"Ed25519","x":"` + base64.RawURLEncoding.EncodeToString(pub) + `"}`
+
+	tests := map[string]struct {
+		method, path, body string
+		existing           func(ctx context.Context, storer clients.Storer, clientID string) error
+		status             int
+	}{
+		"addRedirectURIs": {
+			method: http.MethodPost,
+			path:   "/redirectURIs",
+			body:   `{"redirectURIs":[{"URI":"https://client.example.com/callback"}]}`,
+			status: http.StatusCreated,
+		},
+		"removeRedirectURI": {
+			method: http.MethodDelete,
+			path:   "/redirectURIs/test-uri",
+			existing: func(ctx context.Context, storer clients.Storer, clientID string) error {
+				return storer.AddRedirectURIs(ctx, []clients.RedirectURI{{ID: "test-uri", URI: "https://client.example.com/callback", ClientID: clientID, CreatedAt: time.Now()}})
+			},
+			status: http.StatusOK,
+		},
+		"addScopes": {
+			method: http.MethodPost,
+			path:   "/scopes",
+			body:   `{"scopes":[{"ID":"read"}]}`,
+			status: http.StatusCreated,
+		},
+		"removeScope": {
+			method: http.MethodDelete,
+			path:   "/scopes/read",
+			existing: func(ctx context.Context, storer clients.Storer, clientID string) error {
+				return storer.AddScopes(ctx, []clients.Scope{{ID: "read", ClientID: clientID, CreatedAt: time.Now()}})
+			},
+			status: http.StatusOK,
+		},
+		"addSecret": {
+			method: http.MethodPost,
+			path:   "/secrets",
+			body:   `{}`,
+			status: http.StatusCreated,
+		},
+		"removeSecret": {
+			method: http.MethodDelete,
+			path:   "/secrets/test-secret",
+			existing: func(ctx context.Context, storer clients.Storer, clientID string) error {
+				return storer.AddSecrets(ctx, []clients.Secret{{ID: "test-secret", ClientID: clientID, Hash: "test", CreatedAt: time.Now()}})
+			},
+			status: http.StatusOK,
+		},
+		"addKeys": {
+			method: http.MethodPost,
+			path:   "/keys",
+			body:   `{"keys":[` + jwk + `]}`,
+			status: http.StatusCreated,
+		},
+		"removeKey": {
+			method: http.MethodDelete,
+			path:   "/keys/test-key",
+			existing: func(ctx context.Context, storer clients.Storer, clientID string) error {
+				return storer.AddKeys(ctx, []clients.Key{{ID: "test-key", ClientID: clientID, KeyType: "OKP", Algorithm: "EdDSA", JWK: jwk, CreatedAt: time.Now()}})
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			api, storer := testAPI(t)
+			client := createTestClient(t, storer)
+			if test.existing != nil {
+				if err := test.existing(ctx, storer, client.ID); err != nil {
+					t.Fatalf("Error storing existing dependent: %s", err)
+				}
+			}
+			before := countDependents(t, storer, client.ID)
+
+			// a stale If-Match changes nothing
+			w := serveRequest(t, api, test.method, "/"+client.ID+test.path, test.body, test.body, map[string]string{"If-Match": `"12"`})
+			decodeResponseOrFail(t, w, http.StatusPreconditionFailed)
+			if after := countDependents(t, storer, client.ID); after != before {
+				t.Errorf("Expected stale request to change nothing, went from %d to %d dependents", before, after)
+			}
+
+			w = serveRequest(t, api, test.method, "/"+client.ID+test.path, test.body, test.body, map[string]string{"If-Match": clientETag(client)})
+			decodeResponseOrFail(t, w, test.status)
+			if after := countDependents(t, storer, client.ID); after == before {
+				t.Errorf("Expected request to change dependents, still have %d", after)
+			}
+			stored, err := storer.Get(ctx, client.ID)
+			if err != nil {
+				t.Fatalf("Error retrieving client: %s", err)
+			}
+			if stored.Version != client.Version+1 {
+				t.Errorf("Expected version %d, got %d", client.Version+1, stored.Version)
+			}
+			if etag := w.Header().Get("ETag"); etag != clientETag(stored) {
+				t.Errorf("Expected ETag %s, got %s", clientETag(stored), etag)
+			}
+
+			// the client has changed, so the old ETag is stale
+			w = serveRequest(t, api, test.method, "/"+client.ID+test.path, test.body, test.body, map[string]string{"If-Match": clientETag(client)})
+			decodeResponseOrFail(t, w, http.StatusPreconditionFailed)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package apiv1
+
+import (
+	"encoding/json"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+// Key is an API-specific representation of a public key a client has
+// registered to authenticate with.
+type Key struct {
+	ID          string          `json:"kid"`
+	ClientID    string          `json:"clientID"`
+	KeyType     string          `json:"kty"`
+	Algorithm   string          `json:"alg"`
+	JWK         json.RawMessage `json:"jwk"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	CreatedBy   string          `json:"createdBy"`
+	CreatedByIP string          `json:"createdByIP"`
+}
+
+func apiKey(key clients.Key) Key {
+	return Key{
+		ID:          key.ID,
+		ClientID:    key.ClientID,
+		KeyType:     key.KeyType,
+		Algorithm:   key.Algorithm,
+		JWK:         json.RawMessage(key.JWK),
+		CreatedAt:   key.CreatedAt,
+		CreatedBy:   key.CreatedBy,
+		CreatedByIP: key.CreatedByIP,
+	}
+}
+
+func apiKeys(keys []clients.Key) []Key {
+	res := make([]Key, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, apiKey(key))
+	}
+	return res
+}
@@ -0,0 +1,122 @@
+package apiv1
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"lockbox.dev/clients"
+)
+
+// immutableClientFields are the JSON fields of a Client that can't be
+// changed by a merge patch, in the order their errors are reported.
+var immutableClientFields = []string{
+	"id",
+	"confidential",
+	"secret",
+	"secretRotatedAt",
+	"secretExpiresAt",
+	"status",
+	"statusReason",
+	"statusChangedAt",
+	"statusChangedBy",
+	"createdAt",
+	"createdBy",
+	"createdByIP",
+	"version",
+}
+
+// mergePatch applies the JSON Merge Patch patch to target, as described in
+// RFC 7396, and returns the result. Both are decoded JSON values.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// patchClient applies the JSON Merge Patch patch to client and returns the
+// result.
+func patchClient(client Client, patch map[string]interface{}) (Client, error) {
+	encoded, err := json.Marshal(client)
+	if err != nil {
+		return Client{}, err
+	}
+	var target interface{}
+	err = json.Unmarshal(encoded, &target)
+	if err != nil {
+		return Client{}, err
+	}
+	encoded, err = json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return Client{}, err
+	}
+	var res Client
+	err = json.Unmarshal(encoded, &res)
+	if err != nil {
+		return Client{}, err
+	}
+	return res, nil
+}
+
+// clientChange returns the clients.Change that updates the mutable fields
+// of a client from their values in orig to their values in updated.
+func clientChange(orig, updated Client) clients.Change {
+	var change clients.Change
+	strs := []struct {
+		orig, updated string
+		dest          **string
+	}{
+		{orig.Name, updated.Name, &change.Name},
+		{orig.TokenEndpointAuthMethod, updated.TokenEndpointAuthMethod, &change.TokenEndpointAuthMethod},
+		{orig.JWKSURI, updated.JWKSURI, &change.JWKSURI},
+		{orig.TLSClientAuthSubjectDN, updated.TLSClientAuthSubjectDN, &change.TLSClientAuthSubjectDN},
+		{orig.TLSClientAuthSANDNS, updated.TLSClientAuthSANDNS, &change.TLSClientAuthSANDNS},
+		{orig.TLSClientAuthSANURI, updated.TLSClientAuthSANURI, &change.TLSClientAuthSANURI},
+		{orig.TLSClientAuthSANIP, updated.TLSClientAuthSANIP, &change.TLSClientAuthSANIP},
+		{orig.TLSClientAuthSANEmail, updated.TLSClientAuthSANEmail, &change.TLSClientAuthSANEmail},
+		{orig.TLSClientCertThumbprint, updated.TLSClientCertThumbprint, &change.TLSClientCertThumbprint},
+		{orig.ClientURI, updated.ClientURI, &change.ClientURI},
+		{orig.LogoURI, updated.LogoURI, &change.LogoURI},
+		{orig.PolicyURI, updated.PolicyURI, &change.PolicyURI},
+		{orig.TOSURI, updated.TOSURI, &change.TOSURI},
+		{orig.SoftwareID, updated.SoftwareID, &change.SoftwareID},
+		{orig.SoftwareVersion, updated.SoftwareVersion, &change.SoftwareVersion},
+	}
+	for _, str := range strs {
+		if str.orig != str.updated {
+			value := str.updated
+			*str.dest = &value
+		}
+	}
+	slices := []struct {
+		orig, updated []string
+		dest          **[]string
+	}{
+		{orig.GrantTypes, updated.GrantTypes, &change.GrantTypes},
+		{orig.ResponseTypes, updated.ResponseTypes, &change.ResponseTypes},
+		{orig.Contacts, updated.Contacts, &change.Contacts},
+	}
+	for _, slice := range slices {
+		// an empty list and no list are the same thing
+		if len(slice.orig) == 0 && len(slice.updated) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(slice.orig, slice.updated) {
+			value := slice.updated
+			*slice.dest = &value
+		}
+	}
+	return change
+}
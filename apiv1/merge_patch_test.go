@@ -0,0 +1,128 @@
+package apiv1
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/clients"
+)
+
+func decodeJSONOrFail(t *testing.T, input string) interface{} {
+	t.Helper()
+	var res interface{}
+	err := json.Unmarshal([]byte(input), &res)
+	if err != nil {
+		t.Fatalf("Error decoding %q: %s", input, err)
+	}
+	return res
+}
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	// the examples from RFC 7396, Appendix A
+	tests := []struct {
+		target, patch, result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		got := mergePatch(decodeJSONOrFail(t, test.target), decodeJSONOrFail(t, test.patch))
+		if diff := cmp.Diff(decodeJSONOrFail(t, test.result), got); diff != "" {
+			t.Errorf("Unexpected diff patching %s with %s (-wanted, +got): %s", test.target, test.patch, diff)
+		}
+	}
+}
+
+func TestPatchClient(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Now().Round(time.Millisecond).UTC()
+	orig := Client{
+		ID:            "test-client",
+		Name:          "Test Client",
+		GrantTypes:    []string{clients.GrantTypeAuthorizationCode},
+		ResponseTypes: []string{clients.ResponseTypeCode},
+		ClientURI:     "https://client.example.com",
+		Contacts:      []string{"admin@example.com"},
+		CreatedAt:     createdAt,
+		CreatedBy:     "test",
+		CreatedByIP:   "127.0.0.1",
+		Version:       3,
+	}
+	patch := decodeJSONOrFail(t, `{"name":"Patched Client","clientURI":null,"logoURI":"https://client.example.com/logo.png","contacts":["ops@example.com","dev@example.com"]}`).(map[string]interface{})
+	got, err := patchClient(orig, patch)
+	if err != nil {
+		t.Fatalf("Error patching client: %s", err)
+	}
+	want := orig
+	want.Name = "Patched Client"
+	want.ClientURI = ""
+	want.LogoURI = "https://client.example.com/logo.png"
+	want.Contacts = []string{"ops@example.com", "dev@example.com"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+	if orig.Name != "Test Client" || len(orig.Contacts) != 1 {
+		t.Errorf("Expected original client to be unchanged, got %+v", orig)
+	}
+
+	_, err = patchClient(orig, map[string]interface{}{"grantTypes": "not a list"})
+	if err == nil {
+		t.Error("Expected an error patching a field with the wrong type, got nil")
+	}
+}
+
+func TestClientChange(t *testing.T) {
+	t.Parallel()
+
+	orig := Client{
+		Name:          "Test Client",
+		GrantTypes:    []string{clients.GrantTypeAuthorizationCode},
+		ResponseTypes: []string{clients.ResponseTypeCode},
+		ClientURI:     "https://client.example.com",
+	}
+	if change := clientChange(orig, orig); !change.IsEmpty() {
+		t.Errorf("Expected no change for an unchanged client, got %+v", change)
+	}
+
+	// an empty list and no list are the same thing
+	updated := orig
+	updated.Contacts = []string{}
+	if change := clientChange(orig, updated); !change.IsEmpty() {
+		t.Errorf("Expected no change for an empty list, got %+v", change)
+	}
+
+	updated.Name = "Updated Client"
+	updated.ClientURI = ""
+	updated.GrantTypes = []string{clients.GrantTypeAuthorizationCode, clients.GrantTypeRefreshToken}
+	updated.Contacts = []string{"admin@example.com"}
+	change := clientChange(orig, updated)
+	name, clientURI := "Updated Client", ""
+	want := clients.Change{
+		Name:       &name,
+		ClientURI:  &clientURI,
+		GrantTypes: &updated.GrantTypes,
+		Contacts:   &updated.Contacts,
+	}
+	if diff := cmp.Diff(want, change); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
@@ -0,0 +1,56 @@
+package apiv1
+
+import (
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+// Scope is an API-specific representation of a scope a client is allowed to
+// use.
+type Scope struct {
+	ID          string    `json:"ID"`
+	ClientID    string    `json:"clientID"`
+	IsDefault   bool      `json:"isDefault"`
+	CreatedAt   time.Time `json:"createdAt"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedByIP string    `json:"createdByIP"`
+}
+
+func coreScope(scope Scope) clients.Scope {
+	return clients.Scope{
+		ID:          scope.ID,
+		ClientID:    scope.ClientID,
+		IsDefault:   scope.IsDefault,
+		CreatedAt:   scope.CreatedAt,
+		CreatedBy:   scope.CreatedBy,
+		CreatedByIP: scope.CreatedByIP,
+	}
+}
+
+func coreScopes(scopes []Scope) []clients.Scope {
+	res := make([]clients.Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		res = append(res, coreScope(scope))
+	}
+	return res
+}
+
+func apiScope(scope clients.Scope) Scope {
+	return Scope{
+		ID:          scope.ID,
+		ClientID:    scope.ClientID,
+		IsDefault:   scope.IsDefault,
+		CreatedAt:   scope.CreatedAt,
+		CreatedBy:   scope.CreatedBy,
+		CreatedByIP: scope.CreatedByIP,
+	}
+}
+
+func apiScopes(scopes []clients.Scope) []Scope {
+	res := make([]Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		res = append(res, apiScope(scope))
+	}
+	return res
+}
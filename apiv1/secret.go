@@ -0,0 +1,48 @@
+package apiv1
+
+import (
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+// Secret is an API-specific representation of an additional secret a client
+// may authenticate with. The hash of the secret is never included; the secret
+// itself is only included when it is first generated.
+type Secret struct {
+	ID          string     `json:"ID"`
+	ClientID    string     `json:"clientID"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedByIP string     `json:"createdByIP"`
+	Secret      string     `json:"secret,omitempty"`
+}
+
+func apiSecret(secret clients.Secret) Secret {
+	res := Secret{
+		ID:          secret.ID,
+		ClientID:    secret.ClientID,
+		CreatedAt:   secret.CreatedAt,
+		CreatedBy:   secret.CreatedBy,
+		CreatedByIP: secret.CreatedByIP,
+	}
+	if !secret.ExpiresAt.IsZero() {
+		expiresAt := secret.ExpiresAt
+		res.ExpiresAt = &expiresAt
+	}
+	if !secret.LastUsedAt.IsZero() {
+		lastUsedAt := secret.LastUsedAt
+		res.LastUsedAt = &lastUsedAt
+	}
+	return res
+}
+
+func apiSecrets(secrets []clients.Secret) []Secret {
+	res := make([]Secret, 0, len(secrets))
+	for _, secret := range secrets {
+		res = append(res, apiSecret(secret))
+	}
+	return res
+}
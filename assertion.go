@@ -0,0 +1,457 @@
+package clients
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	yall "yall.in"
+)
+
+const (
+	// ClientAssertionTypeJWTBearer is the client_assertion_type used for
+	// JWT client assertions, as defined in RFC 7523, Section 2.2.
+	ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// DefaultAssertionLeeway is the clock skew allowed when validating the
+	// time-based claims of an assertion if AssertionVerifier.Leeway isn't
+	// set.
+	DefaultAssertionLeeway = 30 * time.Second
+	// DefaultAssertionMaxLifetime is the longest an assertion may be valid
+	// for if AssertionVerifier.MaxLifetime isn't set.
+	DefaultAssertionMaxLifetime = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidAssertion is returned when a client assertion is malformed,
+	// has an invalid signature, or has invalid claims.
+	ErrInvalidAssertion = errors.New("invalid client assertion")
+	// ErrAssertionReplayed is returned when a client assertion's jti has
+	// already been used.
+	ErrAssertionReplayed = errors.New("client assertion has already been used")
+)
+
+// AssertionVerifier verifies JWTs that Clients use to authenticate with the
+// private_key_jwt and client_secret_jwt methods, as described in RFC 7523
+// and Section 9 of OpenID Connect Core.
+type AssertionVerifier struct {
+	// Storer is used to look up Clients and the Keys they've registered.
+	Storer Storer
+
+	// JWKS fetches the Keys of Clients that publish them at a JWKSURI
+	// instead of registering them. If unset, those Clients can't be
+	// verified.
+	JWKS *JWKSCache
+
+	// SecretKeyring decrypts the secrets of Clients using
+	// AuthMethodClientSecretJWT, which sign their assertions with HMAC.
+	// If unset, those Clients can't be verified.
+	SecretKeyring *SecretKeyring
+
+	// ReplayCache records the jti of every accepted assertion, so they
+	// can't be used more than once.
+	ReplayCache ReplayCache
+
+	// Audiences are the values, one of which must be in the assertion's
+	// aud claim. This is usually the URL of the token endpoint.
+	Audiences []string
+
+	// Leeway is the clock skew allowed when validating exp, nbf, and iat.
+	// If unset, DefaultAssertionLeeway is used.
+	Leeway time.Duration
+
+	// MaxLifetime is the longest an assertion may be valid for, measured
+	// from iat (or the current time, if iat isn't set) to exp. If unset,
+	// DefaultAssertionMaxLifetime is used.
+	MaxLifetime time.Duration
+
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+type assertionHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type assertionClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	IssuedAt  *int64          `json:"iat"`
+	JWTID     string          `json:"jti"`
+}
+
+// audiences returns the aud claim, which may be a single string or an array
+// of strings.
+func (c assertionClaims) audiences() ([]string, error) {
+	if len(c.Audience) < 1 {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return []string{single}, nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(c.Audience, &multiple); err != nil {
+		return nil, err
+	}
+	return multiple, nil
+}
+
+// jws is a parsed JWS in compact serialization.
+type jws struct {
+	header       assertionHeader
+	payload      []byte
+	signingInput []byte
+	signature    []byte
+}
+
+func parseJWS(token string) (jws, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 { //nolint:gomnd // header, payload, and signature
+		return jws{}, fmt.Errorf("%w: not a JWS in compact serialization", ErrInvalidAssertion)
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return jws{}, fmt.Errorf("%w: invalid header encoding", ErrInvalidAssertion)
+	}
+	var header assertionHeader
+	err = json.Unmarshal(rawHeader, &header)
+	if err != nil {
+		return jws{}, fmt.Errorf("%w: invalid header", ErrInvalidAssertion)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return jws{}, fmt.Errorf("%w: invalid payload encoding", ErrInvalidAssertion)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return jws{}, fmt.Errorf("%w: invalid signature encoding", ErrInvalidAssertion)
+	}
+	return jws{
+		header:       header,
+		payload:      payload,
+		signingInput: []byte(parts[0] + "." + parts[1]),
+		signature:    signature,
+	}, nil
+}
+
+// verifySignature checks that signature is a valid signature of
+// signingInput using key and the JWS algorithm alg.
+func verifySignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(edKey, signingInput, signature) {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidAssertion)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidAssertion, alg)
+	}
+	hasher := hash.New()
+	hasher.Write(signingInput) //nolint:errcheck // hashes never return errors
+	digest := hasher.Sum(nil)
+	var valid bool
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "PS") {
+			valid = rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(pub, hash, digest, signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		// JWS encodes ECDSA signatures as r and s concatenated, each
+		// padded to the size of the curve
+		size := (pub.Curve.Params().BitSize + 7) / 8 //nolint:gomnd // rounding bits up to bytes
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(pub, digest, r, s)
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%w: invalid signature", ErrInvalidAssertion)
+	}
+	return nil
+}
+
+func (v AssertionVerifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func (v AssertionVerifier) leeway() time.Duration {
+	if v.Leeway > 0 {
+		return v.Leeway
+	}
+	return DefaultAssertionLeeway
+}
+
+func (v AssertionVerifier) maxLifetime() time.Duration {
+	if v.MaxLifetime > 0 {
+		return v.MaxLifetime
+	}
+	return DefaultAssertionMaxLifetime
+}
+
+// Verify checks that assertion is a valid private_key_jwt or
+// client_secret_jwt client assertion and returns the Client it
+// authenticates.
+//
+// The assertion's iss and sub must both be the ID of a Client in the Storer
+// that is active. If the Client registered AuthMethodPrivateKeyJWT, the
+// assertion must be signed by one of the Keys the Client registered, using
+// the algorithm the Key was registered for. If the Client registered
+// AuthMethodClientSecretJWT, it must be signed with HMAC using the Client's
+// secret, which is decrypted using the SecretKeyring. Its aud must contain
+// one of the AssertionVerifier's Audiences, it must have an exp in the
+// future and no longer than MaxLifetime away, its nbf and iat, if set, must
+// not be in the future, and it must have a jti that hasn't been used
+// before.
+//
+// If the Client can't be found, ErrClientNotFound is returned. If the Client
+// registered neither method, ErrAuthMethodNotAllowed is returned, and if
+// it's disabled or suspended, ErrClientDisabled is returned. If the jti has
+// already been used, ErrAssertionReplayed is returned. If the assertion is
+// otherwise invalid, an error wrapping ErrInvalidAssertion is returned.
+//
+// If a client_secret_jwt Client's secret was encrypted with a KEK other than
+// the SecretKeyring's current one, it's re-wrapped with the current KEK and
+// stored, and the updated Client is returned. Failing to store it is
+// logged, but does not fail verification.
+func (v AssertionVerifier) Verify(ctx context.Context, assertion string) (Client, error) {
+	token, err := parseJWS(assertion)
+	if err != nil {
+		return Client{}, err
+	}
+	var claims assertionClaims
+	err = json.Unmarshal(token.payload, &claims)
+	if err != nil {
+		return Client{}, fmt.Errorf("%w: invalid claims", ErrInvalidAssertion)
+	}
+	if claims.Subject == "" || claims.Issuer != claims.Subject {
+		return Client{}, fmt.Errorf("%w: iss and sub must both be the client ID", ErrInvalidAssertion)
+	}
+	client, err := v.Storer.Get(ctx, claims.Subject)
+	if err != nil {
+		return Client{}, err
+	}
+	switch client.AuthMethod() {
+	case AuthMethodPrivateKeyJWT:
+		keys, err := v.keys(ctx, client, token.header.KeyID)
+		if err != nil {
+			return Client{}, err
+		}
+		err = verifyWithKeys(token, keys)
+		if err != nil {
+			return Client{}, err
+		}
+	case AuthMethodClientSecretJWT:
+		err = v.verifyWithSecret(token, client)
+		if err != nil {
+			return Client{}, err
+		}
+	default:
+		return Client{}, ErrAuthMethodNotAllowed
+	}
+	now := v.now()
+	err = v.validateClaims(claims, now)
+	if err != nil {
+		return Client{}, err
+	}
+	err = client.CheckStatus()
+	if err != nil {
+		return Client{}, err
+	}
+	if v.ReplayCache == nil {
+		return Client{}, errors.New("no replay cache configured") //nolint:goerr113 // this is a programming error, not a condition to check for
+	}
+	seen, err := v.ReplayCache.Seen(ctx, client.ID, claims.JWTID, time.Unix(*claims.ExpiresAt, 0).Add(v.leeway()))
+	if err != nil {
+		return Client{}, err
+	}
+	if seen {
+		return Client{}, ErrAssertionReplayed
+	}
+	if client.AuthMethod() == AuthMethodClientSecretJWT && v.SecretKeyring.NeedsRewrap(client.EncryptedSecret) {
+		return v.rewrapSecret(ctx, client), nil
+	}
+	return client, nil
+}
+
+// verifyWithSecret checks that token is signed with HMAC using client's
+// secret, decrypted using the SecretKeyring.
+func (v AssertionVerifier) verifyWithSecret(token jws, client Client) error {
+	var hash crypto.Hash
+	switch token.header.Algorithm {
+	case "HS256":
+		hash = crypto.SHA256
+	case "HS384":
+		hash = crypto.SHA384
+	case "HS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: client_secret_jwt assertions must use an HMAC alg", ErrInvalidAssertion)
+	}
+	if v.SecretKeyring == nil {
+		return fmt.Errorf("%w: client uses client_secret_jwt and no SecretKeyring is configured", ErrInvalidAssertion)
+	}
+	secret, err := v.SecretKeyring.Decrypt(client.ID, client.EncryptedSecret)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(hash.New, secret)
+	mac.Write(token.signingInput) //nolint:errcheck // hashes never return errors
+	if !hmac.Equal(mac.Sum(nil), token.signature) {
+		return fmt.Errorf("%w: invalid signature", ErrInvalidAssertion)
+	}
+	if client.SecretExpired(v.now()) {
+		return ErrSecretExpired
+	}
+	return nil
+}
+
+// rewrapSecret re-wraps client's encrypted secret with the SecretKeyring's
+// current KEK and stores it, returning the updated Client. Failing to store
+// the re-wrapped secret is logged, but doesn't fail verification.
+func (v AssertionVerifier) rewrapSecret(ctx context.Context, client Client) Client {
+	log := yall.FromContext(ctx).WithField("client_id", client.ID)
+	encrypted, err := v.SecretKeyring.Rewrap(client.ID, client.EncryptedSecret)
+	if err != nil {
+		log.WithError(err).Error("error re-wrapping client secret")
+		return client
+	}
+	change := Change{EncryptedSecret: &encrypted}
+	err = v.Storer.Update(ctx, client.ID, client.Version, change)
+	if err != nil {
+		log.WithError(err).Error("error storing re-wrapped client secret")
+		return client
+	}
+	log.WithField("kek_id", v.SecretKeyring.CurrentKEK()).Debug("re-wrapped client secret")
+	client = Apply(change, client)
+	client.Version++
+	return client
+}
+
+// keys returns the Keys client has registered, or the Keys published at its
+// JWKSURI. If the published key set doesn't contain kid, the key set is
+// fetched again in case the Client has rotated its keys since it was cached.
+func (v AssertionVerifier) keys(ctx context.Context, client Client, kid string) ([]Key, error) {
+	if client.JWKSURI == "" {
+		return v.Storer.ListKeys(ctx, client.ID)
+	}
+	if v.JWKS == nil {
+		return nil, fmt.Errorf("%w: client uses a jwks_uri and no JWKSCache is configured", ErrInvalidAssertion)
+	}
+	keys, err := v.JWKS.Keys(ctx, client.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	if kid == "" {
+		return keys, nil
+	}
+	for _, key := range keys {
+		if key.ID == kid {
+			return keys, nil
+		}
+	}
+	return v.JWKS.Refresh(ctx, client.JWKSURI)
+}
+
+// verifyWithKeys checks that token is signed by one of keys. If token's
+// header has a kid, only the Key with that ID is tried.
+func verifyWithKeys(token jws, keys []Key) error {
+	if token.header.Algorithm == "" || strings.EqualFold(token.header.Algorithm, "none") {
+		return fmt.Errorf("%w: unsigned assertions are not accepted", ErrInvalidAssertion)
+	}
+	var candidates int
+	for _, key := range keys {
+		if token.header.KeyID != "" && key.ID != token.header.KeyID {
+			continue
+		}
+		// the algorithm must be the one the key was registered for,
+		// so the assertion can't choose a weaker one
+		if key.Algorithm != token.header.Algorithm {
+			continue
+		}
+		candidates++
+		pub, err := key.PublicKey()
+		if err != nil {
+			continue
+		}
+		if verifySignature(token.header.Algorithm, pub, token.signingInput, token.signature) == nil {
+			return nil
+		}
+	}
+	if candidates < 1 {
+		return fmt.Errorf("%w: no registered key matches the assertion's kid and alg", ErrInvalidAssertion)
+	}
+	return fmt.Errorf("%w: invalid signature", ErrInvalidAssertion)
+}
+
+// validateClaims checks the aud, exp, nbf, iat, and jti claims.
+func (v AssertionVerifier) validateClaims(claims assertionClaims, now time.Time) error {
+	audiences, err := claims.audiences()
+	if err != nil {
+		return fmt.Errorf("%w: invalid aud", ErrInvalidAssertion)
+	}
+	var audienceMatched bool
+	for _, aud := range audiences {
+		for _, expected := range v.Audiences {
+			if aud == expected {
+				audienceMatched = true
+			}
+		}
+	}
+	if !audienceMatched {
+		return fmt.Errorf("%w: aud doesn't contain an accepted audience", ErrInvalidAssertion)
+	}
+	leeway := v.leeway()
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: exp is required", ErrInvalidAssertion)
+	}
+	expiresAt := time.Unix(*claims.ExpiresAt, 0)
+	if !now.Before(expiresAt.Add(leeway)) {
+		return fmt.Errorf("%w: assertion has expired", ErrInvalidAssertion)
+	}
+	if claims.NotBefore != nil && now.Add(leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: assertion is not valid yet", ErrInvalidAssertion)
+	}
+	issuedAt := now
+	if claims.IssuedAt != nil {
+		issuedAt = time.Unix(*claims.IssuedAt, 0)
+		if now.Add(leeway).Before(issuedAt) {
+			return fmt.Errorf("%w: iat is in the future", ErrInvalidAssertion)
+		}
+	}
+	if expiresAt.Sub(issuedAt) > v.maxLifetime() {
+		return fmt.Errorf("%w: assertion is valid for too long", ErrInvalidAssertion)
+	}
+	if claims.JWTID == "" {
+		return fmt.Errorf("%w: jti is required", ErrInvalidAssertion)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package clients_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func signAssertion(t *testing.T, header, claims map[string]interface{}, key crypto.Signer) string {
+	t.Helper()
+	input := base64.RawURLEncoding.EncodeToString(jsonOrFail(t, header)) + "." + base64.RawURLEncoding.EncodeToString(jsonOrFail(t, claims))
+	var sig []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("Error signing assertion: %s", err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(input))
+	default:
+		t.Fatalf("Unsupported key type %T", key)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAssertionVerifier(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:                      uuidOrFail(t),
+			Name:                    "Test Client",
+			Confidential:            true,
+			TokenEndpointAuthMethod: clients.AuthMethodPrivateKeyJWT,
+			CreatedAt:               time.Now().Round(time.Millisecond),
+			CreatedBy:               "test",
+			CreatedByIP:             "127.0.0.1",
+		}
+		err := storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Error generating EC key: %s", err)
+		}
+		_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Error generating Ed25519 key: %s", err)
+		}
+		key, err := clients.ParseKey(jsonOrFail(t, ecJWK(t, "test-key", &ecKey.PublicKey)))
+		if err != nil {
+			t.Fatalf("Error parsing key: %s", err)
+		}
+		key.ClientID = client.ID
+		key.CreatedAt = time.Now().Round(time.Millisecond)
+		err = storer.AddKeys(ctx, []clients.Key{key})
+		if err != nil {
+			t.Fatalf("Error storing key: %s", err)
+		}
+
+		now := time.Now()
+		verifier := clients.AssertionVerifier{
+			Storer:      storer,
+			ReplayCache: &clients.MemoryReplayCache{},
+			Audiences:   []string{"https://auth.example.com/token"},
+			Now:         func() time.Time { return now },
+		}
+		header := map[string]interface{}{"alg": "ES256", "kid": "test-key"}
+		validClaims := func() map[string]interface{} {
+			return map[string]interface{}{
+				"iss": client.ID,
+				"sub": client.ID,
+				"aud": "https://auth.example.com/token",
+				"iat": now.Unix(),
+				"exp": now.Add(time.Minute).Unix(),
+				"jti": uuidOrFail(t),
+			}
+		}
+		with := func(claims map[string]interface{}, claim string, value interface{}) map[string]interface{} {
+			claims[claim] = value
+			return claims
+		}
+
+		claims := validClaims()
+		assertion := signAssertion(t, header, claims, ecKey)
+		res, err := verifier.Verify(ctx, assertion)
+		if err != nil {
+			t.Fatalf("Unexpected error verifying assertion: %s", err)
+		}
+		if res.ID != client.ID {
+			t.Errorf("Expected client %q, got %q", client.ID, res.ID)
+		}
+		_, err = verifier.Verify(ctx, assertion)
+		if !errors.Is(err, clients.ErrAssertionReplayed) {
+			t.Errorf("Expected %v, got %v", clients.ErrAssertionReplayed, err)
+		}
+
+		invalid := map[string]string{
+			"wrong-key":        signAssertion(t, map[string]interface{}{"alg": "ES256", "kid": "test-key"}, validClaims(), otherKey),
+			"wrong-alg":        signAssertion(t, map[string]interface{}{"alg": "EdDSA", "kid": "test-key"}, validClaims(), otherKey),
+			"unknown-kid":      signAssertion(t, map[string]interface{}{"alg": "ES256", "kid": "other-key"}, validClaims(), ecKey),
+			"unsigned":         base64.RawURLEncoding.EncodeToString(jsonOrFail(t, map[string]interface{}{"alg": "none"})) + "." + base64.RawURLEncoding.EncodeToString(jsonOrFail(t, validClaims())) + ".",
+			"wrong-aud":        signAssertion(t, header, with(validClaims(), "aud", "https://other.example.com/token"), ecKey),
+			"multiple-aud":     signAssertion(t, header, with(validClaims(), "aud", []string{"https://other.example.com/token"}), ecKey),
+			"iss-not-sub":      signAssertion(t, header, with(validClaims(), "iss", "someone-else"), ecKey),
+			"expired":          signAssertion(t, header, with(validClaims(), "exp", now.Add(-time.Hour).Unix()), ecKey),
+			"no-exp":           signAssertion(t, header, with(validClaims(), "exp", nil), ecKey),
+			"too-long":         signAssertion(t, header, with(validClaims(), "exp", now.Add(time.Hour).Unix()), ecKey),
+			"not-yet-valid":    signAssertion(t, header, with(validClaims(), "nbf", now.Add(time.Hour).Unix()), ecKey),
+			"issued-in-future": signAssertion(t, header, with(validClaims(), "iat", now.Add(time.Hour).Unix()), ecKey),
+			"no-jti":           signAssertion(t, header, with(validClaims(), "jti", ""), ecKey),
+			"malformed":        "not.a-jwt",
+		}
+		for name, assertion := range invalid {
+			_, err = verifier.Verify(ctx, assertion)
+			if !errors.Is(err, clients.ErrInvalidAssertion) {
+				t.Errorf("%s: expected %v, got %v", name, clients.ErrInvalidAssertion, err)
+			}
+		}
+
+		// a valid audience in an array should be accepted
+		_, err = verifier.Verify(ctx, signAssertion(t, header, with(validClaims(), "aud", []string{"https://other.example.com", "https://auth.example.com/token"}), ecKey))
+		if err != nil {
+			t.Errorf("Unexpected error verifying assertion with multiple audiences: %s", err)
+		}
+	})
+}
+
+func TestMemoryReplayCacheExpires(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	cache := &clients.MemoryReplayCache{Now: func() time.Time { return now }}
+	seen, err := cache.Seen(context.Background(), "client", "jti", now.Add(time.Minute))
+	if err != nil || seen {
+		t.Fatalf("Expected first use not to be seen, got %v, %v", seen, err)
+	}
+	seen, err = cache.Seen(context.Background(), "other-client", "jti", now.Add(time.Minute))
+	if err != nil || seen {
+		t.Errorf("Expected use by another client not to be seen, got %v, %v", seen, err)
+	}
+	seen, err = cache.Seen(context.Background(), "client", "jti", now.Add(time.Minute))
+	if err != nil || !seen {
+		t.Errorf("Expected second use to be seen, got %v, %v", seen, err)
+	}
+	now = now.Add(2 * time.Minute)
+	seen, err = cache.Seen(context.Background(), "client", "jti", now.Add(time.Minute))
+	if err != nil || seen {
+		t.Errorf("Expected use after expiry not to be seen, got %v, %v", seen, err)
+	}
+}
@@ -0,0 +1,132 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Attempts describes the recent failed authentication attempts made by a
+// client ID or from an IP address.
+type Attempts struct {
+	Failures      int       // the number of failures since failures were last reset or forgotten
+	LastFailureAt time.Time // when the most recent failure happened
+	LockedUntil   time.Time // when any lockout ends; the zero value means there is none
+}
+
+// AttemptStore records failed authentication attempts, so they can be
+// throttled by a ThrottledAuthenticator. Attempts are recorded against
+// opaque keys, each identifying a client ID or an IP address.
+type AttemptStore interface {
+	// GetAttempts returns the Attempts recorded for key. If none have
+	// been recorded, the zero value is returned.
+	GetAttempts(ctx context.Context, key string) (Attempts, error)
+
+	// RecordFailure records a failed attempt for key that happened at
+	// at, returning the updated Attempts. If the previous failure
+	// happened more than window before at, the earlier failures are
+	// forgotten and counting starts again. Recording must be atomic, so
+	// concurrent failures are all counted.
+	RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (Attempts, error)
+
+	// SetLockout locks key out until until.
+	SetLockout(ctx context.Context, key string, until time.Time) error
+
+	// ResetAttempts forgets all failures and any lockout for key.
+	ResetAttempts(ctx context.Context, key string) error
+}
+
+// MemoryAttemptStore is an in-memory implementation of AttemptStore. It's
+// only suitable when a single process is authenticating clients;
+// deployments with more than one should use an AttemptStore that shares
+// its state between them, so attackers can't spread their attempts across
+// processes.
+//
+// The zero value is ready to use.
+type MemoryAttemptStore struct {
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]memoryAttempts
+	lastPurged time.Time
+}
+
+type memoryAttempts struct {
+	Attempts
+	window time.Duration
+}
+
+// expired returns true if the failures and lockout in a have both lapsed.
+func (a memoryAttempts) expired(now time.Time) bool {
+	return !a.LastFailureAt.Add(a.window).After(now) && !a.LockedUntil.After(now)
+}
+
+// memoryAttemptStorePurgeInterval is how often MemoryAttemptStore removes
+// expired entries.
+const memoryAttemptStorePurgeInterval = time.Minute
+
+func (m *MemoryAttemptStore) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// GetAttempts returns the Attempts recorded for key.
+func (m *MemoryAttemptStore) GetAttempts(_ context.Context, key string) (Attempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries[key].Attempts, nil
+}
+
+// RecordFailure records a failed attempt for key, forgetting earlier
+// failures if they happened more than window before at. Expired entries are
+// periodically removed as new ones are recorded.
+func (m *MemoryAttemptStore) RecordFailure(_ context.Context, key string, at time.Time, window time.Duration) (Attempts, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = map[string]memoryAttempts{}
+	}
+	if now.Sub(m.lastPurged) >= memoryAttemptStorePurgeInterval {
+		for k, entry := range m.entries {
+			if entry.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastPurged = now
+	}
+	entry := m.entries[key]
+	if entry.LastFailureAt.Add(window).Before(at) {
+		entry.Failures = 0
+	}
+	entry.Failures++
+	entry.LastFailureAt = at
+	entry.window = window
+	m.entries[key] = entry
+	return entry.Attempts, nil
+}
+
+// SetLockout locks key out until until.
+func (m *MemoryAttemptStore) SetLockout(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = map[string]memoryAttempts{}
+	}
+	entry := m.entries[key]
+	entry.LockedUntil = until
+	m.entries[key] = entry
+	return nil
+}
+
+// ResetAttempts forgets all failures and any lockout for key.
+func (m *MemoryAttemptStore) ResetAttempts(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
@@ -0,0 +1,130 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// AuthMethodNone is the token endpoint authentication method used by
+	// public clients, which don't authenticate at all.
+	AuthMethodNone = "none"
+	// AuthMethodClientSecretBasic is the token endpoint authentication
+	// method for clients that send their secret using HTTP Basic
+	// authentication, as described in RFC 6749, Section 2.3.1.
+	AuthMethodClientSecretBasic = "client_secret_basic"
+	// AuthMethodClientSecretPost is the token endpoint authentication method
+	// for clients that send their secret in the request body, as described
+	// in RFC 6749, Section 2.3.1.
+	AuthMethodClientSecretPost = "client_secret_post"
+	// AuthMethodClientSecretJWT is the token endpoint authentication method
+	// for clients that authenticate with a JWT signed using their secret, as
+	// described in RFC 7523.
+	AuthMethodClientSecretJWT = "client_secret_jwt"
+	// AuthMethodPrivateKeyJWT is the token endpoint authentication method for
+	// clients that authenticate with a JWT signed using a private key whose
+	// public key they've registered, as described in RFC 7523.
+	AuthMethodPrivateKeyJWT = "private_key_jwt"
+	// AuthMethodTLSClientAuth is the token endpoint authentication method
+	// for clients that authenticate using a TLS client certificate, as
+	// described in RFC 8705.
+	AuthMethodTLSClientAuth = "tls_client_auth"
+	// AuthMethodSelfSignedTLSClientAuth is the token endpoint
+	// authentication method for clients that authenticate using a
+	// self-signed TLS client certificate, as described in RFC 8705.
+	AuthMethodSelfSignedTLSClientAuth = "self_signed_tls_client_auth"
+)
+
+// ErrAuthMethodNotAllowed is returned when a Client tries to authenticate
+// using a token endpoint authentication method it didn't register.
+var ErrAuthMethodNotAllowed = errors.New("authentication method not allowed for client")
+
+// AuthMethodError is returned when a Client's TokenEndpointAuthMethod is
+// unknown, or inconsistent with whether the Client is confidential or with
+// the credentials stored for it.
+type AuthMethodError struct {
+	Method string // the authentication method that is invalid
+	Reason string // why the authentication method is invalid
+}
+
+// Error fills the error interface for AuthMethodError.
+func (e AuthMethodError) Error() string {
+	return fmt.Sprintf("invalid token endpoint authentication method %q: %s", e.Method, e.Reason)
+}
+
+// DefaultAuthMethod returns the token endpoint authentication method a
+// Client uses if it doesn't specify one: AuthMethodClientSecretBasic for
+// confidential clients, and AuthMethodNone for public clients.
+func DefaultAuthMethod(confidential bool) string {
+	if confidential {
+		return AuthMethodClientSecretBasic
+	}
+	return AuthMethodNone
+}
+
+// AuthMethod returns the token endpoint authentication method the Client
+// uses. If the Client has no TokenEndpointAuthMethod set, the result of
+// DefaultAuthMethod is returned.
+func (c Client) AuthMethod() string {
+	if c.TokenEndpointAuthMethod == "" {
+		return DefaultAuthMethod(c.Confidential)
+	}
+	return c.TokenEndpointAuthMethod
+}
+
+// UsesSecret returns true if the Client's authentication method requires it
+// to have a secret.
+func (c Client) UsesSecret() bool {
+	switch c.AuthMethod() {
+	case AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodClientSecretJWT:
+		return true
+	}
+	return false
+}
+
+// HasSecret returns true if the Client is confidential, its authentication
+// method uses a secret, and it has a secret stored. Only Clients with a
+// secret have one to rotate or re-hash.
+func (c Client) HasSecret() bool {
+	return c.Confidential && c.UsesSecret() && c.SecretHash != ""
+}
+
+// ValidateAuthMethod checks that the Client's TokenEndpointAuthMethod is
+// known and consistent with whether the Client is confidential and the
+// credentials stored for it. If it's not, an AuthMethodError is returned.
+func (c Client) ValidateAuthMethod() error {
+	method := c.AuthMethod()
+	switch method {
+	case AuthMethodNone:
+		if c.Confidential {
+			return AuthMethodError{Method: method, Reason: "confidential clients must authenticate"}
+		}
+		return nil
+	case AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodClientSecretJWT,
+		AuthMethodPrivateKeyJWT, AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSClientAuth:
+	default:
+		return AuthMethodError{Method: method, Reason: "unknown authentication method"}
+	}
+	if !c.Confidential {
+		return AuthMethodError{Method: method, Reason: "public clients can't authenticate"}
+	}
+	if c.UsesSecret() && c.SecretHash == "" {
+		return AuthMethodError{Method: method, Reason: "client has no secret"}
+	}
+	if method == AuthMethodClientSecretJWT && c.EncryptedSecret == "" {
+		return AuthMethodError{Method: method, Reason: "client has no encrypted secret"}
+	}
+	if method != AuthMethodClientSecretJWT && c.EncryptedSecret != "" {
+		return AuthMethodError{Method: method, Reason: "only client_secret_jwt clients may store an encrypted secret"}
+	}
+	return nil
+}
+
+// CheckAuthMethod returns ErrAuthMethodNotAllowed if method isn't the token
+// endpoint authentication method the Client registered, and nil otherwise.
+func (c Client) CheckAuthMethod(method string) error {
+	if c.AuthMethod() != method {
+		return ErrAuthMethodNotAllowed
+	}
+	return nil
+}
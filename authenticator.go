@@ -0,0 +1,184 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	yall "yall.in"
+)
+
+// Authenticator verifies the credentials of Clients stored in a Storer.
+//
+// Whenever a Client successfully authenticates with a secret that was not
+// hashed using the DefaultSecretScheme and its current parameters, the
+// Authenticator re-hashes the secret and persists it using the Storer. This
+// lets secrets migrate to stronger schemes as they're used, without ever
+// needing to store them in plaintext.
+type Authenticator struct {
+	Storer Storer
+
+	// SecretFormat, if set, is used to reject malformed secrets with an
+	// ErrMalformedSecret error before the Storer is consulted. Secrets
+	// without its prefix, generated before it was used, are still checked
+	// against the Storer.
+	SecretFormat *SecretFormat
+}
+
+// AuthenticateSecret retrieves the Client with the passed ID from the Storer
+// and checks that secret is correct for it. The secret is accepted if it
+// matches the Client's own secret or any of the Client's unexpired Secrets;
+// the Secrets are tried whenever the Client's own secret doesn't match,
+// including when it has expired or its scheme is unsupported. If the Client
+// can't be found, ErrClientNotFound is returned. If the secret is incorrect,
+// ErrIncorrectSecret is returned. If none of the secrets match, the error
+// from checking the Client's own secret is returned. If the secret is
+// correct but the Client is disabled or suspended, ErrClientDisabled is
+// returned.
+//
+// The method should be AuthMethodClientSecretBasic or
+// AuthMethodClientSecretPost, depending on how the secret was presented. If
+// the Client didn't register that method, ErrAuthMethodNotAllowed is
+// returned.
+//
+// If the Authenticator has a SecretFormat and secret has its prefix but
+// isn't valid, an ErrMalformedSecret error is returned without looking the
+// Client up.
+//
+// When one of the Client's Secrets is used, its LastUsedAt is updated.
+// Failing to update it is logged, but does not fail authentication.
+//
+// If the secret is correct but needs to be re-hashed, the re-hashed secret
+// is stored and the updated Client is returned. Failing to store the
+// re-hashed secret is logged, but does not fail authentication.
+func (a Authenticator) AuthenticateSecret(ctx context.Context, method, clientID, secret string) (Client, error) {
+	if method != AuthMethodClientSecretBasic && method != AuthMethodClientSecretPost {
+		return Client{}, ErrAuthMethodNotAllowed
+	}
+	if a.SecretFormat != nil && a.SecretFormat.HasPrefix(secret) {
+		err := a.SecretFormat.Validate(secret)
+		if err != nil {
+			return Client{}, err
+		}
+	}
+	client, err := a.Storer.Get(ctx, clientID)
+	if err != nil {
+		return Client{}, err
+	}
+	err = client.CheckAuthMethod(method)
+	if err != nil {
+		return Client{}, err
+	}
+	err = client.CheckSecret(secret)
+	if err != nil {
+		return a.authenticateAdditionalSecret(ctx, client, secret, err)
+	}
+	err = client.CheckStatus()
+	if err != nil {
+		return Client{}, err
+	}
+	if !client.SecretNeedsRehash() {
+		return client, nil
+	}
+	log := yall.FromContext(ctx).WithField("client_id", client.ID).
+		WithField("secret_scheme", client.SecretScheme)
+	change, err := ChangeSecret([]byte(secret))
+	if err != nil {
+		log.WithError(err).Error("error re-hashing client secret")
+		return client, nil
+	}
+	err = a.Storer.Update(ctx, client.ID, client.Version, change)
+	if err != nil {
+		log.WithError(err).Error("error storing re-hashed client secret")
+		return client, nil
+	}
+	log.WithField("new_secret_scheme", *change.SecretScheme).Debug("re-hashed client secret")
+	client = Apply(change, client)
+	client.Version++
+	return client, nil
+}
+
+// AuthenticatePublic retrieves the Client with the passed ID from the Storer
+// and checks that it is a public Client that doesn't need to authenticate.
+// If the Client can't be found, ErrClientNotFound is returned. If the Client
+// registered an authentication method other than AuthMethodNone,
+// ErrAuthMethodNotAllowed is returned. If the Client is disabled or
+// suspended, ErrClientDisabled is returned.
+func (a Authenticator) AuthenticatePublic(ctx context.Context, clientID string) (Client, error) {
+	client, err := a.Storer.Get(ctx, clientID)
+	if err != nil {
+		return Client{}, err
+	}
+	err = client.CheckAuthMethod(AuthMethodNone)
+	if err != nil {
+		return Client{}, err
+	}
+	err = client.CheckStatus()
+	if err != nil {
+		return Client{}, err
+	}
+	return client, nil
+}
+
+// authenticateAdditionalSecret checks secret against the Secrets stored for
+// client, returning client if it matches an unexpired one. primaryErr is the
+// error from checking client's own secret, and is returned if secret doesn't
+// match any of the Secrets either.
+func (a Authenticator) authenticateAdditionalSecret(ctx context.Context, client Client, secret string, primaryErr error) (Client, error) {
+	secrets, err := a.Storer.ListSecrets(ctx, client.ID)
+	if err != nil {
+		return Client{}, err
+	}
+	now := time.Now()
+	match, err := CheckSecrets(secrets, secret, now)
+	if errors.Is(err, ErrIncorrectSecret) {
+		return Client{}, primaryErr
+	}
+	if err != nil {
+		return Client{}, err
+	}
+	err = client.CheckStatus()
+	if err != nil {
+		return Client{}, err
+	}
+	err = a.Storer.UseSecret(ctx, client.ID, match.ID, now)
+	if err != nil {
+		yall.FromContext(ctx).WithField("client_id", client.ID).WithField("secret_id", match.ID).
+			WithError(err).Error("error recording client secret use")
+	}
+	return client, nil
+}
+
+// SecretSchemeReport describes how many Clients have their secrets hashed
+// using each secret scheme.
+type SecretSchemeReport struct {
+	DefaultScheme string           // the scheme new secrets are hashed with
+	Counts        map[string]int64 // the number of Clients with secrets using each scheme
+}
+
+// Remaining returns the number of Clients whose secrets are not hashed using
+// the DefaultScheme.
+func (r SecretSchemeReport) Remaining() int64 {
+	var remaining int64
+	for scheme, count := range r.Counts {
+		if scheme == r.DefaultScheme {
+			continue
+		}
+		remaining += count
+	}
+	return remaining
+}
+
+// SecretSchemeReport returns a SecretSchemeReport for the Clients in the
+// Storer, which can be used to track the progress of migrating secrets to
+// the DefaultSecretScheme.
+func (a Authenticator) SecretSchemeReport(ctx context.Context) (SecretSchemeReport, error) {
+	counts, err := a.Storer.CountSecretSchemes(ctx)
+	if err != nil {
+		return SecretSchemeReport{}, err
+	}
+	return SecretSchemeReport{
+		DefaultScheme: DefaultSecretScheme(),
+		Counts:        counts,
+	}, nil
+}
@@ -0,0 +1,335 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func TestAuthenticatorRehashesLegacySecret(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		ch, err := clients.ChangeSecretWithScheme(clients.SecretSchemeSHA256, []byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		client = clients.Apply(ch, client)
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+
+		authenticator := clients.Authenticator{Storer: storer}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "wrong secret")
+		if !errors.Is(err, clients.ErrIncorrectSecret) {
+			t.Errorf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+		}
+		stored, err := storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.SecretScheme != clients.SecretSchemeSHA256 {
+			t.Errorf("Expected failed authentication to leave scheme as %q, got %q", clients.SecretSchemeSHA256, stored.SecretScheme)
+		}
+
+		res, err := authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "test secret")
+		if err != nil {
+			t.Fatalf("Error authenticating client: %s", err)
+		}
+		stored, err = storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.SecretScheme != clients.DefaultSecretScheme() {
+			t.Errorf("Expected secret to be re-hashed with %q, got %q", clients.DefaultSecretScheme(), stored.SecretScheme)
+		}
+		if res.SecretHash != stored.SecretHash {
+			t.Errorf("Expected returned client to have stored hash %q, got %q", stored.SecretHash, res.SecretHash)
+		}
+		if err = stored.CheckSecret("test secret"); err != nil {
+			t.Errorf("Expected re-hashed secret to be accepted, got %v", err)
+		}
+
+		report, err := authenticator.SecretSchemeReport(ctx)
+		if err != nil {
+			t.Fatalf("Error generating secret scheme report: %s", err)
+		}
+		if remaining := report.Remaining(); remaining != 0 {
+			t.Errorf("Expected no clients remaining on old schemes, got %d", remaining)
+		}
+	})
+}
+
+func TestAuthenticatorAcceptsUnexpiredSecrets(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		ch, err := clients.ChangeSecret([]byte("primary secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		client = clients.Apply(ch, client)
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		now := time.Now().Round(time.Millisecond)
+		current, err := clients.Secret{
+			ID:        uuidOrFail(t),
+			ClientID:  client.ID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		}.HashSecret([]byte("current secret"))
+		if err != nil {
+			t.Fatalf("Error hashing secret: %s", err)
+		}
+		expired, err := clients.Secret{
+			ID:        uuidOrFail(t),
+			ClientID:  client.ID,
+			CreatedAt: now.Add(-2 * time.Hour),
+			ExpiresAt: now.Add(-time.Hour),
+		}.HashSecret([]byte("expired secret"))
+		if err != nil {
+			t.Fatalf("Error hashing secret: %s", err)
+		}
+		err = storer.AddSecrets(ctx, []clients.Secret{current, expired})
+		if err != nil {
+			t.Fatalf("Error storing secrets: %s", err)
+		}
+
+		authenticator := clients.Authenticator{Storer: storer}
+		for _, secret := range []string{"primary secret", "current secret"} {
+			res, err := authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, secret)
+			if err != nil {
+				t.Errorf("Error authenticating with %q: %s", secret, err)
+			}
+			if res.ID != client.ID {
+				t.Errorf("Expected client %q, got %q", client.ID, res.ID)
+			}
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "expired secret")
+		if !errors.Is(err, clients.ErrSecretExpired) {
+			t.Errorf("Expected %v, got %v", clients.ErrSecretExpired, err)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "wrong secret")
+		if !errors.Is(err, clients.ErrIncorrectSecret) {
+			t.Errorf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+		}
+
+		secrets, err := storer.ListSecrets(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error listing secrets: %s", err)
+		}
+		for _, secret := range secrets {
+			if secret.ID == current.ID && secret.LastUsedAt.IsZero() {
+				t.Errorf("Expected secret %q to have been marked as used", secret.ID)
+			}
+			if secret.ID == expired.ID && !secret.LastUsedAt.IsZero() {
+				t.Errorf("Expected expired secret %q not to have been marked as used", secret.ID)
+			}
+		}
+	})
+}
+
+func TestAuthenticatorTriesSecretsAfterPrimaryMismatch(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		now := time.Now().Round(time.Millisecond)
+		expiredCh, err := clients.ChangeSecret([]byte("primary secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		expiredAt := now.Add(-time.Hour)
+		expiredCh.SecretExpiresAt = &expiredAt
+		unsupportedScheme, unsupportedHash := "unsupported-scheme", "unsupported hash"
+		unsupportedCh := clients.Change{SecretScheme: &unsupportedScheme, SecretHash: &unsupportedHash}
+
+		tests := map[string]struct {
+			change     clients.Change
+			primaryErr error
+		}{
+			"expired":     {change: expiredCh, primaryErr: clients.ErrSecretExpired},
+			"unsupported": {change: unsupportedCh, primaryErr: clients.ErrUnsupportedSecretScheme},
+		}
+		authenticator := clients.Authenticator{Storer: storer}
+		for name, test := range tests {
+			client := clients.Apply(test.change, clients.Client{
+				ID:           uuidOrFail(t),
+				Name:         "Test Client",
+				Confidential: true,
+				CreatedAt:    now,
+				CreatedBy:    "test",
+				CreatedByIP:  "127.0.0.1",
+			})
+			err = storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating %s client: %s", name, err)
+			}
+			secret, err := clients.Secret{
+				ID:        uuidOrFail(t),
+				ClientID:  client.ID,
+				CreatedAt: now,
+				ExpiresAt: now.Add(time.Hour),
+			}.HashSecret([]byte("additional secret"))
+			if err != nil {
+				t.Fatalf("Error hashing secret: %s", err)
+			}
+			err = storer.AddSecrets(ctx, []clients.Secret{secret})
+			if err != nil {
+				t.Fatalf("Error storing secret: %s", err)
+			}
+
+			res, err := authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "additional secret")
+			if err != nil {
+				t.Errorf("Expected additional secret to authenticate %s client, got %v", name, err)
+			}
+			if res.ID != client.ID {
+				t.Errorf("Expected client %q, got %q", client.ID, res.ID)
+			}
+			_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "primary secret")
+			if !errors.Is(err, test.primaryErr) {
+				t.Errorf("Expected %v for %s primary secret, got %v", test.primaryErr, name, err)
+			}
+		}
+	})
+}
+
+func TestAuthenticatorRejectsDisabledClient(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		ch, err := clients.ChangeSecret([]byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		client = clients.Apply(ch, client)
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		authenticator := clients.Authenticator{Storer: storer}
+
+		for _, status := range []string{clients.StatusDisabled, clients.StatusSuspended} {
+			ch, err = clients.ChangeStatus(status, "testing", "test", time.Now())
+			if err != nil {
+				t.Fatalf("Error generating status change: %s", err)
+			}
+			err = storer.Update(ctx, client.ID, client.Version, ch)
+			if err != nil {
+				t.Fatalf("Error updating client: %s", err)
+			}
+			client.Version++
+			_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "test secret")
+			if !errors.Is(err, clients.ErrClientDisabled) {
+				t.Errorf("Expected %v for %s client, got %v", clients.ErrClientDisabled, status, err)
+			}
+		}
+
+		ch, err = clients.ChangeStatus(clients.StatusActive, "testing", "test", time.Now())
+		if err != nil {
+			t.Fatalf("Error generating status change: %s", err)
+		}
+		err = storer.Update(ctx, client.ID, client.Version, ch)
+		if err != nil {
+			t.Fatalf("Error updating client: %s", err)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "test secret")
+		if err != nil {
+			t.Errorf("Expected re-enabled client to authenticate, got %v", err)
+		}
+	})
+}
+
+func TestAuthenticatorRefusesUnregisteredAuthMethods(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		confidential := clients.Client{
+			ID:                      uuidOrFail(t),
+			Name:                    "Test Confidential Client",
+			Confidential:            true,
+			TokenEndpointAuthMethod: clients.AuthMethodClientSecretPost,
+			CreatedAt:               time.Now().Round(time.Millisecond),
+			CreatedBy:               "test",
+			CreatedByIP:             "127.0.0.1",
+		}
+		ch, err := clients.ChangeSecret([]byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		confidential = clients.Apply(ch, confidential)
+		public := clients.Client{
+			ID:          uuidOrFail(t),
+			Name:        "Test Public Client",
+			CreatedAt:   time.Now().Round(time.Millisecond),
+			CreatedBy:   "test",
+			CreatedByIP: "127.0.0.1",
+		}
+		for _, client := range []clients.Client{confidential, public} {
+			if err = client.ValidateAuthMethod(); err != nil {
+				t.Errorf("Unexpected error validating auth method for %q: %s", client.Name, err)
+			}
+			err = storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+		}
+		authenticator := clients.Authenticator{Storer: storer}
+
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, confidential.ID, "test secret")
+		if !errors.Is(err, clients.ErrAuthMethodNotAllowed) {
+			t.Errorf("Expected %v, got %v", clients.ErrAuthMethodNotAllowed, err)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretPost, confidential.ID, "test secret")
+		if err != nil {
+			t.Errorf("Unexpected error authenticating client: %s", err)
+		}
+		_, err = authenticator.AuthenticatePublic(ctx, confidential.ID)
+		if !errors.Is(err, clients.ErrAuthMethodNotAllowed) {
+			t.Errorf("Expected %v, got %v", clients.ErrAuthMethodNotAllowed, err)
+		}
+		_, err = authenticator.AuthenticatePublic(ctx, public.ID)
+		if err != nil {
+			t.Errorf("Unexpected error authenticating public client: %s", err)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, public.ID, "")
+		if !errors.Is(err, clients.ErrAuthMethodNotAllowed) {
+			t.Errorf("Expected %v, got %v", clients.ErrAuthMethodNotAllowed, err)
+		}
+
+		invalid := clients.Apply(ch, clients.Client{Confidential: true, TokenEndpointAuthMethod: clients.AuthMethodNone})
+		var authMethodErr clients.AuthMethodError
+		if err = invalid.ValidateAuthMethod(); !errors.As(err, &authMethodErr) {
+			t.Errorf("Expected an AuthMethodError, got %v", err)
+		}
+	})
+}
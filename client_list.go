@@ -0,0 +1,132 @@
+package clients
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListClientsLimit is the number of Clients returned per page
+	// if ListClientsOptions.Limit isn't set.
+	DefaultListClientsLimit = 100
+	// MaxListClientsLimit is the most Clients returned per page.
+	MaxListClientsLimit = 1000
+)
+
+// ErrInvalidCursor is returned when a cursor passed to Storer.ListClients
+// wasn't returned by it.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListClientsOptions filters and paginates the Clients returned by
+// Storer.ListClients. The zero value lists every Client, one page at a
+// time.
+type ListClientsOptions struct {
+	CreatedBy     string    // only list Clients created by this HMAC key (optional)
+	Confidential  *bool     // only list confidential (true) or public (false) Clients (optional)
+	NamePrefix    string    // only list Clients whose names start with this, case-sensitively (optional)
+	CreatedSince  time.Time // only list Clients created at or after this (optional)
+	CreatedBefore time.Time // only list Clients created before this (optional)
+
+	// Limit is the most Clients to return. If unset, or more than
+	// MaxListClientsLimit, DefaultListClientsLimit or MaxListClientsLimit
+	// is used.
+	Limit int
+
+	// Cursor is the cursor returned with the previous page, to list the
+	// Clients after it. If unset, the first page is listed.
+	Cursor string
+}
+
+// PageSize returns the number of Clients to return per page, applying the
+// defaults and limits described on Limit.
+func (o ListClientsOptions) PageSize() int {
+	if o.Limit <= 0 {
+		return DefaultListClientsLimit
+	}
+	if o.Limit > MaxListClientsLimit {
+		return MaxListClientsLimit
+	}
+	return o.Limit
+}
+
+// Matches returns true if client passes the filters in the
+// ListClientsOptions. Pagination isn't taken into account.
+func (o ListClientsOptions) Matches(client Client) bool {
+	if o.CreatedBy != "" && client.CreatedBy != o.CreatedBy {
+		return false
+	}
+	if o.Confidential != nil && client.Confidential != *o.Confidential {
+		return false
+	}
+	if !strings.HasPrefix(client.Name, o.NamePrefix) {
+		return false
+	}
+	if !o.CreatedSince.IsZero() && client.CreatedAt.Before(o.CreatedSince) {
+		return false
+	}
+	if !o.CreatedBefore.IsZero() && !client.CreatedAt.Before(o.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// ClientCursor is a position in the stable order Storer.ListClients lists
+// Clients in: by CreatedAt, then by ID.
+type ClientCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorFor returns the ClientCursor positioned at client.
+func CursorFor(client Client) ClientCursor {
+	return ClientCursor{CreatedAt: client.CreatedAt, ID: client.ID}
+}
+
+// After returns true if client comes after the ClientCursor in the order
+// Storer.ListClients lists Clients in. Clients at the ClientCursor aren't
+// after it.
+func (c ClientCursor) After(client Client) bool {
+	if client.CreatedAt.Equal(c.CreatedAt) {
+		return c.ID < client.ID
+	}
+	return c.CreatedAt.Before(client.CreatedAt)
+}
+
+// String encodes the ClientCursor as an opaque string, to be returned by
+// Storer.ListClients and parsed with ParseClientCursor.
+func (c ClientCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID))
+}
+
+// ParseClientCursor parses a cursor encoded by ClientCursor.String. If the
+// cursor can't be parsed, ErrInvalidCursor is returned.
+func ParseClientCursor(cursor string) (ClientCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ClientCursor{}, ErrInvalidCursor
+	}
+	sep := strings.IndexByte(string(decoded), ':')
+	if sep < 0 {
+		return ClientCursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(string(decoded[:sep]), 10, 64)
+	if err != nil {
+		return ClientCursor{}, ErrInvalidCursor
+	}
+	return ClientCursor{CreatedAt: time.Unix(0, nanos), ID: string(decoded[sep+1:])}, nil
+}
+
+// ClientsByCreatedAt sorts `clients` in the order Storer.ListClients lists
+// them in: by their CreatedAt property, oldest first, then by their ID.
+func ClientsByCreatedAt(clients []Client) {
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].CreatedAt.Equal(clients[j].CreatedAt) {
+			return clients[i].ID < clients[j].ID
+		}
+		return clients[i].CreatedAt.Before(clients[j].CreatedAt)
+	})
+}
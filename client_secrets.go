@@ -0,0 +1,109 @@
+package clients
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrSecretAlreadyExists is returned when a Secret with the same ID
+	// already exists in a Storer.
+	ErrSecretAlreadyExists = errors.New("secret already exists")
+	// ErrSecretNotFound is returned when a Secret can't be located in a
+	// Storer.
+	ErrSecretNotFound = errors.New("secret not found")
+)
+
+// Secret represents an additional secret a confidential Client may
+// authenticate with, alongside the secret stored on the Client itself.
+// Clients may have any number of Secrets, which lets secrets be rotated
+// without an outage: a new Secret is added, deployed, and then the old one is
+// removed or allowed to expire.
+type Secret struct {
+	ID          string    // unique ID per secret
+	ClientID    string    // the ID of the Client this secret belongs to
+	Hash        string    // hash of the secret
+	Scheme      string    // the hashing scheme used for the secret
+	PepperID    string    // the ID of the pepper mixed into the secret before hashing; empty means none
+	Fingerprint string    // the SecretFingerprint of the secret, used to find the Secret if it leaks
+	CreatedAt   time.Time // timestamp of creation
+	ExpiresAt   time.Time // timestamp the secret stops being valid at; the zero value never expires
+	LastUsedAt  time.Time // timestamp the secret was last used to authenticate; the zero value means never
+	CreatedBy   string    // the HMAC key that created this secret
+	CreatedByIP string    // the IP that created this secret
+}
+
+// HashSecret returns a copy of the Secret with its Hash, Scheme, PepperID,
+// and Fingerprint set from value, using the scheme returned by
+// DefaultSecretScheme and the CurrentPepper.
+func (s Secret) HashSecret(value []byte) (Secret, error) {
+	change, err := ChangeSecret(value)
+	if err != nil {
+		return Secret{}, err
+	}
+	s.Hash = *change.SecretHash
+	s.Scheme = *change.SecretScheme
+	s.PepperID = *change.SecretPepperID
+	s.Fingerprint = *change.SecretFingerprint
+	return s, nil
+}
+
+// Check returns nil if the passed secret is correct for the Secret, or
+// ErrIncorrectSecret if the secret is incorrect. Expiration is not taken into
+// account; use Expired or CheckSecrets for that.
+func (s Secret) Check(attempt string) error {
+	return Client{SecretHash: s.Hash, SecretScheme: s.Scheme, SecretPepperID: s.PepperID}.CheckSecret(attempt)
+}
+
+// Expired returns true if the Secret has an expiration and it is not after
+// `now`.
+func (s Secret) Expired(now time.Time) bool {
+	if s.ExpiresAt.IsZero() {
+		return false
+	}
+	return !s.ExpiresAt.After(now)
+}
+
+// CheckSecrets returns the first Secret in `secrets` that has not expired as
+// of `now` and that attempt is correct for. If attempt is only correct for
+// expired Secrets, ErrSecretExpired is returned, like it is for a Client's
+// own expired secret. If attempt isn't correct for any Secret,
+// ErrIncorrectSecret is returned, unless a Secret could not be checked, in
+// which case the error from checking it is returned.
+func CheckSecrets(secrets []Secret, attempt string, now time.Time) (Secret, error) {
+	var checkErr error
+	var expired bool
+	for _, secret := range secrets {
+		err := secret.Check(attempt)
+		if err == nil && secret.Expired(now) {
+			expired = true
+			continue
+		}
+		if err == nil {
+			return secret, nil
+		}
+		if !errors.Is(err, ErrIncorrectSecret) && checkErr == nil {
+			checkErr = err
+		}
+	}
+	if expired {
+		return Secret{}, ErrSecretExpired
+	}
+	if checkErr != nil {
+		return Secret{}, checkErr
+	}
+	return Secret{}, ErrIncorrectSecret
+}
+
+// SecretsByCreatedAt sorts `secrets` by their CreatedAt property, with the
+// oldest Secrets returned first. Secrets created at the same time are sorted
+// by their ID.
+func SecretsByCreatedAt(secrets []Secret) {
+	sort.Slice(secrets, func(i, j int) bool {
+		if secrets[i].CreatedAt.Equal(secrets[j].CreatedAt) {
+			return secrets[i].ID < secrets[j].ID
+		}
+		return secrets[i].CreatedAt.Before(secrets[j].CreatedAt)
+	})
+}
@@ -0,0 +1,341 @@
+package clientauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	yall "yall.in"
+
+	"lockbox.dev/clients"
+)
+
+const (
+	// ErrCodeInvalidClient is the RFC 6749 error code returned when client
+	// authentication fails.
+	ErrCodeInvalidClient = "invalid_client"
+	// ErrCodeInvalidRequest is the RFC 6749 error code returned when a
+	// request is malformed, including when it uses more than one method of
+	// client authentication.
+	ErrCodeInvalidRequest = "invalid_request"
+	// ErrCodeServerError is the RFC 6749 error code returned when client
+	// authentication can't be completed because of an unexpected error.
+	ErrCodeServerError = "server_error"
+
+	// defaultRealm is the realm used in WWW-Authenticate headers if the
+	// Middleware doesn't have a Realm set.
+	defaultRealm = "lockbox"
+)
+
+type ctxKey struct{}
+
+// authenticated is the value stored in the request context.
+type authenticated struct {
+	client clients.Client
+	method string
+}
+
+// Middleware authenticates the client making a request before passing the
+// request to the handler it wraps.
+type Middleware struct {
+	// Storer is used to look up Clients and check their credentials.
+	Storer clients.Storer
+
+	// AssertionVerifier verifies JWT client assertions. If it has no
+	// Storer, Middleware's Storer is used. If unset, clients can't
+	// authenticate with assertions.
+	AssertionVerifier *clients.AssertionVerifier
+
+	// SecretFormat, if set, is used to reject malformed secrets without
+	// looking the client up. See clients.Authenticator.SecretFormat.
+	SecretFormat *clients.SecretFormat
+
+	// Throttle, if set, is used to check client secrets, locking out
+	// client IDs and IP addresses that fail too many times. If its
+	// Authenticator has no Storer or SecretFormat, Middleware's are used.
+	Throttle *clients.ThrottledAuthenticator
+
+	// ClientIP returns the IP address a request was made from, which
+	// Throttle tracks failed attempts by. If unset, the host of the
+	// request's RemoteAddr is used; set it when requests arrive through a
+	// proxy.
+	ClientIP func(*http.Request) string
+
+	// Realm is the realm included in WWW-Authenticate headers. If unset,
+	// "lockbox" is used.
+	Realm string
+}
+
+// credentials are the client credentials extracted from a request.
+type credentials struct {
+	method    string
+	clientID  string
+	secret    string
+	assertion string
+}
+
+// Wrap returns an http.Handler that authenticates the client making each
+// request, stores the authenticated Client in the request's context, and
+// calls next. If the client can't be authenticated, next isn't called and
+// an RFC 6749 error response is written instead.
+func (m Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds, errCode, description := extractCredentials(r)
+		if errCode != "" {
+			m.writeError(w, errCode, description)
+			return
+		}
+		client, err := m.authenticate(r, creds)
+		if err != nil {
+			log := yall.FromContext(r.Context()).WithField("client_id", creds.clientID).
+				WithField("auth_method", creds.method)
+			var lockout clients.LockoutError
+			if errors.As(err, &lockout) {
+				log.WithError(err).Debug("client authentication locked out")
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(lockout.Until.Sub(m.now()).Seconds())), 10))
+				m.writeError(w, ErrCodeInvalidClient, "too many failed authentication attempts")
+				return
+			}
+			if isAuthenticationFailure(err) {
+				log.WithError(err).Debug("client authentication failed")
+				m.writeError(w, ErrCodeInvalidClient, "client authentication failed")
+				return
+			}
+			log.WithError(err).Error("error authenticating client")
+			m.writeError(w, ErrCodeServerError, "")
+			return
+		}
+		ctx := context.WithValue(r.Context(), ctxKey{}, authenticated{client: client, method: client.AuthMethod()})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the Client authenticated by Middleware, and true if
+// there is one.
+func FromContext(ctx context.Context) (clients.Client, bool) {
+	auth, ok := ctx.Value(ctxKey{}).(authenticated)
+	return auth.client, ok
+}
+
+// MethodFromContext returns the token endpoint authentication method the
+// Client in ctx authenticated with, like clients.AuthMethodClientSecretBasic,
+// or an empty string if no Client was authenticated.
+func MethodFromContext(ctx context.Context) string {
+	auth, _ := ctx.Value(ctxKey{}).(authenticated)
+	return auth.method
+}
+
+// extractCredentials finds the client credentials in r. If r contains no
+// credentials, more than one set of credentials, or malformed credentials,
+// an RFC 6749 error code and description are returned.
+func extractCredentials(r *http.Request) (credentials, string, string) {
+	var found []credentials
+	if header := r.Header.Get("Authorization"); header != "" {
+		creds, isBasic, ok := parseBasicAuth(header)
+		if isBasic && !ok {
+			return credentials{}, ErrCodeInvalidClient, "malformed Authorization header"
+		}
+		if ok {
+			found = append(found, creds)
+		}
+	}
+	var form url.Values
+	if r.Method == http.MethodPost {
+		// only the body is considered; credentials must never be
+		// sent in the URL
+		err := r.ParseForm()
+		if err != nil {
+			return credentials{}, ErrCodeInvalidRequest, "invalid request body"
+		}
+		form = r.PostForm
+	}
+	clientID := form.Get("client_id")
+	if form.Get("client_secret") != "" {
+		found = append(found, credentials{
+			method:   clients.AuthMethodClientSecretPost,
+			clientID: clientID,
+			secret:   form.Get("client_secret"),
+		})
+	}
+	if form.Get("client_assertion") != "" || form.Get("client_assertion_type") != "" {
+		if form.Get("client_assertion_type") != clients.ClientAssertionTypeJWTBearer {
+			return credentials{}, ErrCodeInvalidClient, "unsupported client_assertion_type"
+		}
+		found = append(found, credentials{
+			method:    clients.AuthMethodPrivateKeyJWT,
+			clientID:  clientID,
+			assertion: form.Get("client_assertion"),
+		})
+	}
+	if len(found) > 1 {
+		return credentials{}, ErrCodeInvalidRequest, "more than one client authentication method used"
+	}
+	if len(found) == 1 {
+		return found[0], "", ""
+	}
+	if clientID == "" {
+		return credentials{}, ErrCodeInvalidClient, "no client credentials"
+	}
+	// a client_id without any other credentials is either a client
+	// authenticating with a TLS certificate or a public client
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return credentials{method: clients.AuthMethodTLSClientAuth, clientID: clientID}, "", ""
+	}
+	return credentials{method: clients.AuthMethodNone, clientID: clientID}, "", ""
+}
+
+// parseBasicAuth parses an HTTP Basic Authorization header. The client ID
+// and secret are form-encoded before being base64-encoded, as required by
+// RFC 6749, Section 2.3.1. The first bool returned is whether header uses
+// the Basic scheme, and the second is whether it could be parsed.
+func parseBasicAuth(header string) (credentials, bool, bool) {
+	const prefix = "basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return credentials{}, false, false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header[len(prefix):]))
+	if err != nil {
+		return credentials{}, true, false
+	}
+	pos := strings.IndexByte(string(decoded), ':')
+	if pos < 0 {
+		return credentials{}, true, false
+	}
+	rawID, rawSecret := string(decoded[:pos]), string(decoded[pos+1:])
+	clientID, err := url.QueryUnescape(rawID)
+	if err != nil {
+		return credentials{}, true, false
+	}
+	secret, err := url.QueryUnescape(rawSecret)
+	if err != nil {
+		return credentials{}, true, false
+	}
+	return credentials{
+		method:   clients.AuthMethodClientSecretBasic,
+		clientID: clientID,
+		secret:   secret,
+	}, true, true
+}
+
+// authenticate checks creds, returning the Client they authenticate.
+func (m Middleware) authenticate(r *http.Request, creds credentials) (clients.Client, error) {
+	authenticator := clients.Authenticator{Storer: m.Storer, SecretFormat: m.SecretFormat}
+	switch creds.method {
+	case clients.AuthMethodClientSecretBasic, clients.AuthMethodClientSecretPost:
+		if creds.clientID == "" {
+			return clients.Client{}, clients.ErrClientNotFound
+		}
+		if m.Throttle != nil {
+			throttle := *m.Throttle
+			if throttle.Authenticator.Storer == nil {
+				throttle.Authenticator.Storer = m.Storer
+			}
+			if throttle.Authenticator.SecretFormat == nil {
+				throttle.Authenticator.SecretFormat = m.SecretFormat
+			}
+			return throttle.AuthenticateSecret(r.Context(), creds.method, creds.clientID, creds.secret, m.clientIP(r))
+		}
+		return authenticator.AuthenticateSecret(r.Context(), creds.method, creds.clientID, creds.secret)
+	case clients.AuthMethodPrivateKeyJWT:
+		if m.AssertionVerifier == nil {
+			return clients.Client{}, clients.ErrAuthMethodNotAllowed
+		}
+		verifier := *m.AssertionVerifier
+		if verifier.Storer == nil {
+			verifier.Storer = m.Storer
+		}
+		client, err := verifier.Verify(r.Context(), creds.assertion)
+		if err != nil {
+			return clients.Client{}, err
+		}
+		// a client_id is optional, but must match the assertion if
+		// it's included
+		if creds.clientID != "" && creds.clientID != client.ID {
+			return clients.Client{}, clients.ErrInvalidAssertion
+		}
+		return client, nil
+	case clients.AuthMethodTLSClientAuth:
+		client, err := authenticator.AuthenticateTLS(r.Context(), creds.clientID, r.TLS)
+		if errors.Is(err, clients.ErrAuthMethodNotAllowed) {
+			// public clients may still present a certificate
+			return authenticator.AuthenticatePublic(r.Context(), creds.clientID)
+		}
+		return client, err
+	}
+	return authenticator.AuthenticatePublic(r.Context(), creds.clientID)
+}
+
+// clientIP returns the IP address r was made from.
+func (m Middleware) clientIP(r *http.Request) string {
+	if m.ClientIP != nil {
+		return m.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (m Middleware) now() time.Time {
+	if m.Throttle != nil && m.Throttle.Now != nil {
+		return m.Throttle.Now()
+	}
+	return time.Now()
+}
+
+// isAuthenticationFailure returns true if err means the client's
+// credentials were rejected, rather than that they couldn't be checked.
+func isAuthenticationFailure(err error) bool {
+	for _, failure := range []error{
+		clients.ErrClientNotFound,
+		clients.ErrIncorrectSecret,
+		clients.ErrMalformedSecret,
+		clients.ErrSecretExpired,
+		clients.ErrAuthMethodNotAllowed,
+		clients.ErrClientDisabled,
+		clients.ErrLockedOut,
+		clients.ErrCertificateMismatch,
+		clients.ErrInvalidAssertion,
+		clients.ErrAssertionReplayed,
+		clients.ErrJWKSFetch,
+		clients.ErrJWKSAddressNotAllowed,
+	} {
+		if errors.Is(err, failure) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes an RFC 6749, Section 5.2 error response.
+func (m Middleware) writeError(w http.ResponseWriter, code, description string) {
+	status := http.StatusBadRequest
+	switch code {
+	case ErrCodeInvalidClient:
+		realm := m.Realm
+		if realm == "" {
+			realm = defaultRealm
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+strings.ReplaceAll(realm, `"`, `\"`)+`"`)
+		status = http.StatusUnauthorized
+	case ErrCodeServerError:
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct { //nolint:errcheck // the status has already been written, nothing else can be done
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,415 @@
+package clientauth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+	"lockbox.dev/clients/clientauth"
+	"lockbox.dev/clients/storers/memory"
+)
+
+var errStorerFailure = errors.New("storer failure")
+
+// failingStorer is a clients.Storer that can't retrieve Clients.
+type failingStorer struct {
+	clients.Storer
+}
+
+func (failingStorer) Get(_ context.Context, _ string) (clients.Client, error) {
+	return clients.Client{}, errStorerFailure
+}
+
+type fixture struct {
+	storer           *memory.Storer
+	basicClient      clients.Client
+	postClient       clients.Client
+	publicClient     clients.Client
+	jwtClient        clients.Client
+	selfSignedClient clients.Client
+	jwtKey           ed25519.PrivateKey
+	cert             *x509.Certificate
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %s", err)
+	}
+	f := fixture{storer: storer}
+	create := func(id, method string, confidential bool, change clients.Change) clients.Client {
+		client := clients.Apply(change, clients.Client{
+			ID:                      id,
+			Name:                    id,
+			Confidential:            confidential,
+			TokenEndpointAuthMethod: method,
+			CreatedAt:               time.Now().Round(time.Millisecond),
+			CreatedBy:               "test",
+			CreatedByIP:             "127.0.0.1",
+		})
+		err := storer.Create(context.Background(), client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		return client
+	}
+	secret, err := clients.ChangeSecret([]byte("s3cr:t/+"))
+	if err != nil {
+		t.Fatalf("Error hashing secret: %s", err)
+	}
+	f.basicClient = create("basic:client", clients.AuthMethodClientSecretBasic, true, secret)
+	f.postClient = create("post-client", clients.AuthMethodClientSecretPost, true, secret)
+	f.publicClient = create("public-client", clients.AuthMethodNone, false, clients.Change{})
+	f.jwtClient = create("jwt-client", clients.AuthMethodPrivateKeyJWT, true, clients.Change{})
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	f.jwtKey = priv
+	rawKey, err := json.Marshal(map[string]string{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"kid": "key",
+		"alg": "EdDSA",
+		"x":   base64.RawURLEncoding.EncodeToString(pub),
+	})
+	if err != nil {
+		t.Fatalf("Error encoding key: %s", err)
+	}
+	key, err := clients.ParseKey(rawKey)
+	if err != nil {
+		t.Fatalf("Error parsing key: %s", err)
+	}
+	key.ClientID = f.jwtClient.ID
+	err = storer.AddKeys(context.Background(), []clients.Key{key})
+	if err != nil {
+		t.Fatalf("Error storing key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "self-signed-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	f.cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Error parsing certificate: %s", err)
+	}
+	thumbprint := clients.CertificateThumbprint(f.cert)
+	f.selfSignedClient = create("self-signed-client", clients.AuthMethodSelfSignedTLSClientAuth, true, clients.Change{TLSClientCertThumbprint: &thumbprint})
+	return f
+}
+
+func (f fixture) assertion(t *testing.T, clientID string) string {
+	t.Helper()
+	encode := func(in interface{}) string {
+		out, err := json.Marshal(in)
+		if err != nil {
+			t.Fatalf("Error encoding JSON: %s", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(out)
+	}
+	input := encode(map[string]string{"alg": "EdDSA", "kid": "key"}) + "." + encode(map[string]interface{}{
+		"iss": clientID,
+		"sub": clientID,
+		"aud": "https://auth.example.com/token",
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"jti": clientID + time.Now().String(),
+	})
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(f.jwtKey, []byte(input)))
+}
+
+func basicAuth(id, secret string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(url.QueryEscape(id)+":"+url.QueryEscape(secret)))
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	middleware := clientauth.Middleware{
+		Storer: f.storer,
+		AssertionVerifier: &clients.AssertionVerifier{
+			ReplayCache: &clients.MemoryReplayCache{},
+			Audiences:   []string{"https://auth.example.com/token"},
+		},
+	}
+
+	tests := map[string]struct {
+		middleware clientauth.Middleware
+		header     string
+		form       url.Values
+		tls        *tls.ConnectionState
+		status     int
+		errCode    string
+		clientID   string
+		method     string
+	}{
+		"basic": {
+			header:   basicAuth(f.basicClient.ID, "s3cr:t/+"),
+			status:   http.StatusOK,
+			clientID: f.basicClient.ID,
+			method:   clients.AuthMethodClientSecretBasic,
+		},
+		"basic-wrong-secret": {
+			header:  basicAuth(f.basicClient.ID, "wrong"),
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"basic-malformed": {
+			header:  "Basic not-base64!",
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"basic-unregistered-method": {
+			header:  basicAuth(f.postClient.ID, "s3cr:t/+"),
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"post": {
+			form:     url.Values{"client_id": {f.postClient.ID}, "client_secret": {"s3cr:t/+"}},
+			status:   http.StatusOK,
+			clientID: f.postClient.ID,
+			method:   clients.AuthMethodClientSecretPost,
+		},
+		"post-unknown-client": {
+			form:    url.Values{"client_id": {"unknown"}, "client_secret": {"s3cr:t/+"}},
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"multiple-methods": {
+			header:  basicAuth(f.basicClient.ID, "s3cr:t/+"),
+			form:    url.Values{"client_id": {f.postClient.ID}, "client_secret": {"s3cr:t/+"}},
+			status:  http.StatusBadRequest,
+			errCode: clientauth.ErrCodeInvalidRequest,
+		},
+		"no-credentials": {
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"public": {
+			form:     url.Values{"client_id": {f.publicClient.ID}},
+			status:   http.StatusOK,
+			clientID: f.publicClient.ID,
+			method:   clients.AuthMethodNone,
+		},
+		"confidential-without-credentials": {
+			form:    url.Values{"client_id": {f.basicClient.ID}},
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"assertion": {
+			form: url.Values{
+				"client_assertion_type": {clients.ClientAssertionTypeJWTBearer},
+				"client_assertion":      {f.assertion(t, f.jwtClient.ID)},
+			},
+			status:   http.StatusOK,
+			clientID: f.jwtClient.ID,
+			method:   clients.AuthMethodPrivateKeyJWT,
+		},
+		"assertion-client-id-mismatch": {
+			form: url.Values{
+				"client_id":             {f.publicClient.ID},
+				"client_assertion_type": {clients.ClientAssertionTypeJWTBearer},
+				"client_assertion":      {f.assertion(t, f.jwtClient.ID)},
+			},
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"assertion-unsupported-type": {
+			form: url.Values{
+				"client_assertion_type": {"urn:example:unsupported"},
+				"client_assertion":      {f.assertion(t, f.jwtClient.ID)},
+			},
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"assertion-no-verifier": {
+			middleware: clientauth.Middleware{Storer: f.storer},
+			form: url.Values{
+				"client_assertion_type": {clients.ClientAssertionTypeJWTBearer},
+				"client_assertion":      {f.assertion(t, f.jwtClient.ID)},
+			},
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"tls": {
+			form:     url.Values{"client_id": {f.selfSignedClient.ID}},
+			tls:      &tls.ConnectionState{PeerCertificates: []*x509.Certificate{f.cert}},
+			status:   http.StatusOK,
+			clientID: f.selfSignedClient.ID,
+			method:   clients.AuthMethodSelfSignedTLSClientAuth,
+		},
+		"tls-without-certificate": {
+			form:    url.Values{"client_id": {f.selfSignedClient.ID}},
+			tls:     &tls.ConnectionState{},
+			status:  http.StatusUnauthorized,
+			errCode: clientauth.ErrCodeInvalidClient,
+		},
+		"tls-public-client": {
+			form:     url.Values{"client_id": {f.publicClient.ID}},
+			tls:      &tls.ConnectionState{PeerCertificates: []*x509.Certificate{f.cert}},
+			status:   http.StatusOK,
+			clientID: f.publicClient.ID,
+			method:   clients.AuthMethodNone,
+		},
+		"storer-failure": {
+			middleware: clientauth.Middleware{Storer: failingStorer{Storer: f.storer}},
+			header:     basicAuth(f.basicClient.ID, "s3cr:t/+"),
+			status:     http.StatusInternalServerError,
+			errCode:    clientauth.ErrCodeServerError,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mw := middleware
+			if test.middleware.Storer != nil {
+				mw = test.middleware
+			}
+			var called bool
+			handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				client, ok := clientauth.FromContext(r.Context())
+				if !ok {
+					t.Error("Expected client in context")
+				}
+				if client.ID != test.clientID {
+					t.Errorf("Expected client %q, got %q", test.clientID, client.ID)
+				}
+				if method := clientauth.MethodFromContext(r.Context()); method != test.method {
+					t.Errorf("Expected method %q, got %q", test.method, method)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "https://auth.example.com/token", strings.NewReader(test.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			req.TLS = test.tls
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Errorf("Expected status %d, got %d: %s", test.status, w.Code, w.Body.String())
+			}
+			if test.errCode == "" {
+				if !called {
+					t.Error("Expected wrapped handler to be called")
+				}
+				return
+			}
+			if called {
+				t.Error("Expected wrapped handler not to be called")
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("Error decoding response: %s", err)
+			}
+			if body.Error != test.errCode {
+				t.Errorf("Expected error %q, got %q", test.errCode, body.Error)
+			}
+			if test.errCode == clientauth.ErrCodeInvalidClient && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header")
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("Expected Cache-Control: no-store, got %q", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestMiddlewareThrottle(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	now := time.Now()
+	middleware := clientauth.Middleware{
+		Storer: f.storer,
+		Throttle: &clients.ThrottledAuthenticator{
+			Attempts:          &clients.MemoryAttemptStore{Now: func() time.Time { return now }},
+			MaxClientFailures: 2,
+			MaxIPFailures:     3,
+			LockoutBase:       time.Minute,
+			Now:               func() time.Time { return now },
+		},
+		ClientIP: func(r *http.Request) string { return r.Header.Get("Test-Client-IP") },
+	}
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	attempt := func(ip, clientID, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "https://auth.example.com/token", nil)
+		req.Header.Set("Authorization", basicAuth(clientID, secret))
+		req.Header.Set("Test-Client-IP", ip)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// too many failures for a client ID locks it out, even with the
+	// right secret
+	for i := 0; i < 2; i++ {
+		if w := attempt("192.0.2.1", f.basicClient.ID, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+		}
+	}
+	w := attempt("192.0.2.2", f.basicClient.ID, "s3cr:t/+")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", got)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("Error decoding response: %s", err)
+	}
+	if body.Error != clientauth.ErrCodeInvalidClient {
+		t.Errorf("Expected error %q, got %q", clientauth.ErrCodeInvalidClient, body.Error)
+	}
+
+	// too many failures from an IP address locks it out for every client
+	for i := 0; i < 3; i++ {
+		if w := attempt("192.0.2.3", "unknown", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+		}
+	}
+	// the lockout is checked before anything about the client is
+	w = attempt("192.0.2.3", f.postClient.ID, "s3cr:t/+")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected locked out IP address to be rejected with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
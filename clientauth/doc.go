@@ -0,0 +1,14 @@
+// Package clientauth provides net/http middleware that authenticates the
+// client making a request.
+//
+// The middleware extracts client credentials using whichever method the
+// client used: HTTP Basic authentication or a client_secret in the request
+// body, as described in RFC 6749, Section 2.3.1; a JWT client assertion, as
+// described in RFC 7523; a TLS client certificate, as described in RFC 8705;
+// or just a client_id, for public clients. The credentials are checked
+// against a lockbox.dev/clients.Storer, and the authenticated Client is made
+// available to the wrapped handler through the request's context.
+//
+// Requests that fail to authenticate receive an RFC 6749 invalid_client
+// error response, and are never passed to the wrapped handler.
+package clientauth
@@ -1,17 +1,10 @@
 package clients
 
 import (
-	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/hex"
 	"errors"
 	"time"
 )
 
-const (
-	secretSchemeSHA256 = "sha256" // #nosec
-)
-
 var (
 	// ErrClientAlreadyExists is returned when a client with the same ID
 	// already exists in a Storer.
@@ -25,47 +18,136 @@ var (
 	// ErrUnsupportedSecretScheme is returned when a client uses a secret
 	// scheme that we don't know how to use.
 	ErrUnsupportedSecretScheme = errors.New("an unsupported secret scheme was used")
+	// ErrSecretExpired is returned when a client tries to authenticate
+	// with a correct secret that has expired.
+	ErrSecretExpired = errors.New("client secret has expired")
+	// ErrVersionConflict is returned when a Client is updated, but it has
+	// been updated since the expected version was retrieved.
+	ErrVersionConflict = errors.New("client has been updated since it was retrieved")
 )
 
 // Client represents an API client.
 type Client struct {
-	ID           string    // unique ID per client
-	Name         string    // friendly name for this client
-	SecretHash   string    // hash of unique secret to authenticate with (optional)
-	SecretScheme string    // the hashing scheme used for the secret
-	Confidential bool      // whether this is a confidential (true) or public (false) client
-	CreatedAt    time.Time // timestamp of creation
-	CreatedBy    string    // the HMAC key that created this client
-	CreatedByIP  string    // the IP that created this client
+	ID                      string    // unique ID per client
+	Name                    string    // friendly name for this client
+	SecretHash              string    // hash of unique secret to authenticate with (optional)
+	SecretScheme            string    // the hashing scheme used for the secret
+	SecretPepperID          string    // the ID of the pepper mixed into the secret before hashing; empty means none
+	SecretFingerprint       string    // the SecretFingerprint of the secret, used to find the Client if the secret leaks
+	EncryptedSecret         string    // the secret, encrypted by a SecretKeyring; only for client_secret_jwt clients
+	SecretRotatedAt         time.Time // timestamp the secret was last set; the zero value means it was never recorded
+	SecretExpiresAt         time.Time // timestamp the secret stops being valid at; the zero value never expires
+	Confidential            bool      // whether this is a confidential (true) or public (false) client
+	GrantTypes              []string  // the OAuth 2 grant types this client may use
+	ResponseTypes           []string  // the OAuth 2 response types this client may use
+	TokenEndpointAuthMethod string    // how this client authenticates; empty means DefaultAuthMethod
+	JWKSURI                 string    // URL of the client's JSON Web Key Set, used instead of registered Keys (optional)
+	TLSClientAuthSubjectDN  string    // subject DN the client's certificate must have, for tls_client_auth (optional)
+	TLSClientAuthSANDNS     string    // DNS name SAN the client's certificate must have, for tls_client_auth (optional)
+	TLSClientAuthSANURI     string    // URI SAN the client's certificate must have, for tls_client_auth (optional)
+	TLSClientAuthSANIP      string    // IP address SAN the client's certificate must have, for tls_client_auth (optional)
+	TLSClientAuthSANEmail   string    // email address SAN the client's certificate must have, for tls_client_auth (optional)
+	TLSClientCertThumbprint string    // SHA-256 thumbprint of the client's certificate, for self_signed_tls_client_auth (optional)
+	Status                  string    // the lifecycle status of this client; empty means active
+	StatusReason            string    // why the status was last changed
+	StatusChangedAt         time.Time // timestamp the status was last changed
+	StatusChangedBy         string    // the HMAC key that last changed the status
+	ClientURI               string    // URL of the client's home page (optional)
+	LogoURI                 string    // URL of the client's logo (optional)
+	PolicyURI               string    // URL of the client's privacy policy (optional)
+	TOSURI                  string    // URL of the client's terms of service (optional)
+	Contacts                []string  // email addresses of the people responsible for the client
+	SoftwareID              string    // identifier of the software the client is running (optional)
+	SoftwareVersion         string    // version of the software the client is running (optional)
+	CreatedAt               time.Time // timestamp of creation
+	CreatedBy               string    // the HMAC key that created this client
+	CreatedByIP             string    // the IP that created this client
+	Version                 int64     // incremented every time the client is updated
 }
 
 // CheckSecret returns nil if the passed secret is correct for the Client, or
-// ErrIncorrectSecret if the secret is incorrect. Any other error signals data
-// corruption.
+// ErrIncorrectSecret if the secret is incorrect. If the secret is correct but
+// has expired, ErrSecretExpired is returned. The SecretHasher registered
+// for the Client's SecretScheme is used to check the secret; if none is
+// registered, ErrUnsupportedSecretScheme is returned. If the secret was
+// peppered with a pepper that isn't registered, ErrUnknownPepper is
+// returned. Any other error signals data corruption.
 func (c Client) CheckSecret(attempt string) error {
-	switch c.SecretScheme {
-	case secretSchemeSHA256:
-		hashed, err := hex.DecodeString(c.SecretHash)
-		if err != nil {
-			return err
-		}
-		candidate := sha256.New().Sum([]byte(attempt))
-		if subtle.ConstantTimeCompare(candidate, hashed) != 1 {
-			return ErrIncorrectSecret
-		}
-	default:
-		return ErrUnsupportedSecretScheme
+	hasher, err := SecretHasherFor(c.SecretScheme)
+	if err != nil {
+		return err
+	}
+	peppered, err := pepperSecret(c.SecretPepperID, []byte(attempt))
+	if err != nil {
+		return err
+	}
+	err = hasher.Verify(c.SecretHash, peppered)
+	if err != nil {
+		return err
+	}
+	if c.SecretExpired(time.Now()) {
+		return ErrSecretExpired
 	}
 	return nil
 }
 
+// SecretExpired returns true if the Client's secret has an expiration and it
+// is not after `now`.
+func (c Client) SecretExpired(now time.Time) bool {
+	if c.SecretExpiresAt.IsZero() {
+		return false
+	}
+	return !c.SecretExpiresAt.After(now)
+}
+
+// SecretNeedsRehash returns true if the Client's secret is not hashed using
+// the DefaultSecretScheme, was hashed with parameters other than the ones
+// currently configured for it, or wasn't peppered with the CurrentPepper.
+func (c Client) SecretNeedsRehash() bool {
+	scheme := DefaultSecretScheme()
+	if c.SecretScheme != scheme || c.SecretPepperID != CurrentPepper() {
+		return true
+	}
+	hasher, err := SecretHasherFor(scheme)
+	if err != nil {
+		return false
+	}
+	return hasher.NeedsRehash(c.SecretHash)
+}
+
 // Change represents a change we'd like to make to a Client. Nil values always
 // represent "no change", whereas empty values will be interpreted as a desire
 // to set the property to the empty value.
 type Change struct {
-	Name         *string
-	SecretHash   *string
-	SecretScheme *string
+	Name                    *string
+	SecretHash              *string
+	SecretScheme            *string
+	SecretPepperID          *string
+	SecretFingerprint       *string
+	EncryptedSecret         *string
+	SecretRotatedAt         *time.Time
+	SecretExpiresAt         *time.Time
+	GrantTypes              *[]string
+	ResponseTypes           *[]string
+	TokenEndpointAuthMethod *string
+	JWKSURI                 *string
+	TLSClientAuthSubjectDN  *string
+	TLSClientAuthSANDNS     *string
+	TLSClientAuthSANURI     *string
+	TLSClientAuthSANIP      *string
+	TLSClientAuthSANEmail   *string
+	TLSClientCertThumbprint *string
+	Status                  *string
+	StatusReason            *string
+	StatusChangedAt         *time.Time
+	StatusChangedBy         *string
+	ClientURI               *string
+	LogoURI                 *string
+	PolicyURI               *string
+	TOSURI                  *string
+	Contacts                *[]string
+	SoftwareID              *string
+	SoftwareVersion         *string
 }
 
 // IsEmpty returns true if none of the fields in Change are set.
@@ -79,16 +161,117 @@ func (c Change) IsEmpty() bool {
 	if c.SecretScheme != nil {
 		return false
 	}
+	if c.SecretPepperID != nil {
+		return false
+	}
+	if c.SecretFingerprint != nil {
+		return false
+	}
+	if c.SecretRotatedAt != nil {
+		return false
+	}
+	if c.SecretExpiresAt != nil {
+		return false
+	}
+	if c.EncryptedSecret != nil {
+		return false
+	}
+	if c.GrantTypes != nil {
+		return false
+	}
+	if c.ResponseTypes != nil {
+		return false
+	}
+	if c.TokenEndpointAuthMethod != nil {
+		return false
+	}
+	if c.JWKSURI != nil {
+		return false
+	}
+	if c.TLSClientAuthSubjectDN != nil {
+		return false
+	}
+	if c.TLSClientAuthSANDNS != nil {
+		return false
+	}
+	if c.TLSClientAuthSANURI != nil {
+		return false
+	}
+	if c.TLSClientAuthSANIP != nil {
+		return false
+	}
+	if c.TLSClientAuthSANEmail != nil {
+		return false
+	}
+	if c.TLSClientCertThumbprint != nil {
+		return false
+	}
+	if c.Status != nil {
+		return false
+	}
+	if c.StatusReason != nil {
+		return false
+	}
+	if c.StatusChangedAt != nil {
+		return false
+	}
+	if c.StatusChangedBy != nil {
+		return false
+	}
+	if c.ClientURI != nil {
+		return false
+	}
+	if c.LogoURI != nil {
+		return false
+	}
+	if c.PolicyURI != nil {
+		return false
+	}
+	if c.TOSURI != nil {
+		return false
+	}
+	if c.Contacts != nil {
+		return false
+	}
+	if c.SoftwareID != nil {
+		return false
+	}
+	if c.SoftwareVersion != nil {
+		return false
+	}
 	return true
 }
 
-// ChangeSecret generates a Change that will update a Client's secret.
+// ChangeSecret generates a Change that will update a Client's secret, hashed
+// using the scheme returned by DefaultSecretScheme.
 func ChangeSecret(newSecret []byte) (Change, error) {
-	secret := hex.EncodeToString(sha256.New().Sum(newSecret))
-	scheme := secretSchemeSHA256
+	return ChangeSecretWithScheme(DefaultSecretScheme(), newSecret)
+}
+
+// ChangeSecretWithScheme generates a Change that will update a Client's
+// secret, peppered with the CurrentPepper, if any, and hashed using the
+// SecretHasher registered for scheme. If no SecretHasher is registered for
+// scheme, ErrUnsupportedSecretScheme is returned.
+func ChangeSecretWithScheme(scheme string, newSecret []byte) (Change, error) {
+	hasher, err := SecretHasherFor(scheme)
+	if err != nil {
+		return Change{}, err
+	}
+	pepperID := CurrentPepper()
+	peppered, err := pepperSecret(pepperID, newSecret)
+	if err != nil {
+		return Change{}, err
+	}
+	secret, err := hasher.Hash(peppered)
+	if err != nil {
+		return Change{}, err
+	}
+	fingerprint := SecretFingerprint(newSecret)
 	return Change{
-		SecretHash:   &secret,
-		SecretScheme: &scheme,
+		SecretHash:        &secret,
+		SecretScheme:      &scheme,
+		SecretPepperID:    &pepperID,
+		SecretFingerprint: &fingerprint,
 	}, nil
 }
 
@@ -107,5 +290,83 @@ func Apply(change Change, client Client) Client {
 	if change.SecretScheme != nil {
 		res.SecretScheme = *change.SecretScheme
 	}
+	if change.SecretPepperID != nil {
+		res.SecretPepperID = *change.SecretPepperID
+	}
+	if change.SecretFingerprint != nil {
+		res.SecretFingerprint = *change.SecretFingerprint
+	}
+	if change.SecretRotatedAt != nil {
+		res.SecretRotatedAt = *change.SecretRotatedAt
+	}
+	if change.SecretExpiresAt != nil {
+		res.SecretExpiresAt = *change.SecretExpiresAt
+	}
+	if change.EncryptedSecret != nil {
+		res.EncryptedSecret = *change.EncryptedSecret
+	}
+	if change.GrantTypes != nil {
+		res.GrantTypes = *change.GrantTypes
+	}
+	if change.ResponseTypes != nil {
+		res.ResponseTypes = *change.ResponseTypes
+	}
+	if change.TokenEndpointAuthMethod != nil {
+		res.TokenEndpointAuthMethod = *change.TokenEndpointAuthMethod
+	}
+	if change.JWKSURI != nil {
+		res.JWKSURI = *change.JWKSURI
+	}
+	if change.TLSClientAuthSubjectDN != nil {
+		res.TLSClientAuthSubjectDN = *change.TLSClientAuthSubjectDN
+	}
+	if change.TLSClientAuthSANDNS != nil {
+		res.TLSClientAuthSANDNS = *change.TLSClientAuthSANDNS
+	}
+	if change.TLSClientAuthSANURI != nil {
+		res.TLSClientAuthSANURI = *change.TLSClientAuthSANURI
+	}
+	if change.TLSClientAuthSANIP != nil {
+		res.TLSClientAuthSANIP = *change.TLSClientAuthSANIP
+	}
+	if change.TLSClientAuthSANEmail != nil {
+		res.TLSClientAuthSANEmail = *change.TLSClientAuthSANEmail
+	}
+	if change.TLSClientCertThumbprint != nil {
+		res.TLSClientCertThumbprint = *change.TLSClientCertThumbprint
+	}
+	if change.Status != nil {
+		res.Status = *change.Status
+	}
+	if change.StatusReason != nil {
+		res.StatusReason = *change.StatusReason
+	}
+	if change.StatusChangedAt != nil {
+		res.StatusChangedAt = *change.StatusChangedAt
+	}
+	if change.StatusChangedBy != nil {
+		res.StatusChangedBy = *change.StatusChangedBy
+	}
+	if change.ClientURI != nil {
+		res.ClientURI = *change.ClientURI
+	}
+	if change.LogoURI != nil {
+		res.LogoURI = *change.LogoURI
+	}
+	if change.PolicyURI != nil {
+		res.PolicyURI = *change.PolicyURI
+	}
+	if change.TOSURI != nil {
+		res.TOSURI = *change.TOSURI
+	}
+	if change.Contacts != nil {
+		res.Contacts = *change.Contacts
+	}
+	if change.SoftwareID != nil {
+		res.SoftwareID = *change.SoftwareID
+	}
+	if change.SoftwareVersion != nil {
+		res.SoftwareVersion = *change.SoftwareVersion
+	}
 	return res
 }
@@ -9,9 +9,12 @@
 // The clients package provides the definitions of the service and its
 // boundaries. It sets up the Client type, which represents an API consumer,
 // the RedirectURI type, which represents a URI that a client's authentication
-// requests are able to be redirected to, and the Storer interface, which
-// defines how to implement data storage backends for these Clients and
-// RedirectURIs.
+// requests are able to be redirected to, the Scope type, which represents a
+// scope a client is allowed to use, the Secret type, which represents an
+// additional secret a client can authenticate with, the Key type, which
+// represents a public key a client can authenticate with, and the Storer
+// interface, which defines how to implement data storage backends for these
+// Clients, RedirectURIs, Scopes, Secrets, and Keys.
 //
 // This package can be thought of as providing the types and helpers that form
 // the conceptual framework of the subsystem, but with very little
@@ -9,6 +9,7 @@ require (
 	github.com/hashicorp/go-uuid v1.0.3
 	github.com/lib/pq v1.10.7
 	github.com/rubenv/sql-migrate v0.0.0-20200616145509-8d140a17f351
+	golang.org/x/crypto v0.10.0
 	impractical.co/userip v0.1.1
 	lockbox.dev/hmac v0.2.0
 	yall.in v0.0.8
@@ -22,6 +23,7 @@ require (
 	github.com/hashicorp/golang-lru v0.5.4 // indirect
 	github.com/mitchellh/go-testing-interface v1.14.1 // indirect
 	github.com/pborman/uuid v1.2.1 // indirect
+	golang.org/x/sys v0.9.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/gorp.v1 v1.7.2 // indirect
 )
@@ -0,0 +1,143 @@
+package clients
+
+import (
+	"fmt"
+)
+
+const (
+	// GrantTypeAuthorizationCode is the authorization code grant, defined in
+	// RFC 6749, Section 4.1.
+	GrantTypeAuthorizationCode = "authorization_code"
+	// GrantTypeClientCredentials is the client credentials grant, defined in
+	// RFC 6749, Section 4.4. Only confidential clients may use it.
+	GrantTypeClientCredentials = "client_credentials"
+	// GrantTypeRefreshToken is the refresh token grant, defined in RFC 6749,
+	// Section 6.
+	GrantTypeRefreshToken = "refresh_token"
+	// GrantTypeDeviceCode is the device authorization grant, defined in RFC
+	// 8628.
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+	// GrantTypeImplicit is the implicit grant, defined in RFC 6749, Section
+	// 4.2.
+	GrantTypeImplicit = "implicit"
+
+	// ResponseTypeCode is the response type used to request an
+	// authorization code.
+	ResponseTypeCode = "code"
+	// ResponseTypeToken is the response type used to request an access token
+	// using the implicit grant.
+	ResponseTypeToken = "token"
+)
+
+var (
+	grantTypes = map[string]struct{}{
+		GrantTypeAuthorizationCode: {},
+		GrantTypeClientCredentials: {},
+		GrantTypeRefreshToken:      {},
+		GrantTypeDeviceCode:        {},
+		GrantTypeImplicit:          {},
+	}
+	responseTypes = map[string]struct{}{
+		ResponseTypeCode:  {},
+		ResponseTypeToken: {},
+	}
+)
+
+// GrantTypeError is returned when a Client's grant types or response types
+// are invalid or inconsistent with each other or with whether the Client is
+// confidential.
+type GrantTypeError struct {
+	GrantType    string // the grant type that is invalid, if any
+	ResponseType string // the response type that is invalid, if any
+	Reason       string // why the grant type or response type is invalid
+}
+
+// Error fills the error interface for GrantTypeError.
+func (e GrantTypeError) Error() string {
+	if e.GrantType != "" {
+		return fmt.Sprintf("invalid grant type %q: %s", e.GrantType, e.Reason)
+	}
+	if e.ResponseType != "" {
+		return fmt.Sprintf("invalid response type %q: %s", e.ResponseType, e.Reason)
+	}
+	return e.Reason
+}
+
+// DefaultGrantTypes returns the grant types and response types that should
+// be used for a Client that doesn't specify any.
+func DefaultGrantTypes() ([]string, []string) {
+	return []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken}, []string{ResponseTypeCode}
+}
+
+// ValidateGrantTypes checks that the Client's GrantTypes and ResponseTypes
+// are known, not duplicated, consistent with each other, and permitted for
+// the Client given whether it's confidential. If they're not, a
+// GrantTypeError is returned.
+func (c Client) ValidateGrantTypes() error {
+	if len(c.GrantTypes) < 1 {
+		return GrantTypeError{Reason: "at least one grant type is required"}
+	}
+	seenGrants := map[string]struct{}{}
+	for _, grantType := range c.GrantTypes {
+		if _, ok := grantTypes[grantType]; !ok {
+			return GrantTypeError{GrantType: grantType, Reason: "unknown grant type"}
+		}
+		if _, ok := seenGrants[grantType]; ok {
+			return GrantTypeError{GrantType: grantType, Reason: "duplicate grant type"}
+		}
+		seenGrants[grantType] = struct{}{}
+		if grantType == GrantTypeClientCredentials && !c.Confidential {
+			return GrantTypeError{GrantType: grantType, Reason: "only confidential clients may use this grant type"}
+		}
+	}
+	seenResponses := map[string]struct{}{}
+	for _, responseType := range c.ResponseTypes {
+		if _, ok := responseTypes[responseType]; !ok {
+			return GrantTypeError{ResponseType: responseType, Reason: "unknown response type"}
+		}
+		if _, ok := seenResponses[responseType]; ok {
+			return GrantTypeError{ResponseType: responseType, Reason: "duplicate response type"}
+		}
+		seenResponses[responseType] = struct{}{}
+	}
+	// the authorization code and implicit grants are the only ones that
+	// use the authorization endpoint, and so the only ones that use
+	// response types. Each needs its response type, and each response
+	// type needs its grant.
+	pairs := [][2]string{
+		{GrantTypeAuthorizationCode, ResponseTypeCode},
+		{GrantTypeImplicit, ResponseTypeToken},
+	}
+	for _, pair := range pairs {
+		_, hasGrant := seenGrants[pair[0]]
+		_, hasResponse := seenResponses[pair[1]]
+		if hasGrant && !hasResponse {
+			return GrantTypeError{GrantType: pair[0], Reason: fmt.Sprintf("requires the %q response type", pair[1])}
+		}
+		if hasResponse && !hasGrant {
+			return GrantTypeError{ResponseType: pair[1], Reason: fmt.Sprintf("requires the %q grant type", pair[0])}
+		}
+	}
+	return nil
+}
+
+// AllowsGrantType returns true if the Client is allowed to use grantType.
+func (c Client) AllowsGrantType(grantType string) bool {
+	for _, allowed := range c.GrantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsResponseType returns true if the Client is allowed to use
+// responseType.
+func (c Client) AllowsResponseType(responseType string) bool {
+	for _, allowed := range c.ResponseTypes {
+		if allowed == responseType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package clients
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// KeyTypeRSA is the JWK key type for RSA keys.
+	KeyTypeRSA = "RSA"
+	// KeyTypeEC is the JWK key type for elliptic curve keys.
+	KeyTypeEC = "EC"
+	// KeyTypeOKP is the JWK key type for octet key pairs, used for Ed25519
+	// keys.
+	KeyTypeOKP = "OKP"
+
+	// MinRSAKeyBits is the smallest RSA modulus, in bits, that a Key may
+	// have.
+	MinRSAKeyBits = 2048
+)
+
+var (
+	// keyAlgorithms maps the JWS algorithms a Key may be registered for to
+	// the key type and, where it matters, the curve they require.
+	keyAlgorithms = map[string]struct {
+		keyType string
+		curve   string
+	}{
+		"RS256": {keyType: KeyTypeRSA},
+		"RS384": {keyType: KeyTypeRSA},
+		"RS512": {keyType: KeyTypeRSA},
+		"PS256": {keyType: KeyTypeRSA},
+		"PS384": {keyType: KeyTypeRSA},
+		"PS512": {keyType: KeyTypeRSA},
+		"ES256": {keyType: KeyTypeEC, curve: "P-256"},
+		"ES384": {keyType: KeyTypeEC, curve: "P-384"},
+		"ES512": {keyType: KeyTypeEC, curve: "P-521"},
+		"EdDSA": {keyType: KeyTypeOKP, curve: "Ed25519"},
+	}
+
+	// privateKeyParams are the JWK parameters that hold private or
+	// symmetric key material, which must never be registered.
+	privateKeyParams = []string{"d", "p", "q", "dp", "dq", "qi", "oth", "k"}
+)
+
+// Key represents a public key, encoded as a JSON Web Key (RFC 7517), that a
+// Client has registered to authenticate with using private_key_jwt.
+type Key struct {
+	ID          string    // the key ID (kid), unique per client
+	ClientID    string    // the ID of the Client this key belongs to
+	KeyType     string    // the JWK key type (kty)
+	Algorithm   string    // the JWS algorithm (alg) the key is used with
+	JWK         string    // the JSON encoding of the key
+	CreatedAt   time.Time // the timestamp this key was registered at
+	CreatedBy   string    // the HMAC key that registered this key
+	CreatedByIP string    // the IP that registered this key
+}
+
+// InvalidKeyError is returned when a JWK can't be registered as a Key.
+type InvalidKeyError struct {
+	ID     string // the key ID (kid) of the invalid key, if known
+	Reason string // why the key is invalid
+}
+
+// Error fills the error interface for InvalidKeyError.
+func (e InvalidKeyError) Error() string {
+	if e.ID == "" {
+		return "invalid key: " + e.Reason
+	}
+	return fmt.Sprintf("invalid key %q: %s", e.ID, e.Reason)
+}
+
+// KeyAlreadyExistsError is returned when a Key with the same ID is already
+// registered for a Client in a Storer.
+type KeyAlreadyExistsError struct {
+	ID       string // the ID of the key that already exists
+	ClientID string // the ID of the Client the key already exists for
+	Err      error  // the error that was returned, if any
+}
+
+// Error fills the error interface for KeyAlreadyExistsError.
+func (e KeyAlreadyExistsError) Error() string {
+	if e.ID == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("key %q already exists for client %q", e.ID, e.ClientID)
+}
+
+type jwk struct {
+	KeyType   string   `json:"kty"`
+	KeyID     string   `json:"kid"`
+	Algorithm string   `json:"alg"`
+	Use       string   `json:"use"`
+	KeyOps    []string `json:"key_ops"`
+	Curve     string   `json:"crv"`
+	N         string   `json:"n"`
+	E         string   `json:"e"`
+	X         string   `json:"x"`
+	Y         string   `json:"y"`
+}
+
+// ParseKey parses raw as a JSON Web Key and validates that it can be
+// registered as a Key: it must have a key ID and an allowed algorithm that
+// matches its key type, must only be used for signatures, must not contain
+// any private or symmetric key material, and RSA keys must be at least
+// MinRSAKeyBits long. If it can't be registered, an InvalidKeyError is
+// returned. The returned Key only has its ID, KeyType, Algorithm, and JWK
+// set.
+func ParseKey(raw []byte) (Key, error) {
+	var params map[string]json.RawMessage
+	err := json.Unmarshal(raw, &params)
+	if err != nil {
+		return Key{}, InvalidKeyError{Reason: "not a JSON object"}
+	}
+	var parsed jwk
+	err = json.Unmarshal(raw, &parsed)
+	if err != nil {
+		return Key{}, InvalidKeyError{Reason: "invalid JWK: " + err.Error()}
+	}
+	if parsed.KeyID == "" {
+		return Key{}, InvalidKeyError{Reason: "kid is required"}
+	}
+	for _, param := range privateKeyParams {
+		if _, ok := params[param]; ok {
+			return Key{}, InvalidKeyError{ID: parsed.KeyID, Reason: "must not contain private key material"}
+		}
+	}
+	if parsed.Use != "" && parsed.Use != "sig" {
+		return Key{}, InvalidKeyError{ID: parsed.KeyID, Reason: "use must be sig"}
+	}
+	for _, op := range parsed.KeyOps {
+		if op != "verify" {
+			return Key{}, InvalidKeyError{ID: parsed.KeyID, Reason: "key_ops must only contain verify"}
+		}
+	}
+	_, err = parsed.publicKey()
+	if err != nil {
+		return Key{}, err
+	}
+	var compacted bytes.Buffer
+	err = json.Compact(&compacted, raw)
+	if err != nil {
+		return Key{}, InvalidKeyError{ID: parsed.KeyID, Reason: "invalid JSON: " + err.Error()}
+	}
+	return Key{
+		ID:        parsed.KeyID,
+		KeyType:   parsed.KeyType,
+		Algorithm: parsed.Algorithm,
+		JWK:       compacted.String(),
+	}, nil
+}
+
+// ParseJWKS parses raw as a JSON Web Key Set, returning a Key for each JWK
+// in it. Each JWK is validated using ParseKey, and key IDs must be unique
+// within the set.
+func ParseJWKS(raw []byte) ([]Key, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	err := json.Unmarshal(raw, &set)
+	if err != nil {
+		return nil, InvalidKeyError{Reason: "invalid JWKS: " + err.Error()}
+	}
+	keys := make([]Key, 0, len(set.Keys))
+	seen := map[string]struct{}{}
+	for _, rawKey := range set.Keys {
+		key, err := ParseKey(rawKey)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[key.ID]; ok {
+			return nil, InvalidKeyError{ID: key.ID, Reason: "duplicate kid"}
+		}
+		seen[key.ID] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// PublicKey returns the public key the Key represents, as an
+// *rsa.PublicKey, *ecdsa.PublicKey, or ed25519.PublicKey.
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	var parsed jwk
+	err := json.Unmarshal([]byte(k.JWK), &parsed)
+	if err != nil {
+		return nil, InvalidKeyError{ID: k.ID, Reason: "invalid JWK: " + err.Error()}
+	}
+	return parsed.publicKey()
+}
+
+// publicKey validates the algorithm and key parameters of the JWK and
+// returns the public key it represents.
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	alg, ok := keyAlgorithms[j.Algorithm]
+	if !ok {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("unsupported alg %q", j.Algorithm)}
+	}
+	if alg.keyType != j.KeyType {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("alg %q can't be used with kty %q", j.Algorithm, j.KeyType)}
+	}
+	if alg.curve != "" && alg.curve != j.Curve {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("alg %q can't be used with crv %q", j.Algorithm, j.Curve)}
+	}
+	switch j.KeyType {
+	case KeyTypeRSA:
+		return j.rsaPublicKey()
+	case KeyTypeEC:
+		return j.ecPublicKey()
+	case KeyTypeOKP:
+		return j.okpPublicKey()
+	}
+	return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("unsupported kty %q", j.KeyType)}
+}
+
+func (j jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil || len(n) < 1 {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "invalid n"}
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil || len(e) < 1 || len(e) > 4 {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "invalid e"}
+	}
+	modulus := new(big.Int).SetBytes(n)
+	if modulus.BitLen() < MinRSAKeyBits {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("RSA keys must be at least %d bits", MinRSAKeyBits)}
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if exponent.Int64() < 3 || exponent.Bit(0) == 0 {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "invalid e"}
+	}
+	return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+}
+
+func (j jwk) ecPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch j.Curve {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("unsupported crv %q", j.Curve)}
+	}
+	size := (curve.Params().BitSize + 7) / 8 //nolint:gomnd // rounding bits up to bytes
+	x, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil || len(x) != size {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "invalid x"}
+	}
+	y, err := base64.RawURLEncoding.DecodeString(j.Y)
+	if err != nil || len(y) != size {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "invalid y"}
+	}
+	key := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "point is not on the curve"}
+	}
+	return key, nil
+}
+
+func (j jwk) okpPublicKey() (ed25519.PublicKey, error) {
+	if j.Curve != "Ed25519" {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: fmt.Sprintf("unsupported crv %q", j.Curve)}
+	}
+	x, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, InvalidKeyError{ID: j.KeyID, Reason: "invalid x"}
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// KeysByID sorts `keys` by their ID property, with IDs that are
+// lexicographically lower returned first.
+func KeysByID(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+}
+
+// ValidateJWKSURI checks that uri can be used as a Client's JWKSURI: it must
+// be an absolute https URI with a host and no userinfo or fragment. If it
+// can't, an InvalidKeyError is returned.
+func ValidateJWKSURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return InvalidKeyError{Reason: "jwks_uri must be a valid URI"}
+	}
+	if parsed.Scheme != "https" {
+		return InvalidKeyError{Reason: "jwks_uri must be an https URI"}
+	}
+	if parsed.Hostname() == "" {
+		return InvalidKeyError{Reason: "jwks_uri must have a host"}
+	}
+	if parsed.User != nil {
+		return InvalidKeyError{Reason: "jwks_uri must not contain userinfo"}
+	}
+	if strings.Contains(uri, "#") {
+		return InvalidKeyError{Reason: "jwks_uri must not contain a fragment"}
+	}
+	return nil
+}
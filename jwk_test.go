@@ -0,0 +1,169 @@
+package clients_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"lockbox.dev/clients"
+)
+
+func b64(in []byte) string {
+	return base64.RawURLEncoding.EncodeToString(in)
+}
+
+func rsaJWK(t *testing.T, kid, alg string, key *rsa.PublicKey) map[string]interface{} {
+	t.Helper()
+	return map[string]interface{}{
+		"kty": "RSA",
+		"kid": kid,
+		"alg": alg,
+		"use": "sig",
+		"n":   b64(key.N.Bytes()),
+		"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(t *testing.T, kid string, key *ecdsa.PublicKey) map[string]interface{} {
+	t.Helper()
+	size := (key.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return map[string]interface{}{
+		"kty": "EC",
+		"kid": kid,
+		"alg": "ES256",
+		"crv": key.Curve.Params().Name,
+		"x":   b64(x),
+		"y":   b64(y),
+	}
+}
+
+func ed25519JWK(t *testing.T, kid string, key ed25519.PublicKey) map[string]interface{} {
+	t.Helper()
+	return map[string]interface{}{
+		"kty": "OKP",
+		"kid": kid,
+		"alg": "EdDSA",
+		"crv": "Ed25519",
+		"x":   b64(key),
+	}
+}
+
+func jsonOrFail(t *testing.T, in interface{}) []byte {
+	t.Helper()
+	out, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %s", err)
+	}
+	return out
+}
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %s", err)
+	}
+	smallRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating EC key: %s", err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating Ed25519 key: %s", err)
+	}
+
+	withParam := func(jwk map[string]interface{}, param string, value interface{}) map[string]interface{} {
+		res := map[string]interface{}{}
+		for k, v := range jwk {
+			res[k] = v
+		}
+		if value == nil {
+			delete(res, param)
+		} else {
+			res[param] = value
+		}
+		return res
+	}
+
+	cases := map[string]struct {
+		jwk   map[string]interface{}
+		valid bool
+	}{
+		"rsa":             {jwk: rsaJWK(t, "rsa", "RS256", &rsaKey.PublicKey), valid: true},
+		"rsa-pss":         {jwk: rsaJWK(t, "rsa", "PS256", &rsaKey.PublicKey), valid: true},
+		"ec":              {jwk: ecJWK(t, "ec", &ecKey.PublicKey), valid: true},
+		"ed25519":         {jwk: ed25519JWK(t, "ed", edKey), valid: true},
+		"rsa-too-small":   {jwk: rsaJWK(t, "rsa", "RS256", &smallRSAKey.PublicKey)},
+		"rsa-private":     {jwk: withParam(rsaJWK(t, "rsa", "RS256", &rsaKey.PublicKey), "d", b64(rsaKey.D.Bytes()))},
+		"ec-private":      {jwk: withParam(ecJWK(t, "ec", &ecKey.PublicKey), "d", b64(ecKey.D.Bytes()))},
+		"symmetric":       {jwk: map[string]interface{}{"kty": "oct", "kid": "hmac", "alg": "HS256", "k": b64([]byte("secret"))}},
+		"no-kid":          {jwk: withParam(rsaJWK(t, "rsa", "RS256", &rsaKey.PublicKey), "kid", nil)},
+		"no-alg":          {jwk: withParam(rsaJWK(t, "rsa", "RS256", &rsaKey.PublicKey), "alg", nil)},
+		"none-alg":        {jwk: withParam(rsaJWK(t, "rsa", "RS256", &rsaKey.PublicKey), "alg", "none")},
+		"mismatched-alg":  {jwk: withParam(ecJWK(t, "ec", &ecKey.PublicKey), "alg", "RS256")},
+		"mismatched-crv":  {jwk: withParam(ecJWK(t, "ec", &ecKey.PublicKey), "alg", "ES384")},
+		"encryption-use":  {jwk: withParam(rsaJWK(t, "rsa", "RS256", &rsaKey.PublicKey), "use", "enc")},
+		"point-off-curve": {jwk: withParam(ecJWK(t, "ec", &ecKey.PublicKey), "y", b64(make([]byte, 32)))},
+	}
+	for name, tc := range cases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := clients.ParseKey(jsonOrFail(t, tc.jwk))
+			if !tc.valid {
+				var keyErr clients.InvalidKeyError
+				if !errors.As(err, &keyErr) {
+					t.Errorf("Expected an InvalidKeyError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error parsing key: %s", err)
+			}
+			if key.ID != tc.jwk["kid"] {
+				t.Errorf("Expected kid %q, got %q", tc.jwk["kid"], key.ID)
+			}
+			if _, err = key.PublicKey(); err != nil {
+				t.Errorf("Unexpected error getting public key: %s", err)
+			}
+		})
+	}
+}
+
+func TestValidateJWKSURI(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"https://client.example.com/jwks.json":      true,
+		"http://client.example.com/jwks.json":       false,
+		"https://user@client.example.com/jwks.json": false,
+		"https://client.example.com/jwks.json#keys": false,
+		"/jwks.json": false,
+	}
+	for uri, valid := range cases {
+		err := clients.ValidateJWKSURI(uri)
+		if valid && err != nil {
+			t.Errorf("Expected %q to be valid, got %v", uri, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected %q to be invalid", uri)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultJWKSTimeout is how long fetching a JWKS may take if
+	// JWKSCache.Timeout isn't set.
+	DefaultJWKSTimeout = 10 * time.Second
+	// DefaultJWKSMaxBytes is the largest JWKS response, in bytes, that will
+	// be read if JWKSCache.MaxBytes isn't set.
+	DefaultJWKSMaxBytes = 1 << 20
+	// DefaultJWKSTTL is how long a JWKS is cached if JWKSCache.DefaultTTL
+	// isn't set and the response has no usable cache headers.
+	DefaultJWKSTTL = time.Hour
+	// DefaultJWKSMaxTTL is the longest a JWKS is cached, regardless of its
+	// cache headers, if JWKSCache.MaxTTL isn't set.
+	DefaultJWKSMaxTTL = 24 * time.Hour
+	// DefaultJWKSRefreshInterval is the shortest time between two fetches
+	// of the same JWKS if JWKSCache.RefreshInterval isn't set.
+	DefaultJWKSRefreshInterval = time.Minute
+
+	// jwksMaxRedirects is the most redirects that will be followed when
+	// fetching a JWKS.
+	jwksMaxRedirects = 3
+)
+
+var (
+	// ErrJWKSFetch is returned when a JWKS can't be fetched or parsed.
+	ErrJWKSFetch = errors.New("error fetching JWKS")
+	// ErrJWKSAddressNotAllowed is returned when a jwks_uri resolves to an
+	// address that JWKSCache isn't allowed to connect to.
+	ErrJWKSAddressNotAllowed = errors.New("JWKS address not allowed")
+
+	// disallowedJWKSNetworks are ranges that aren't covered by the net.IP
+	// helpers but still shouldn't be reachable from a jwks_uri.
+	disallowedJWKSNetworks = mustParseCIDRs(
+		"0.0.0.0/8",     // "this" network
+		"100.64.0.0/10", // carrier-grade NAT
+		"192.0.0.0/24",  // IETF protocol assignments
+		"198.18.0.0/15", // benchmarking
+		"240.0.0.0/4",   // reserved
+		"64:ff9b::/96",  // NAT64, which can reach private IPv4 addresses
+	)
+)
+
+// JWKSCache fetches and caches the JSON Web Key Sets that Clients publish at
+// their JWKSURI.
+//
+// Key sets are cached for as long as the response's Cache-Control or Expires
+// headers allow, within RefreshInterval and MaxTTL. Refresh can be used to
+// fetch a key set again before it expires, such as when an assertion uses a
+// kid that isn't in the cached set, but no key set is fetched more than once
+// per RefreshInterval, so callers can't be used to flood the jwks_uri with
+// requests. If fetching a key set fails, the last key set successfully
+// fetched keeps being used.
+//
+// Because jwks_uri values are supplied by Clients, JWKSCache only connects
+// to public addresses over https, doesn't use proxies, and limits how long
+// requests may take and how large responses may be.
+//
+// The zero value is ready to use.
+type JWKSCache struct {
+	// AllowPrivateNetworks allows fetching key sets from loopback,
+	// private, link-local, and other non-public addresses. It should only
+	// be set in tests or when every Client is trusted.
+	AllowPrivateNetworks bool
+
+	// RootCAs are the certificate authorities trusted when connecting to a
+	// jwks_uri. If unset, the system's are used.
+	RootCAs *x509.CertPool
+
+	// Timeout is how long fetching a key set may take. If unset,
+	// DefaultJWKSTimeout is used.
+	Timeout time.Duration
+
+	// MaxBytes is the largest response, in bytes, that will be read. If
+	// unset, DefaultJWKSMaxBytes is used.
+	MaxBytes int64
+
+	// DefaultTTL is how long key sets are cached when the response
+	// doesn't say. If unset, DefaultJWKSTTL is used.
+	DefaultTTL time.Duration
+
+	// MaxTTL is the longest key sets are cached. If unset,
+	// DefaultJWKSMaxTTL is used.
+	MaxTTL time.Duration
+
+	// RefreshInterval is the shortest time between two fetches of the same
+	// key set. It's also the shortest time a key set is cached for. If
+	// unset, DefaultJWKSRefreshInterval is used.
+	RefreshInterval time.Duration
+
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	client  *http.Client
+	entries map[string]*jwksEntry
+}
+
+// jwksEntry is the cached state of a single jwks_uri. Its mutex is held
+// while fetching, so concurrent requests for the same key set share a
+// single fetch.
+type jwksEntry struct {
+	mu          sync.Mutex
+	keys        []Key
+	expiresAt   time.Time
+	lastAttempt time.Time
+	lastErr     error
+}
+
+// Keys returns the Keys published at uri, fetching them if they're not
+// cached or the cached key set has expired.
+func (c *JWKSCache) Keys(ctx context.Context, uri string) ([]Key, error) {
+	entry := c.entry(uri)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	now := c.now()
+	if entry.keys != nil && now.Before(entry.expiresAt) {
+		return entry.keys, nil
+	}
+	return c.refresh(ctx, uri, entry, now)
+}
+
+// Refresh fetches the Keys published at uri again, even if the cached key
+// set hasn't expired, unless it was already fetched within the last
+// RefreshInterval, in which case the cached key set is returned.
+func (c *JWKSCache) Refresh(ctx context.Context, uri string) ([]Key, error) {
+	entry := c.entry(uri)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	return c.refresh(ctx, uri, entry, c.now())
+}
+
+// refresh fetches the key set for entry, unless it was fetched too
+// recently. entry's mutex must be held.
+func (c *JWKSCache) refresh(ctx context.Context, uri string, entry *jwksEntry, now time.Time) ([]Key, error) {
+	if !entry.lastAttempt.IsZero() && now.Sub(entry.lastAttempt) < c.refreshInterval() {
+		if entry.keys != nil {
+			return entry.keys, nil
+		}
+		return nil, entry.lastErr
+	}
+	entry.lastAttempt = now
+	keys, ttl, err := c.fetch(ctx, uri, now)
+	if err != nil {
+		entry.lastErr = err
+		if entry.keys != nil {
+			return entry.keys, nil
+		}
+		return nil, err
+	}
+	entry.keys = keys
+	entry.expiresAt = now.Add(ttl)
+	entry.lastErr = nil
+	return keys, nil
+}
+
+func (c *JWKSCache) entry(uri string) *jwksEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*jwksEntry{}
+	}
+	entry, ok := c.entries[uri]
+	if !ok {
+		entry = &jwksEntry{}
+		c.entries[uri] = entry
+	}
+	return entry
+}
+
+// fetch retrieves and parses the key set at uri, returning the keys and how
+// long they may be cached for.
+func (c *JWKSCache) fetch(ctx context.Context, uri string, now time.Time) ([]Key, time.Duration, error) {
+	err := ValidateJWKSURI(uri)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrJWKSFetch, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrJWKSFetch, err)
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		if errors.Is(err, ErrJWKSAddressNotAllowed) {
+			return nil, 0, err
+		}
+		return nil, 0, fmt.Errorf("%w: %s", ErrJWKSFetch, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // nothing to do if closing fails
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("%w: unexpected status %d", ErrJWKSFetch, resp.StatusCode)
+	}
+	maxBytes := c.maxBytes()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrJWKSFetch, err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, 0, fmt.Errorf("%w: response is larger than %d bytes", ErrJWKSFetch, maxBytes)
+	}
+	keys, err := parsePublishedJWKS(body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrJWKSFetch, err)
+	}
+	return keys, c.ttl(resp.Header, now), nil
+}
+
+// parsePublishedJWKS parses a key set fetched from a jwks_uri. Unlike
+// ParseJWKS, keys that can't be used to verify signatures, like encryption
+// keys, are skipped rather than rejecting the whole set, as published key
+// sets are often shared with other uses. At least one usable key is
+// required.
+func parsePublishedJWKS(raw []byte) ([]Key, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	err := json.Unmarshal(raw, &set)
+	if err != nil {
+		return nil, InvalidKeyError{Reason: "invalid JWKS: " + err.Error()}
+	}
+	keys := make([]Key, 0, len(set.Keys))
+	for _, rawKey := range set.Keys {
+		key, err := ParseKey(rawKey)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) < 1 {
+		return nil, InvalidKeyError{Reason: "JWKS contains no usable keys"}
+	}
+	return keys, nil
+}
+
+// ttl returns how long a response with the passed headers may be cached,
+// based on its Cache-Control or Expires headers, within RefreshInterval
+// and MaxTTL.
+func (c *JWKSCache) ttl(header http.Header, now time.Time) time.Duration {
+	ttl := c.defaultTTL()
+	var fromCacheControl bool
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			ttl, fromCacheControl = 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.ParseInt(strings.TrimPrefix(directive, "max-age="), 10, 64)
+			if err != nil || fromCacheControl {
+				continue
+			}
+			ttl, fromCacheControl = time.Duration(seconds)*time.Second, true
+			if age, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil {
+				ttl -= time.Duration(age) * time.Second
+			}
+		}
+	}
+	if !fromCacheControl && header.Get("Expires") != "" {
+		expires, err := http.ParseTime(header.Get("Expires"))
+		if err != nil {
+			ttl = 0
+		} else {
+			date, err := http.ParseTime(header.Get("Date"))
+			if err != nil {
+				date = now
+			}
+			ttl = expires.Sub(date)
+		}
+	}
+	if ttl < c.refreshInterval() {
+		ttl = c.refreshInterval()
+	}
+	if ttl > c.maxTTL() {
+		ttl = c.maxTTL()
+	}
+	return ttl
+}
+
+// httpClient returns the http.Client used to fetch key sets, building it
+// the first time it's needed.
+func (c *JWKSCache) httpClient() *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client
+	}
+	dialer := &net.Dialer{
+		Timeout: c.timeout(),
+	}
+	if !c.AllowPrivateNetworks {
+		dialer.Control = checkJWKSAddress
+	}
+	c.client = &http.Client{
+		Timeout: c.timeout(),
+		Transport: &http.Transport{
+			// proxies would make the connection on our behalf,
+			// bypassing the address checks
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSClientConfig:       &tls.Config{RootCAs: c.RootCAs, MinVersion: tls.VersionTLS12},
+			TLSHandshakeTimeout:   c.timeout(),
+			ResponseHeaderTimeout: c.timeout(),
+			MaxIdleConns:          10, //nolint:gomnd // key sets are fetched rarely
+			IdleConnTimeout:       time.Minute,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= jwksMaxRedirects {
+				return fmt.Errorf("%w: too many redirects", ErrJWKSFetch)
+			}
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirected to a non-https URI", ErrJWKSFetch)
+			}
+			return nil
+		},
+	}
+	return c.client
+}
+
+// checkJWKSAddress is used as a net.Dialer's Control function to refuse
+// connections to addresses that aren't publicly routable. It runs after DNS
+// resolution, so hostnames that resolve to private addresses are refused,
+// too.
+func checkJWKSAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrJWKSAddressNotAllowed, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrJWKSAddressNotAllowed, address)
+	}
+	return nil
+}
+
+// isPublicIP returns true if ip is a publicly routable unicast address.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range disallowedJWKSNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func (c *JWKSCache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *JWKSCache) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultJWKSTimeout
+}
+
+func (c *JWKSCache) maxBytes() int64 {
+	if c.MaxBytes > 0 {
+		return c.MaxBytes
+	}
+	return DefaultJWKSMaxBytes
+}
+
+func (c *JWKSCache) defaultTTL() time.Duration {
+	if c.DefaultTTL > 0 {
+		return c.DefaultTTL
+	}
+	return DefaultJWKSTTL
+}
+
+func (c *JWKSCache) maxTTL() time.Duration {
+	if c.MaxTTL > 0 {
+		return c.MaxTTL
+	}
+	return DefaultJWKSMaxTTL
+}
+
+func (c *JWKSCache) refreshInterval() time.Duration {
+	if c.RefreshInterval > 0 {
+		return c.RefreshInterval
+	}
+	return DefaultJWKSRefreshInterval
+}
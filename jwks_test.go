@@ -0,0 +1,329 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+// jwksServer is an httptest server publishing a JWKS that can be changed
+// while it's running, counting the requests it receives.
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	body     []byte
+	header   http.Header
+	requests int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	srv := &jwksServer{header: http.Header{}}
+	srv.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		srv.requests++
+		body := srv.body
+		for k, v := range srv.header {
+			w.Header()[k] = v
+		}
+		srv.mu.Unlock()
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Write(body) //nolint:errcheck // test server
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *jwksServer) publish(t *testing.T, keys ...map[string]interface{}) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = jsonOrFail(t, map[string]interface{}{"keys": keys})
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *jwksServer) cache(now *time.Time) *clients.JWKSCache {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	return &clients.JWKSCache{
+		AllowPrivateNetworks: true,
+		RootCAs:              pool,
+		Now:                  func() time.Time { return *now },
+	}
+}
+
+func ecKeyOrFail(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating EC key: %s", err)
+	}
+	return key
+}
+
+func TestJWKSCacheHonoursCacheHeaders(t *testing.T) {
+	t.Parallel()
+
+	srv := newJWKSServer(t)
+	srv.publish(t, ecJWK(t, "key-1", &ecKeyOrFail(t).PublicKey))
+	srv.header.Set("Cache-Control", "public, max-age=600")
+	now := time.Now()
+	cache := srv.cache(&now)
+
+	for i := 0; i < 3; i++ {
+		keys, err := cache.Keys(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error fetching keys: %s", err)
+		}
+		if len(keys) != 1 || keys[0].ID != "key-1" {
+			t.Fatalf("Expected key-1, got %+v", keys)
+		}
+	}
+	if srv.requestCount() != 1 {
+		t.Errorf("Expected 1 request while cached, got %d", srv.requestCount())
+	}
+
+	now = now.Add(11 * time.Minute)
+	_, err := cache.Keys(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error fetching keys: %s", err)
+	}
+	if srv.requestCount() != 2 {
+		t.Errorf("Expected key set to be fetched again after max-age, got %d requests", srv.requestCount())
+	}
+}
+
+func TestJWKSCacheRateLimitsRefresh(t *testing.T) {
+	t.Parallel()
+
+	srv := newJWKSServer(t)
+	srv.publish(t, ecJWK(t, "key-1", &ecKeyOrFail(t).PublicKey))
+	now := time.Now()
+	cache := srv.cache(&now)
+
+	_, err := cache.Keys(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error fetching keys: %s", err)
+	}
+	srv.publish(t, ecJWK(t, "key-2", &ecKeyOrFail(t).PublicKey))
+	for i := 0; i < 5; i++ {
+		keys, err := cache.Refresh(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error refreshing keys: %s", err)
+		}
+		if keys[0].ID != "key-1" {
+			t.Errorf("Expected cached key-1 within the refresh interval, got %s", keys[0].ID)
+		}
+	}
+	if srv.requestCount() != 1 {
+		t.Errorf("Expected refreshes to be rate limited, got %d requests", srv.requestCount())
+	}
+
+	now = now.Add(clients.DefaultJWKSRefreshInterval)
+	keys, err := cache.Refresh(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error refreshing keys: %s", err)
+	}
+	if keys[0].ID != "key-2" {
+		t.Errorf("Expected key-2 after the refresh interval, got %s", keys[0].ID)
+	}
+	if srv.requestCount() != 2 {
+		t.Errorf("Expected 2 requests, got %d", srv.requestCount())
+	}
+}
+
+func TestJWKSCacheKeepsKeysWhenFetchFails(t *testing.T) {
+	t.Parallel()
+
+	srv := newJWKSServer(t)
+	srv.publish(t, ecJWK(t, "key-1", &ecKeyOrFail(t).PublicKey))
+	now := time.Now()
+	cache := srv.cache(&now)
+
+	_, err := cache.Keys(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error fetching keys: %s", err)
+	}
+	srv.mu.Lock()
+	srv.body = []byte("not a JWKS")
+	srv.mu.Unlock()
+	now = now.Add(2 * clients.DefaultJWKSTTL)
+	keys, err := cache.Keys(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error fetching keys: %s", err)
+	}
+	if len(keys) != 1 || keys[0].ID != "key-1" {
+		t.Errorf("Expected previously fetched key-1, got %+v", keys)
+	}
+}
+
+func TestJWKSCacheRejectsPrivateAddresses(t *testing.T) {
+	t.Parallel()
+
+	srv := newJWKSServer(t)
+	srv.publish(t, ecJWK(t, "key-1", &ecKeyOrFail(t).PublicKey))
+	cache := &clients.JWKSCache{}
+
+	_, err := cache.Keys(context.Background(), srv.URL)
+	if !errors.Is(err, clients.ErrJWKSAddressNotAllowed) {
+		t.Errorf("Expected %v, got %v", clients.ErrJWKSAddressNotAllowed, err)
+	}
+	if srv.requestCount() != 0 {
+		t.Errorf("Expected no requests to reach the server, got %d", srv.requestCount())
+	}
+}
+
+func TestJWKSCacheLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		handler http.HandlerFunc
+		uri     func(string) string
+		cache   func(*clients.JWKSCache)
+	}{
+		"too-large": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"keys":[` + strings.Repeat(" ", 2048) + `]}`)) //nolint:errcheck // test server
+			},
+			cache: func(c *clients.JWKSCache) { c.MaxBytes = 1024 },
+		},
+		"too-slow": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			},
+			cache: func(c *clients.JWKSCache) { c.Timeout = 100 * time.Millisecond },
+		},
+		"error-status": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		"no-usable-keys": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"keys":[{"kty":"oct","kid":"secret","k":"c2VjcmV0"}]}`)) //nolint:errcheck // test server
+			},
+		},
+		"not-https": {
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			uri:     func(u string) string { return strings.Replace(u, "https://", "http://", 1) },
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewTLSServer(test.handler)
+			defer srv.Close()
+			pool := x509.NewCertPool()
+			pool.AddCert(srv.Certificate())
+			cache := &clients.JWKSCache{AllowPrivateNetworks: true, RootCAs: pool}
+			if test.cache != nil {
+				test.cache(cache)
+			}
+			uri := srv.URL
+			if test.uri != nil {
+				uri = test.uri(uri)
+			}
+			_, err := cache.Keys(context.Background(), uri)
+			if !errors.Is(err, clients.ErrJWKSFetch) {
+				t.Errorf("Expected %v, got %v", clients.ErrJWKSFetch, err)
+			}
+		})
+	}
+}
+
+func TestAssertionVerifierFetchesRotatedJWKS(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		srv := newJWKSServer(t)
+		oldKey, newKey := ecKeyOrFail(t), ecKeyOrFail(t)
+		srv.publish(t, ecJWK(t, "old-key", &oldKey.PublicKey))
+
+		client := clients.Client{
+			ID:                      uuidOrFail(t),
+			Name:                    "Test Client",
+			Confidential:            true,
+			TokenEndpointAuthMethod: clients.AuthMethodPrivateKeyJWT,
+			JWKSURI:                 srv.URL,
+			CreatedAt:               time.Now().Round(time.Millisecond),
+			CreatedBy:               "test",
+			CreatedByIP:             "127.0.0.1",
+		}
+		err := storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+
+		now := time.Now()
+		verifier := clients.AssertionVerifier{
+			Storer:      storer,
+			JWKS:        srv.cache(&now),
+			ReplayCache: &clients.MemoryReplayCache{},
+			Audiences:   []string{"https://auth.example.com/token"},
+			Now:         func() time.Time { return now },
+		}
+		assertion := func(kid string, key *ecdsa.PrivateKey) string {
+			return signAssertion(t, map[string]interface{}{"alg": "ES256", "kid": kid}, map[string]interface{}{
+				"iss": client.ID,
+				"sub": client.ID,
+				"aud": "https://auth.example.com/token",
+				"iat": now.Unix(),
+				"exp": now.Add(time.Minute).Unix(),
+				"jti": uuidOrFail(t),
+			}, key)
+		}
+
+		_, err = verifier.Verify(ctx, assertion("old-key", oldKey))
+		if err != nil {
+			t.Fatalf("Unexpected error verifying assertion: %s", err)
+		}
+
+		// the client rotates its keys, but the old key set is still
+		// cached, so an assertion signed with an unknown kid triggers
+		// a refresh
+		srv.publish(t, ecJWK(t, "new-key", &newKey.PublicKey))
+		now = now.Add(clients.DefaultJWKSRefreshInterval)
+		_, err = verifier.Verify(ctx, assertion("new-key", newKey))
+		if err != nil {
+			t.Fatalf("Unexpected error verifying assertion after rotation: %s", err)
+		}
+		if srv.requestCount() != 2 {
+			t.Errorf("Expected 2 requests, got %d", srv.requestCount())
+		}
+
+		// unknown kids can't force more fetches within the refresh
+		// interval
+		for i := 0; i < 5; i++ {
+			_, err = verifier.Verify(ctx, assertion("unknown-key", newKey))
+			if !errors.Is(err, clients.ErrInvalidAssertion) {
+				t.Errorf("Expected %v, got %v", clients.ErrInvalidAssertion, err)
+			}
+		}
+		if srv.requestCount() != 2 {
+			t.Errorf("Expected unknown kids not to trigger more fetches, got %d requests", srv.requestCount())
+		}
+	})
+}
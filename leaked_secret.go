@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MinFingerprintKeySize is the smallest fingerprint key, in bytes, that can
+// be set.
+const MinFingerprintKeySize = 32
+
+var (
+	// ErrInvalidFingerprintKey is returned when a fingerprint key can't be
+	// set.
+	ErrInvalidFingerprintKey = errors.New("invalid secret fingerprint key")
+	// ErrNoFingerprintKey is returned when a leaked secret is reported but
+	// no fingerprint key is set, so its owner can't be found.
+	ErrNoFingerprintKey = errors.New("no secret fingerprint key set")
+
+	// fingerprintKey is protected by secretHashersMu.
+	fingerprintKey []byte
+)
+
+// SecretOwner identifies where a secret is stored.
+type SecretOwner struct {
+	ClientID string // the ID of the Client the secret belongs to
+	SecretID string // the ID of the Secret the secret is stored as; empty if it's the Client's own secret
+}
+
+// SetFingerprintKey sets the server-held key SecretFingerprint uses, so
+// fingerprints leaked without the key can't be used to test guesses at
+// secrets offline. key must be at least MinFingerprintKeySize bytes, or
+// empty to stop recording fingerprints.
+//
+// Fingerprints recorded with a different key can't be found, so changing
+// the key means secrets can't be revoked as leaked until they're reset.
+func SetFingerprintKey(key []byte) error {
+	if len(key) > 0 && len(key) < MinFingerprintKeySize {
+		return fmt.Errorf("%w: must be at least %d bytes", ErrInvalidFingerprintKey, MinFingerprintKeySize)
+	}
+	secretHashersMu.Lock()
+	defer secretHashersMu.Unlock()
+	fingerprintKey = append([]byte(nil), key...)
+	return nil
+}
+
+// SecretFingerprint returns an HMAC-SHA256 of secret under the key set with
+// SetFingerprintKey, hex-encoded, or an empty string if no key is set. It's
+// stored alongside the salted hash of the secret so the owner of a leaked
+// secret can be found without knowing which Client it belongs to.
+func SecretFingerprint(secret []byte) string {
+	secretHashersMu.RLock()
+	defer secretHashersMu.RUnlock()
+	if len(fingerprintKey) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, fingerprintKey)
+	mac.Write(secret) //nolint:errcheck // hashes never return errors
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RevokeLeakedSecret finds the owner of a secret that has been reported
+// leaked, and revokes it. If the secret is one of a Client's Secrets, that
+// Secret is removed. If it's the Client's own secret, the Client is
+// suspended, with reportedBy recorded as the source of the change, and the
+// secret is expired as of now, so re-enabling the Client doesn't make the
+// leaked secret valid again; it stays unusable until the secret is reset.
+// The SecretOwner is returned either way.
+//
+// If the secret can't be found, ErrSecretNotFound is returned. Secrets set
+// before SecretFingerprints were recorded can't be found until they've been
+// re-hashed. If no fingerprint key is set, ErrNoFingerprintKey is
+// returned. If the Client is updated while it's being suspended,
+// ErrVersionConflict is returned, and revoking the secret can be retried.
+func RevokeLeakedSecret(ctx context.Context, storer Storer, secret, reportedBy string, now time.Time) (SecretOwner, error) {
+	fingerprint := SecretFingerprint([]byte(secret))
+	if fingerprint == "" {
+		return SecretOwner{}, ErrNoFingerprintKey
+	}
+	owner, err := storer.FindSecretOwner(ctx, fingerprint)
+	if err != nil {
+		return SecretOwner{}, err
+	}
+	if owner.SecretID != "" {
+		err = storer.RemoveSecrets(ctx, owner.ClientID, []string{owner.SecretID})
+		if err != nil {
+			return SecretOwner{}, err
+		}
+		return owner, nil
+	}
+	client, err := storer.Get(ctx, owner.ClientID)
+	if err != nil {
+		return SecretOwner{}, err
+	}
+	change, err := ChangeStatus(StatusSuspended, "client secret was reported leaked", reportedBy, now)
+	if err != nil {
+		return SecretOwner{}, err
+	}
+	change.SecretExpiresAt = &now
+	err = storer.Update(ctx, owner.ClientID, client.Version, change)
+	if err != nil {
+		return SecretOwner{}, err
+	}
+	return owner, nil
+}
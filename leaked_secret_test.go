@@ -0,0 +1,149 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func TestRevokeLeakedSecret(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		change, err := clients.ChangeSecret([]byte("client secret " + client.ID))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		client = clients.Apply(change, client)
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		secret, err := clients.Secret{
+			ID:          uuidOrFail(t),
+			ClientID:    client.ID,
+			CreatedAt:   time.Now().Round(time.Millisecond),
+			CreatedBy:   "test",
+			CreatedByIP: "127.0.0.1",
+		}.HashSecret([]byte("additional secret " + client.ID))
+		if err != nil {
+			t.Fatalf("Error hashing secret: %s", err)
+		}
+		err = storer.AddSecrets(ctx, []clients.Secret{secret})
+		if err != nil {
+			t.Fatalf("Error storing secret: %s", err)
+		}
+
+		owner, err := storer.FindSecretOwner(ctx, clients.SecretFingerprint([]byte("client secret "+client.ID)))
+		if err != nil {
+			t.Fatalf("Error finding client secret owner: %s", err)
+		}
+		if owner != (clients.SecretOwner{ClientID: client.ID}) {
+			t.Errorf("Expected client secret to belong to %q, got %+v", client.ID, owner)
+		}
+		_, err = storer.FindSecretOwner(ctx, clients.SecretFingerprint([]byte("unknown secret "+client.ID)))
+		if !errors.Is(err, clients.ErrSecretNotFound) {
+			t.Errorf("Expected %v for an unknown secret, got %v", clients.ErrSecretNotFound, err)
+		}
+		_, err = storer.FindSecretOwner(ctx, "")
+		if !errors.Is(err, clients.ErrSecretNotFound) {
+			t.Errorf("Expected %v for an empty fingerprint, got %v", clients.ErrSecretNotFound, err)
+		}
+
+		owner, err = clients.RevokeLeakedSecret(ctx, storer, "additional secret "+client.ID, "test", time.Now())
+		if err != nil {
+			t.Fatalf("Error revoking additional secret: %s", err)
+		}
+		if owner != (clients.SecretOwner{ClientID: client.ID, SecretID: secret.ID}) {
+			t.Errorf("Expected additional secret to belong to %q/%q, got %+v", client.ID, secret.ID, owner)
+		}
+		secrets, err := storer.ListSecrets(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error listing secrets: %s", err)
+		}
+		if len(secrets) != 0 {
+			t.Errorf("Expected leaked secret to be removed, got %+v", secrets)
+		}
+
+		_, err = clients.RevokeLeakedSecret(ctx, storer, "client secret "+client.ID, "test", time.Now())
+		if err != nil {
+			t.Fatalf("Error revoking client secret: %s", err)
+		}
+		stored, err := storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.Status != clients.StatusSuspended || stored.StatusChangedBy != "test" {
+			t.Errorf("Expected client to be suspended by %q, got %q by %q", "test", stored.Status, stored.StatusChangedBy)
+		}
+
+		// re-enabling the client mustn't bring the leaked secret back
+		change, err = clients.ChangeStatus(clients.StatusActive, "re-enabled", "test", time.Now())
+		if err != nil {
+			t.Fatalf("Error generating status change: %s", err)
+		}
+		err = storer.Update(ctx, client.ID, stored.Version, change)
+		if err != nil {
+			t.Fatalf("Error re-enabling client: %s", err)
+		}
+		authenticator := clients.Authenticator{Storer: storer}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "client secret "+client.ID)
+		if !errors.Is(err, clients.ErrSecretExpired) {
+			t.Errorf("Expected %v for the leaked secret after re-enabling, got %v", clients.ErrSecretExpired, err)
+		}
+	})
+}
+
+//nolint:paralleltest // changes the fingerprint key
+func TestSecretFingerprintKey(t *testing.T) {
+	t.Cleanup(func() {
+		err := clients.SetFingerprintKey(testFingerprintKey)
+		if err != nil {
+			t.Errorf("Error restoring fingerprint key: %s", err)
+		}
+	})
+	secret := []byte("fingerprinted secret")
+
+	err := clients.SetFingerprintKey(make([]byte, clients.MinFingerprintKeySize-1))
+	if !errors.Is(err, clients.ErrInvalidFingerprintKey) {
+		t.Errorf("Expected %v for a short key, got %v", clients.ErrInvalidFingerprintKey, err)
+	}
+
+	fingerprint := clients.SecretFingerprint(secret)
+	sum := sha256.Sum256(secret)
+	if fingerprint == "" || fingerprint == hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected a keyed fingerprint, got %q", fingerprint)
+	}
+	err = clients.SetFingerprintKey(pepperOrFail(t))
+	if err != nil {
+		t.Fatalf("Error setting fingerprint key: %s", err)
+	}
+	if other := clients.SecretFingerprint(secret); other == fingerprint {
+		t.Errorf("Expected fingerprint to change with the key, got %q both times", other)
+	}
+
+	err = clients.SetFingerprintKey(nil)
+	if err != nil {
+		t.Fatalf("Error clearing fingerprint key: %s", err)
+	}
+	if fingerprint := clients.SecretFingerprint(secret); fingerprint != "" {
+		t.Errorf("Expected no fingerprint without a key, got %q", fingerprint)
+	}
+	_, err = clients.RevokeLeakedSecret(context.Background(), nil, string(secret), "test", time.Now())
+	if !errors.Is(err, clients.ErrNoFingerprintKey) {
+		t.Errorf("Expected %v without a key, got %v", clients.ErrNoFingerprintKey, err)
+	}
+}
@@ -0,0 +1,75 @@
+package clients
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// MetadataError is returned when a Client's metadata, as defined in RFC 7591,
+// Section 2, is invalid.
+type MetadataError struct {
+	Field  string // the metadata field that is invalid, e.g. "logo_uri"
+	Index  int    // the position of the invalid value, for fields with multiple values
+	Reason string // why the field is invalid
+}
+
+// Error fills the error interface for MetadataError.
+func (e MetadataError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ValidateMetadata checks that the Client's ClientURI, LogoURI, PolicyURI,
+// TOSURI, and Contacts are valid, returning a MetadataError if they're not.
+// Empty URIs are valid, and indicate the Client has no value for them.
+func (c Client) ValidateMetadata() error {
+	uris := []struct {
+		field string
+		value string
+	}{
+		{field: "client_uri", value: c.ClientURI},
+		{field: "logo_uri", value: c.LogoURI},
+		{field: "policy_uri", value: c.PolicyURI},
+		{field: "tos_uri", value: c.TOSURI},
+	}
+	for _, uri := range uris {
+		if uri.value == "" {
+			continue
+		}
+		if reason := invalidMetadataURIReason(uri.value); reason != "" {
+			return MetadataError{Field: uri.field, Reason: reason}
+		}
+	}
+	for pos, contact := range c.Contacts {
+		addr, err := mail.ParseAddress(contact)
+		if err != nil || addr.Address != contact {
+			return MetadataError{Field: "contacts", Index: pos, Reason: "must be an email address"}
+		}
+	}
+	return nil
+}
+
+// invalidMetadataURIReason returns why uri can't be used as a metadata URI,
+// or an empty string if it can. Metadata URIs are shown to and followed by
+// end users, so they need to be absolute web URLs that don't embed
+// credentials.
+func invalidMetadataURIReason(uri string) string {
+	if strings.TrimSpace(uri) != uri {
+		return "must not contain leading or trailing whitespace"
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "must be a valid URI"
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return "must be an http or https URI"
+	}
+	if parsed.Host == "" || parsed.Hostname() == "" {
+		return "must have a host"
+	}
+	if parsed.User != nil {
+		return "must not contain userinfo"
+	}
+	return ""
+}
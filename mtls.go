@@ -0,0 +1,297 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrCertificateMismatch is returned when a Client tries to authenticate
+// using a TLS client certificate that doesn't match the one it registered,
+// or without a certificate at all.
+var ErrCertificateMismatch = errors.New("client certificate doesn't match client")
+
+// CertificateThumbprint returns the SHA-256 thumbprint of cert, encoded the
+// same way as the x5t#S256 JWK parameter: the unpadded base64url encoding
+// of the SHA-256 hash of the certificate's DER encoding. It's the format
+// TLSClientCertThumbprint is stored in.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ValidateTLSClientAuth checks that the Client's TLS client certificate
+// settings are consistent with its TokenEndpointAuthMethod, returning a
+// MetadataError if they're not.
+//
+// Clients using AuthMethodTLSClientAuth must set exactly one of
+// TLSClientAuthSubjectDN, TLSClientAuthSANDNS, TLSClientAuthSANURI,
+// TLSClientAuthSANIP, or TLSClientAuthSANEmail, as described in RFC 8705,
+// Section 2.1.2. Clients using AuthMethodSelfSignedTLSClientAuth must set
+// TLSClientCertThumbprint. Other Clients must set none of them.
+func (c Client) ValidateTLSClientAuth() error {
+	identifiers := []struct {
+		field string
+		value string
+	}{
+		{field: "tls_client_auth_subject_dn", value: c.TLSClientAuthSubjectDN},
+		{field: "tls_client_auth_san_dns", value: c.TLSClientAuthSANDNS},
+		{field: "tls_client_auth_san_uri", value: c.TLSClientAuthSANURI},
+		{field: "tls_client_auth_san_ip", value: c.TLSClientAuthSANIP},
+		{field: "tls_client_auth_san_email", value: c.TLSClientAuthSANEmail},
+	}
+	method := c.AuthMethod()
+	var set int
+	for _, identifier := range identifiers {
+		if identifier.value == "" {
+			continue
+		}
+		if method != AuthMethodTLSClientAuth {
+			return MetadataError{Field: identifier.field, Reason: "only allowed for tls_client_auth clients"}
+		}
+		set++
+		if set > 1 {
+			return MetadataError{Field: identifier.field, Reason: "only one subject DN or SAN may be set"}
+		}
+		if reason := invalidCertificateIdentifierReason(identifier.field, identifier.value); reason != "" {
+			return MetadataError{Field: identifier.field, Reason: reason}
+		}
+	}
+	if method == AuthMethodTLSClientAuth && set < 1 {
+		return MetadataError{Field: "tls_client_auth_subject_dn", Reason: "tls_client_auth clients must set a subject DN or SAN"}
+	}
+	if c.TLSClientCertThumbprint != "" && method != AuthMethodSelfSignedTLSClientAuth {
+		return MetadataError{Field: "tls_client_cert_thumbprint", Reason: "only allowed for self_signed_tls_client_auth clients"}
+	}
+	if method == AuthMethodSelfSignedTLSClientAuth {
+		thumbprint, err := base64.RawURLEncoding.DecodeString(c.TLSClientCertThumbprint)
+		if err != nil || len(thumbprint) != sha256.Size {
+			return MetadataError{Field: "tls_client_cert_thumbprint", Reason: "must be a base64url-encoded SHA-256 hash"}
+		}
+	}
+	return nil
+}
+
+// invalidCertificateIdentifierReason returns why value can't be used for
+// the passed tls_client_auth metadata field, or an empty string if it can.
+func invalidCertificateIdentifierReason(field, value string) string {
+	if strings.TrimSpace(value) != value {
+		return "must not contain leading or trailing whitespace"
+	}
+	switch field {
+	case "tls_client_auth_subject_dn":
+		if _, ok := parseDN(value); !ok {
+			return "must be a distinguished name in RFC 4514 format"
+		}
+	case "tls_client_auth_san_uri":
+		parsed, err := url.Parse(value)
+		if err != nil || !parsed.IsAbs() {
+			return "must be an absolute URI"
+		}
+	case "tls_client_auth_san_ip":
+		if net.ParseIP(value) == nil {
+			return "must be an IP address"
+		}
+	case "tls_client_auth_san_email":
+		if !strings.Contains(value, "@") {
+			return "must be an email address"
+		}
+	}
+	return ""
+}
+
+// MatchesCertificate returns true if cert is the certificate the Client
+// registered to authenticate with.
+//
+// For Clients using AuthMethodTLSClientAuth, cert's subject or one of its
+// subject alternative names must match the one the Client registered. The
+// caller is responsible for making sure cert chains to a trusted
+// certificate authority. For Clients using
+// AuthMethodSelfSignedTLSClientAuth, cert's thumbprint must match
+// TLSClientCertThumbprint. Clients using any other method never match.
+func (c Client) MatchesCertificate(cert *x509.Certificate) bool {
+	if cert == nil {
+		return false
+	}
+	switch c.AuthMethod() {
+	case AuthMethodTLSClientAuth:
+		return c.matchesCertificateIdentity(cert)
+	case AuthMethodSelfSignedTLSClientAuth:
+		return c.TLSClientCertThumbprint != "" && CertificateThumbprint(cert) == c.TLSClientCertThumbprint
+	}
+	return false
+}
+
+func (c Client) matchesCertificateIdentity(cert *x509.Certificate) bool {
+	switch {
+	case c.TLSClientAuthSubjectDN != "":
+		expected, ok := parseDN(c.TLSClientAuthSubjectDN)
+		if !ok {
+			return false
+		}
+		actual, ok := parseDN(cert.Subject.String())
+		return ok && expected == actual
+	case c.TLSClientAuthSANDNS != "":
+		for _, name := range cert.DNSNames {
+			if strings.EqualFold(name, c.TLSClientAuthSANDNS) {
+				return true
+			}
+		}
+	case c.TLSClientAuthSANURI != "":
+		for _, uri := range cert.URIs {
+			if uri.String() == c.TLSClientAuthSANURI {
+				return true
+			}
+		}
+	case c.TLSClientAuthSANIP != "":
+		expected := net.ParseIP(c.TLSClientAuthSANIP)
+		for _, ip := range cert.IPAddresses {
+			if expected != nil && expected.Equal(ip) {
+				return true
+			}
+		}
+	case c.TLSClientAuthSANEmail != "":
+		for _, email := range cert.EmailAddresses {
+			if strings.EqualFold(email, c.TLSClientAuthSANEmail) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseDN parses a distinguished name in the string format described in RFC
+// 4514, like the one returned by pkix.Name.String, and returns a normalized
+// form of it so equivalent names compare equal: attribute types are
+// upper-cased, escapes are decoded, values are compared case-insensitively
+// with insignificant whitespace removed, and the attributes of
+// multi-valued RDNs are sorted. It returns false if dn can't be parsed.
+func parseDN(dn string) (string, bool) {
+	var rdns []string
+	var rdn []string
+	var attr strings.Builder
+	var typ string
+	var inValue bool
+	finishAttr := func() bool {
+		value := strings.Join(strings.Fields(strings.ToLower(attr.String())), " ")
+		if !inValue || typ == "" || value == "" {
+			return false
+		}
+		rdn = append(rdn, typ+"="+value)
+		attr.Reset()
+		typ, inValue = "", false
+		return true
+	}
+	for i := 0; i < len(dn); i++ {
+		ch := dn[i]
+		switch {
+		case ch == '\\':
+			if i+1 >= len(dn) {
+				return "", false
+			}
+			// either a special character or two hex digits
+			if i+2 < len(dn) && isHex(dn[i+1]) && isHex(dn[i+2]) {
+				decoded, err := hex.DecodeString(dn[i+1 : i+3])
+				if err != nil {
+					return "", false
+				}
+				attr.Write(decoded)
+				i += 2
+			} else {
+				attr.WriteByte(dn[i+1])
+				i++
+			}
+		case ch == '=' && !inValue:
+			typ = strings.ToUpper(strings.TrimSpace(attr.String()))
+			attr.Reset()
+			inValue = true
+		case (ch == ',' || ch == '+') && inValue:
+			if !finishAttr() {
+				return "", false
+			}
+			if ch == ',' {
+				sort.Strings(rdn)
+				rdns = append(rdns, strings.Join(rdn, "+"))
+				rdn = nil
+			}
+		default:
+			attr.WriteByte(ch)
+		}
+	}
+	if !finishAttr() {
+		return "", false
+	}
+	sort.Strings(rdn)
+	rdns = append(rdns, strings.Join(rdn, "+"))
+	return strings.Join(rdns, ","), true
+}
+
+func isHex(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+// AuthenticateCertificate retrieves the Client with the passed ID from the
+// Storer and checks that cert, the certificate presented in the TLS
+// handshake, authenticates it using AuthMethodTLSClientAuth or
+// AuthMethodSelfSignedTLSClientAuth, as described in RFC 8705.
+//
+// chainVerified must only be true if the TLS server verified that cert
+// chains to one of the certificate authorities trusted for client
+// certificates; Clients using AuthMethodTLSClientAuth can't authenticate
+// with unverified certificates. Clients using
+// AuthMethodSelfSignedTLSClientAuth are matched by certificate thumbprint,
+// so their certificates don't need to be verified.
+//
+// If the Client can't be found, ErrClientNotFound is returned. If the Client
+// didn't register either method, ErrAuthMethodNotAllowed is returned. If
+// cert doesn't match the Client, ErrCertificateMismatch is returned. If the
+// Client is disabled or suspended, ErrClientDisabled is returned.
+func (a Authenticator) AuthenticateCertificate(ctx context.Context, clientID string, cert *x509.Certificate, chainVerified bool) (Client, error) {
+	client, err := a.Storer.Get(ctx, clientID)
+	if err != nil {
+		return Client{}, err
+	}
+	switch client.AuthMethod() {
+	case AuthMethodTLSClientAuth:
+		if !chainVerified {
+			return Client{}, ErrCertificateMismatch
+		}
+	case AuthMethodSelfSignedTLSClientAuth:
+	default:
+		return Client{}, ErrAuthMethodNotAllowed
+	}
+	if !client.MatchesCertificate(cert) {
+		return Client{}, ErrCertificateMismatch
+	}
+	err = client.CheckStatus()
+	if err != nil {
+		return Client{}, err
+	}
+	return client, nil
+}
+
+// AuthenticateTLS calls AuthenticateCertificate with the client certificate
+// from state, which is usually an http.Request's TLS field. If the TLS
+// server verified the certificate's chain, the certificate is treated as
+// verified. If no client certificate was presented, ErrCertificateMismatch
+// is returned.
+func (a Authenticator) AuthenticateTLS(ctx context.Context, clientID string, state *tls.ConnectionState) (Client, error) {
+	if state == nil {
+		return Client{}, ErrCertificateMismatch
+	}
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+		return a.AuthenticateCertificate(ctx, clientID, state.VerifiedChains[0][0], true)
+	}
+	if len(state.PeerCertificates) > 0 {
+		return a.AuthenticateCertificate(ctx, clientID, state.PeerCertificates[0], false)
+	}
+	return Client{}, ErrCertificateMismatch
+}
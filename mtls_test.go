@@ -0,0 +1,255 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func certificateOrFail(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	uri, err := url.Parse("spiffe://example.com/billing")
+	if err != nil {
+		t.Fatalf("Error parsing URI: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName:         "billing.example.com",
+			Organization:       []string{"Example, Inc."},
+			OrganizationalUnit: []string{"Billing"},
+		},
+		DNSNames:       []string{"billing.example.com"},
+		URIs:           []*url.URL{uri},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses: []string{"billing@example.com"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Error parsing certificate: %s", err)
+	}
+	return cert
+}
+
+func TestValidateTLSClientAuth(t *testing.T) {
+	t.Parallel()
+
+	thumbprint := clients.CertificateThumbprint(certificateOrFail(t))
+	tests := map[string]struct {
+		client clients.Client
+		field  string
+	}{
+		"subject-dn": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: "CN=billing.example.com,O=Example"},
+		},
+		"san-ip": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANIP: "10.0.0.1"},
+		},
+		"thumbprint": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodSelfSignedTLSClientAuth, TLSClientCertThumbprint: thumbprint},
+		},
+		"none": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodClientSecretBasic},
+		},
+		"missing-identifier": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth},
+			field:  "tls_client_auth_subject_dn",
+		},
+		"multiple-identifiers": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: "CN=billing", TLSClientAuthSANDNS: "billing.example.com"},
+			field:  "tls_client_auth_san_dns",
+		},
+		"invalid-dn": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: "billing"},
+			field:  "tls_client_auth_subject_dn",
+		},
+		"invalid-ip": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANIP: "billing.example.com"},
+			field:  "tls_client_auth_san_ip",
+		},
+		"relative-uri": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANURI: "/billing"},
+			field:  "tls_client_auth_san_uri",
+		},
+		"missing-thumbprint": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodSelfSignedTLSClientAuth},
+			field:  "tls_client_cert_thumbprint",
+		},
+		"invalid-thumbprint": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodSelfSignedTLSClientAuth, TLSClientCertThumbprint: "abc"},
+			field:  "tls_client_cert_thumbprint",
+		},
+		"thumbprint-wrong-method": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANDNS: "billing.example.com", TLSClientCertThumbprint: thumbprint},
+			field:  "tls_client_cert_thumbprint",
+		},
+		"san-wrong-method": {
+			client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodPrivateKeyJWT, TLSClientAuthSANDNS: "billing.example.com"},
+			field:  "tls_client_auth_san_dns",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.client.ValidateTLSClientAuth()
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+				return
+			}
+			var metadataErr clients.MetadataError
+			if !errors.As(err, &metadataErr) {
+				t.Fatalf("Expected a MetadataError, got %v", err)
+			}
+			if metadataErr.Field != test.field {
+				t.Errorf("Expected error for %q, got %q", test.field, metadataErr.Field)
+			}
+		})
+	}
+}
+
+func TestClientMatchesCertificate(t *testing.T) {
+	t.Parallel()
+
+	cert := certificateOrFail(t)
+	tests := map[string]struct {
+		client  clients.Client
+		matches bool
+	}{
+		"subject-dn":                {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: cert.Subject.String()}, matches: true},
+		"subject-dn-normalized":     {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: "cn=Billing.Example.com, ou=Billing, o=Example\\2C Inc."}, matches: true},
+		"subject-dn-mismatch":       {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: "CN=billing.example.com,OU=Billing,O=Other"}},
+		"subject-dn-wrong-order":    {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSubjectDN: "O=Example\\, Inc.,OU=Billing,CN=billing.example.com"}},
+		"san-dns":                   {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANDNS: "BILLING.example.com"}, matches: true},
+		"san-dns-mismatch":          {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANDNS: "shipping.example.com"}},
+		"san-uri":                   {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANURI: "spiffe://example.com/billing"}, matches: true},
+		"san-ip":                    {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANIP: "10.0.0.1"}, matches: true},
+		"san-ip-mismatch":           {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANIP: "10.0.0.2"}},
+		"san-email":                 {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientAuthSANEmail: "billing@example.com"}, matches: true},
+		"thumbprint":                {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodSelfSignedTLSClientAuth, TLSClientCertThumbprint: clients.CertificateThumbprint(cert)}, matches: true},
+		"thumbprint-mismatch":       {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodSelfSignedTLSClientAuth, TLSClientCertThumbprint: clients.CertificateThumbprint(certificateOrFail(t))}},
+		"thumbprint-wrong-method":   {client: clients.Client{TokenEndpointAuthMethod: clients.AuthMethodTLSClientAuth, TLSClientCertThumbprint: clients.CertificateThumbprint(cert)}},
+		"identifier-without-method": {client: clients.Client{Confidential: true, TLSClientAuthSANDNS: "billing.example.com"}},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if matches := test.client.MatchesCertificate(cert); matches != test.matches {
+				t.Errorf("Expected %v, got %v", test.matches, matches)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorAuthenticatesCertificates(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		cert := certificateOrFail(t)
+		authenticator := clients.Authenticator{Storer: storer}
+		newClient := func(method string, change clients.Change) clients.Client {
+			client := clients.Apply(change, clients.Client{
+				ID:                      uuidOrFail(t),
+				Name:                    "Test Client",
+				Confidential:            true,
+				TokenEndpointAuthMethod: method,
+				CreatedAt:               time.Now().Round(time.Millisecond),
+				CreatedBy:               "test",
+				CreatedByIP:             "127.0.0.1",
+			})
+			err := storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+			return client
+		}
+		san := "billing.example.com"
+		thumbprint := clients.CertificateThumbprint(cert)
+		pkiClient := newClient(clients.AuthMethodTLSClientAuth, clients.Change{TLSClientAuthSANDNS: &san})
+		selfSignedClient := newClient(clients.AuthMethodSelfSignedTLSClientAuth, clients.Change{TLSClientCertThumbprint: &thumbprint})
+		secretClient := newClient(clients.AuthMethodClientSecretBasic, clients.Change{})
+
+		res, err := authenticator.AuthenticateTLS(ctx, pkiClient.ID, &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		})
+		if err != nil {
+			t.Errorf("Unexpected error authenticating with verified certificate: %s", err)
+		}
+		if res.TLSClientAuthSANDNS != san {
+			t.Errorf("Expected SAN %q to be stored, got %q", san, res.TLSClientAuthSANDNS)
+		}
+		_, err = authenticator.AuthenticateTLS(ctx, pkiClient.ID, &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		})
+		if !errors.Is(err, clients.ErrCertificateMismatch) {
+			t.Errorf("Expected %v for unverified certificate, got %v", clients.ErrCertificateMismatch, err)
+		}
+
+		res, err = authenticator.AuthenticateTLS(ctx, selfSignedClient.ID, &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		})
+		if err != nil {
+			t.Errorf("Unexpected error authenticating with self-signed certificate: %s", err)
+		}
+		if res.TLSClientCertThumbprint != thumbprint {
+			t.Errorf("Expected thumbprint %q to be stored, got %q", thumbprint, res.TLSClientCertThumbprint)
+		}
+		_, err = authenticator.AuthenticateCertificate(ctx, selfSignedClient.ID, certificateOrFail(t), false)
+		if !errors.Is(err, clients.ErrCertificateMismatch) {
+			t.Errorf("Expected %v for a different certificate, got %v", clients.ErrCertificateMismatch, err)
+		}
+		_, err = authenticator.AuthenticateTLS(ctx, selfSignedClient.ID, &tls.ConnectionState{})
+		if !errors.Is(err, clients.ErrCertificateMismatch) {
+			t.Errorf("Expected %v without a certificate, got %v", clients.ErrCertificateMismatch, err)
+		}
+
+		_, err = authenticator.AuthenticateCertificate(ctx, secretClient.ID, cert, true)
+		if !errors.Is(err, clients.ErrAuthMethodNotAllowed) {
+			t.Errorf("Expected %v, got %v", clients.ErrAuthMethodNotAllowed, err)
+		}
+
+		disabled, err := clients.ChangeStatus(clients.StatusDisabled, "compromised", "test", time.Now())
+		if err != nil {
+			t.Fatalf("Error creating status change: %s", err)
+		}
+		err = storer.Update(ctx, selfSignedClient.ID, selfSignedClient.Version, disabled)
+		if err != nil {
+			t.Fatalf("Error disabling client: %s", err)
+		}
+		_, err = authenticator.AuthenticateCertificate(ctx, selfSignedClient.ID, cert, false)
+		if !errors.Is(err, clients.ErrClientDisabled) {
+			t.Errorf("Expected %v, got %v", clients.ErrClientDisabled, err)
+		}
+	})
+}
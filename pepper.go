@@ -0,0 +1,95 @@
+package clients
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// MinPepperSize is the smallest pepper, in bytes, that can be registered.
+const MinPepperSize = 32
+
+var (
+	// ErrUnknownPepper is returned when a secret was hashed with a pepper
+	// that isn't registered.
+	ErrUnknownPepper = errors.New("unknown secret pepper")
+	// ErrInvalidPepper is returned when a pepper can't be registered.
+	ErrInvalidPepper = errors.New("invalid secret pepper")
+
+	// peppers is protected by secretHashersMu, along with the current
+	// pepper ID.
+	peppers       = map[string][]byte{}
+	currentPepper string
+)
+
+// RegisterPepper makes pepper available for hashing and verifying secrets
+// under the passed id, replacing any pepper already registered with that
+// id. Peppers are server-held secrets mixed into client secrets before
+// they're hashed, so hashes leaked without the pepper can't be brute-forced
+// offline. pepper must be at least MinPepperSize bytes.
+//
+// Registering a pepper doesn't start using it; see SetCurrentPepper.
+// Peppers that secrets were hashed with must stay registered until those
+// secrets have been re-hashed.
+func RegisterPepper(id string, pepper []byte) error {
+	if id == "" {
+		return fmt.Errorf("%w: ID must not be empty", ErrInvalidPepper)
+	}
+	if len(pepper) < MinPepperSize {
+		return fmt.Errorf("%w: must be at least %d bytes", ErrInvalidPepper, MinPepperSize)
+	}
+	secretHashersMu.Lock()
+	defer secretHashersMu.Unlock()
+	peppers[id] = append([]byte(nil), pepper...)
+	return nil
+}
+
+// SetCurrentPepper sets the pepper that ChangeSecret will mix into new
+// secrets. An empty id stops new secrets from being peppered. If no pepper
+// is registered for id, ErrUnknownPepper is returned and the current pepper
+// is left unchanged.
+//
+// Changing the current pepper rotates it: secrets hashed with the previous
+// pepper keep verifying as long as it's registered, and are re-hashed with
+// the current pepper when they're next used.
+func SetCurrentPepper(id string) error {
+	secretHashersMu.Lock()
+	defer secretHashersMu.Unlock()
+	if _, ok := peppers[id]; id != "" && !ok {
+		return ErrUnknownPepper
+	}
+	currentPepper = id
+	return nil
+}
+
+// CurrentPepper returns the ID of the pepper that ChangeSecret will mix into
+// new secrets, or an empty string if new secrets aren't peppered.
+func CurrentPepper() string {
+	secretHashersMu.RLock()
+	defer secretHashersMu.RUnlock()
+	return currentPepper
+}
+
+// pepperSecret mixes the pepper registered as pepperID into secret using
+// HMAC-SHA256, returning the result to be hashed in place of secret. If
+// pepperID is empty, secret is returned unchanged.
+func pepperSecret(pepperID string, secret []byte) ([]byte, error) {
+	if pepperID == "" {
+		return secret, nil
+	}
+	secretHashersMu.RLock()
+	pepper, ok := peppers[pepperID]
+	secretHashersMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownPepper
+	}
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write(secret) //nolint:errcheck // hashes never return errors
+	// encoded so every SecretHasher receives printable input
+	sum := mac.Sum(nil)
+	encoded := make([]byte, base64.RawStdEncoding.EncodedLen(len(sum)))
+	base64.RawStdEncoding.Encode(encoded, sum)
+	return encoded, nil
+}
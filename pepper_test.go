@@ -0,0 +1,165 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func pepperOrFail(t *testing.T) []byte {
+	t.Helper()
+	pepper := make([]byte, clients.MinPepperSize)
+	_, err := rand.Read(pepper)
+	if err != nil {
+		t.Fatalf("Error generating pepper: %s", err)
+	}
+	return pepper
+}
+
+// setCurrentPepperOrFail sets the current pepper for the duration of the
+// test. Tests that call it change global state, so must not be parallel.
+func setCurrentPepperOrFail(t *testing.T, id string) {
+	t.Helper()
+	previous := clients.CurrentPepper()
+	err := clients.SetCurrentPepper(id)
+	if err != nil {
+		t.Fatalf("Error setting current pepper: %s", err)
+	}
+	t.Cleanup(func() {
+		err := clients.SetCurrentPepper(previous)
+		if err != nil {
+			t.Errorf("Error restoring current pepper: %s", err)
+		}
+	})
+}
+
+func TestRegisterPepperInvalid(t *testing.T) {
+	t.Parallel()
+
+	err := clients.RegisterPepper("", pepperOrFail(t))
+	if !errors.Is(err, clients.ErrInvalidPepper) {
+		t.Errorf("Expected %v for an empty ID, got %v", clients.ErrInvalidPepper, err)
+	}
+	err = clients.RegisterPepper("test-pepper-short", []byte("too short"))
+	if !errors.Is(err, clients.ErrInvalidPepper) {
+		t.Errorf("Expected %v for a short pepper, got %v", clients.ErrInvalidPepper, err)
+	}
+	err = clients.SetCurrentPepper("test-pepper-short")
+	if !errors.Is(err, clients.ErrUnknownPepper) {
+		t.Errorf("Expected %v setting an unregistered pepper, got %v", clients.ErrUnknownPepper, err)
+	}
+}
+
+//nolint:paralleltest // changes the current pepper
+func TestPepperedSecretRoundTrip(t *testing.T) {
+	err := clients.RegisterPepper("test-pepper-round-trip", pepperOrFail(t))
+	if err != nil {
+		t.Fatalf("Error registering pepper: %s", err)
+	}
+	setCurrentPepperOrFail(t, "test-pepper-round-trip")
+
+	change, err := clients.ChangeSecret([]byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error generating client secret: %s", err)
+	}
+	client := clients.Apply(change, clients.Client{ID: "test-client"})
+	if client.SecretPepperID != "test-pepper-round-trip" {
+		t.Errorf("Expected pepper ID %q, got %q", "test-pepper-round-trip", client.SecretPepperID)
+	}
+	if err := client.CheckSecret("test secret"); err != nil {
+		t.Errorf("Expected peppered secret to be accepted, got %v", err)
+	}
+	if err := client.CheckSecret("wrong secret"); !errors.Is(err, clients.ErrIncorrectSecret) {
+		t.Errorf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+	}
+	if client.SecretNeedsRehash() {
+		t.Error("Expected secret hashed with the current pepper not to need rehashing")
+	}
+
+	// the hash alone isn't enough to verify the secret
+	unpeppered := client
+	unpeppered.SecretPepperID = ""
+	if err := unpeppered.CheckSecret("test secret"); !errors.Is(err, clients.ErrIncorrectSecret) {
+		t.Errorf("Expected %v without the pepper, got %v", clients.ErrIncorrectSecret, err)
+	}
+
+	unknown := client
+	unknown.SecretPepperID = "test-pepper-unregistered"
+	if err := unknown.CheckSecret("test secret"); !errors.Is(err, clients.ErrUnknownPepper) {
+		t.Errorf("Expected %v, got %v", clients.ErrUnknownPepper, err)
+	}
+
+	secret, err := clients.Secret{ID: "test-secret", ClientID: client.ID}.HashSecret([]byte("additional secret"))
+	if err != nil {
+		t.Fatalf("Error hashing additional secret: %s", err)
+	}
+	if secret.PepperID != "test-pepper-round-trip" {
+		t.Errorf("Expected additional secret pepper ID %q, got %q", "test-pepper-round-trip", secret.PepperID)
+	}
+	if err := secret.Check("additional secret"); err != nil {
+		t.Errorf("Expected peppered additional secret to be accepted, got %v", err)
+	}
+}
+
+//nolint:paralleltest // changes the current pepper
+func TestAuthenticatorRehashesOnPepperRotation(t *testing.T) {
+	err := clients.RegisterPepper("test-pepper-rotation-1", pepperOrFail(t))
+	if err != nil {
+		t.Fatalf("Error registering pepper: %s", err)
+	}
+	err = clients.RegisterPepper("test-pepper-rotation-2", pepperOrFail(t))
+	if err != nil {
+		t.Fatalf("Error registering pepper: %s", err)
+	}
+	setCurrentPepperOrFail(t, "test-pepper-rotation-1")
+	change, err := clients.ChangeSecret([]byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error generating client secret: %s", err)
+	}
+	setCurrentPepperOrFail(t, "test-pepper-rotation-2")
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Apply(change, clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		})
+		err := storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		stored, err := storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.SecretPepperID != "test-pepper-rotation-1" {
+			t.Errorf("Expected stored pepper ID %q, got %q", "test-pepper-rotation-1", stored.SecretPepperID)
+		}
+		if !stored.SecretNeedsRehash() {
+			t.Error("Expected secret hashed with a previous pepper to need rehashing")
+		}
+
+		authenticator := clients.Authenticator{Storer: storer}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "test secret")
+		if err != nil {
+			t.Fatalf("Error authenticating client with the previous pepper: %s", err)
+		}
+		stored, err = storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.SecretPepperID != "test-pepper-rotation-2" {
+			t.Errorf("Expected secret to be re-hashed with %q, got %q", "test-pepper-rotation-2", stored.SecretPepperID)
+		}
+		if err = stored.CheckSecret("test secret"); err != nil {
+			t.Errorf("Expected re-hashed secret to be accepted, got %v", err)
+		}
+	})
+}
@@ -1,9 +1,17 @@
 package clients
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+
+	yall "yall.in"
 )
 
 // RedirectURI represents a URI that we'll redirect to as part of the OAuth 2
@@ -46,3 +54,198 @@ func RedirectURIsByURI(uris []RedirectURI) {
 		return uris[i].URI < uris[j].URI
 	})
 }
+
+// ErrRedirectURINotRegistered is returned when a redirect URI doesn't match
+// any of the RedirectURIs registered for a Client.
+var ErrRedirectURINotRegistered = errors.New("redirect URI not registered for client")
+
+// InvalidRedirectURIError is returned when a redirect URI can't be used,
+// because it's malformed or contains a component that could be used to
+// trick a URI comparison.
+type InvalidRedirectURIError struct {
+	URI    string // the URI that is invalid
+	Reason string // why the URI is invalid
+}
+
+// Error fills the error interface for InvalidRedirectURIError.
+func (e InvalidRedirectURIError) Error() string {
+	return fmt.Sprintf("invalid redirect URI %q: %s", e.URI, e.Reason)
+}
+
+// MatchRedirectURI returns the RedirectURI registered for the Client
+// identified by clientID that permits redirecting to candidate.
+//
+// Both the candidate and the registered URIs are normalized according to RFC
+// 3986 before they're compared: the scheme and host are lowercased, default
+// ports are removed, and unnecessary percent-encoding is decoded. A
+// RedirectURI with IsBaseURI set to false only matches a candidate that is
+// identical after normalization. A RedirectURI with IsBaseURI set to true
+// matches any candidate with the same scheme, host, and port whose path is
+// the same as or falls beneath the registered path, on a path segment
+// boundary. If the base URI has a query, the candidate's query must match it
+// exactly.
+//
+// Candidates that include userinfo, a fragment, dot segments, or encoded
+// path separators are rejected with an InvalidRedirectURIError. If no
+// registered RedirectURI matches, ErrRedirectURINotRegistered is returned.
+func MatchRedirectURI(ctx context.Context, storer Storer, clientID, candidate string) (RedirectURI, error) {
+	normalized, err := normalizeRedirectURI(candidate)
+	if err != nil {
+		return RedirectURI{}, err
+	}
+	uris, err := storer.ListRedirectURIs(ctx, clientID)
+	if err != nil {
+		return RedirectURI{}, err
+	}
+	for _, uri := range uris {
+		registered, err := normalizeRedirectURI(uri.URI)
+		if err != nil {
+			yall.FromContext(ctx).WithField("client_id", clientID).
+				WithField("redirect_uri_id", uri.ID).WithError(err).
+				Warn("registered redirect URI is invalid")
+			continue
+		}
+		if registered.matches(normalized, uri.IsBaseURI) {
+			return uri, nil
+		}
+	}
+	return RedirectURI{}, ErrRedirectURINotRegistered
+}
+
+type normalizedURI struct {
+	scheme   string
+	host     string
+	port     string
+	path     string
+	query    string
+	hasQuery bool
+}
+
+func (n normalizedURI) matches(candidate normalizedURI, isBase bool) bool {
+	if n.scheme != candidate.scheme || n.host != candidate.host || n.port != candidate.port {
+		return false
+	}
+	if !isBase {
+		return n.path == candidate.path && n.hasQuery == candidate.hasQuery && n.query == candidate.query
+	}
+	if n.hasQuery && (!candidate.hasQuery || n.query != candidate.query) {
+		return false
+	}
+	if n.path == candidate.path {
+		return true
+	}
+	prefix := n.path
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return strings.HasPrefix(candidate.path, prefix)
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+func normalizeRedirectURI(uri string) (normalizedURI, error) {
+	invalid := func(reason string) (normalizedURI, error) {
+		return normalizedURI{}, InvalidRedirectURIError{URI: uri, Reason: reason}
+	}
+	if strings.ContainsAny(uri, "#\\") {
+		return invalid("fragments and backslashes are not allowed")
+	}
+	if strings.IndexFunc(uri, unicode.IsSpace) >= 0 {
+		return invalid("whitespace is not allowed")
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if parsed.Scheme == "" {
+		return invalid("URI must be absolute")
+	}
+	if parsed.Opaque != "" {
+		return invalid("URI must be hierarchical")
+	}
+	if parsed.User != nil {
+		return invalid("userinfo is not allowed")
+	}
+	res := normalizedURI{
+		scheme:   strings.ToLower(parsed.Scheme),
+		host:     strings.ToLower(parsed.Hostname()),
+		port:     parsed.Port(),
+		query:    parsed.RawQuery,
+		hasQuery: parsed.ForceQuery || parsed.RawQuery != "",
+	}
+	if _, ok := defaultPorts[res.scheme]; ok && res.host == "" {
+		return invalid("host is required")
+	}
+	if strings.HasSuffix(parsed.Host, ":") {
+		return invalid("port must not be empty")
+	}
+	if res.port != "" {
+		port, err := strconv.ParseUint(res.port, 10, 16)
+		if err != nil {
+			return invalid("port is invalid")
+		}
+		res.port = strconv.FormatUint(port, 10)
+	}
+	if res.port == defaultPorts[res.scheme] {
+		res.port = ""
+	}
+	res.path, err = normalizePath(parsed.EscapedPath())
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if res.path == "" && res.host != "" {
+		res.path = "/"
+	}
+	return res, nil
+}
+
+// normalizePath decodes percent-encoded unreserved characters, uppercases
+// the hexadecimal digits of all other percent-encodings, and rejects dot
+// segments and encoded path separators.
+func normalizePath(path string) (string, error) {
+	var res strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '%' {
+			res.WriteByte(path[i])
+			continue
+		}
+		if i+2 >= len(path) {
+			return "", errMalformedPercentEncoding
+		}
+		decoded, err := strconv.ParseUint(path[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", errMalformedPercentEncoding
+		}
+		char := byte(decoded)
+		switch {
+		case char == '/' || char == '\\':
+			return "", errEncodedPathSeparator
+		case isUnreserved(char):
+			res.WriteByte(char)
+		default:
+			res.WriteString("%" + strings.ToUpper(path[i+1:i+3]))
+		}
+		i += 2
+	}
+	for _, segment := range strings.Split(res.String(), "/") {
+		if segment == "." || segment == ".." {
+			return "", errDotSegment
+		}
+	}
+	return res.String(), nil
+}
+
+var (
+	errMalformedPercentEncoding = errors.New("malformed percent-encoding")
+	errEncodedPathSeparator     = errors.New("encoded path separators are not allowed")
+	errDotSegment               = errors.New("dot segments are not allowed")
+)
+
+func isUnreserved(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+		(char >= '0' && char <= '9') || char == '-' || char == '.' ||
+		char == '_' || char == '~'
+}
@@ -0,0 +1,128 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func TestMatchRedirectURI(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: false,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		err := storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		clientID := client.ID
+		registered := map[string]bool{
+			"https://exact.impractical.services/callback":        false,
+			"https://base.impractical.services/oauth":            true,
+			"https://slash.impractical.services/oauth/":          true,
+			"https://query.impractical.services/cb?tenant=a":     false,
+			"https://port.impractical.services:8443/callback":    false,
+			"com.impractical.app:/oauth2redirect":                false,
+			"http://localhost:8080/callback":                     false,
+			"https://encoded.impractical.services/caf%C3%A9/cb":  false,
+			"https://basequery.impractical.services/cb?tenant=a": true,
+		}
+		uris := make([]clients.RedirectURI, 0, len(registered))
+		for uri, isBase := range registered {
+			uris = append(uris, clients.RedirectURI{
+				ID:          uuidOrFail(t),
+				URI:         uri,
+				IsBaseURI:   isBase,
+				ClientID:    clientID,
+				CreatedAt:   time.Now().Round(time.Millisecond),
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			})
+		}
+		err = storer.AddRedirectURIs(ctx, uris)
+		if err != nil {
+			t.Fatalf("Error adding redirect URIs: %s", err)
+		}
+
+		matches := map[string]string{
+			"https://exact.impractical.services/callback":              "https://exact.impractical.services/callback",
+			"HTTPS://EXACT.impractical.services:443/callback":          "https://exact.impractical.services/callback",
+			"https://exact.impractical.services/%63allback":            "https://exact.impractical.services/callback",
+			"https://base.impractical.services/oauth":                  "https://base.impractical.services/oauth",
+			"https://base.impractical.services/oauth/callback?state=1": "https://base.impractical.services/oauth",
+			"https://slash.impractical.services/oauth/callback":        "https://slash.impractical.services/oauth/",
+			"https://query.impractical.services/cb?tenant=a":           "https://query.impractical.services/cb?tenant=a",
+			"https://port.impractical.services:8443/callback":          "https://port.impractical.services:8443/callback",
+			"com.impractical.app:/oauth2redirect":                      "com.impractical.app:/oauth2redirect",
+			"http://localhost:8080/callback":                           "http://localhost:8080/callback",
+			"https://encoded.impractical.services/caf%c3%a9/cb":        "https://encoded.impractical.services/caf%C3%A9/cb",
+			"https://basequery.impractical.services/cb/x?tenant=a":     "https://basequery.impractical.services/cb?tenant=a",
+		}
+		for candidate, expected := range matches {
+			res, err := clients.MatchRedirectURI(ctx, storer, clientID, candidate)
+			if err != nil {
+				t.Errorf("Unexpected error matching %q: %s", candidate, err)
+				continue
+			}
+			if res.URI != expected {
+				t.Errorf("Expected %q to match %q, matched %q", candidate, expected, res.URI)
+			}
+		}
+
+		notRegistered := []string{
+			"https://exact.impractical.services/callback/extra",
+			"https://exact.impractical.services/callback?extra=1",
+			"http://exact.impractical.services/callback",
+			"https://exact.impractical.services:8443/callback",
+			"https://base.impractical.services/oauthx",
+			"https://base.impractical.services/",
+			"https://base.impractical.services.evil.example/oauth",
+			"https://slash.impractical.services/oauth",
+			"https://query.impractical.services/cb?tenant=b",
+			"https://query.impractical.services/cb",
+			"https://port.impractical.services/callback",
+			"https://basequery.impractical.services/cb/x",
+			"https://basequery.impractical.services/cb/x?tenant=b",
+		}
+		for _, candidate := range notRegistered {
+			_, err := clients.MatchRedirectURI(ctx, storer, clientID, candidate)
+			if !errors.Is(err, clients.ErrRedirectURINotRegistered) {
+				t.Errorf("Expected %v for %q, got %v", clients.ErrRedirectURINotRegistered, candidate, err)
+			}
+		}
+
+		invalid := []string{
+			"https://attacker@exact.impractical.services/callback",
+			"https://exact.impractical.services/callback#fragment",
+			"https://exact.impractical.services/callback#",
+			"https://base.impractical.services/oauth/../admin",
+			"https://base.impractical.services/oauth/%2e%2E/admin",
+			"https://base.impractical.services/oauth/./callback",
+			"https://base.impractical.services/oauth%2fcallback",
+			"https://base.impractical.services/oauth\\callback",
+			"https://base.impractical.services/oauth/%zz",
+			"https://exact.impractical.services:/callback",
+			"https://exact.impractical.services: 443/callback",
+			"/callback",
+			"https:///callback",
+			"mailto:test@impractical.services",
+		}
+		for _, candidate := range invalid {
+			_, err := clients.MatchRedirectURI(ctx, storer, clientID, candidate)
+			var invalidErr clients.InvalidRedirectURIError
+			if !errors.As(err, &invalidErr) {
+				t.Errorf("Expected %T for %q, got %v", invalidErr, candidate, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplayCache records identifiers that may only be used once, such as the
+// jti of client assertions, until they expire.
+type ReplayCache interface {
+	// Seen records that the client with the passed clientID used jti,
+	// which is valid until expiresAt. It returns true if the client had
+	// already used jti and the earlier use hasn't expired yet. Checking
+	// and recording must be atomic, so two concurrent uses of the same jti
+	// can't both return false.
+	Seen(ctx context.Context, clientID, jti string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryReplayCache is an in-memory implementation of ReplayCache. It's only
+// suitable when a single process is verifying assertions; deployments with
+// more than one should use a ReplayCache that shares its state between them.
+//
+// The zero value is ready to use.
+type MemoryReplayCache struct {
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]time.Time
+	lastPurged time.Time
+}
+
+// memoryReplayCachePurgeInterval is how often MemoryReplayCache removes
+// expired entries.
+const memoryReplayCachePurgeInterval = time.Minute
+
+// Seen records that the client with the passed clientID used jti, returning
+// true if it had already been used and hasn't expired. Expired entries are
+// periodically removed as new ones are recorded.
+func (m *MemoryReplayCache) Seen(_ context.Context, clientID, jti string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	if m.Now != nil {
+		now = m.Now()
+	}
+	key := clientID + "\x00" + jti
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = map[string]time.Time{}
+	}
+	if now.Sub(m.lastPurged) >= memoryReplayCachePurgeInterval {
+		for k, exp := range m.entries {
+			if !exp.After(now) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastPurged = now
+	}
+	if exp, ok := m.entries[key]; ok && exp.After(now) {
+		return true, nil
+	}
+	m.entries[key] = expiresAt
+	return false, nil
+}
@@ -0,0 +1,68 @@
+package clients
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Scope represents a scope that a Client is allowed to request access to.
+// Scopes marked as defaults are the ones a Client is granted when it doesn't
+// request any specific scopes.
+type Scope struct {
+	ID          string    // the identifier of the scope
+	ClientID    string    // the ID of the Client this scope is allowed for
+	IsDefault   bool      // whether the scope is granted when none are requested
+	CreatedAt   time.Time // the timestamp this scope was allowed at
+	CreatedBy   string    // the HMAC key that allowed this scope
+	CreatedByIP string    // the IP that allowed this scope
+}
+
+// ScopeAlreadyExistsError is returned when a scope is already allowed for a
+// Client in a Storer.
+type ScopeAlreadyExistsError struct {
+	ID       string // the ID of the scope that already exists
+	ClientID string // the ID of the Client the scope already exists for
+	Err      error  // the error that was returned, if any
+}
+
+// Error fills the error interface for ScopeAlreadyExistsError.
+func (e ScopeAlreadyExistsError) Error() string {
+	if e.ID == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("scope %q already exists for client %q", e.ID, e.ClientID)
+}
+
+// ScopesByID sorts `scopes` by their ID property, with IDs that are
+// lexicographically lower returned first.
+func ScopesByID(scopes []Scope) {
+	sort.Slice(scopes, func(i, j int) bool {
+		return scopes[i].ID < scopes[j].ID
+	})
+}
+
+// DefaultScopes returns the Scopes in `scopes` that have IsDefault set.
+func DefaultScopes(scopes []Scope) []Scope {
+	res := make([]Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope.IsDefault {
+			res = append(res, scope)
+		}
+	}
+	return res
+}
+
+// IsValidScopeID returns true if `id` is a valid scope-token, as defined by
+// RFC 6749, Section 3.3.
+func IsValidScopeID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, char := range []byte(id) {
+		if char < 0x21 || char == 0x22 || char == 0x5C || char > 0x7E {
+			return false
+		}
+	}
+	return true
+}
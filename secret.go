@@ -0,0 +1,361 @@
+package clients
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// SecretSchemeSHA256 is the legacy secret scheme. It is kept so that
+	// secrets hashed with it can still be verified, but it should not be
+	// used for new secrets.
+	SecretSchemeSHA256 = "sha256" // #nosec
+	// SecretSchemeArgon2id hashes secrets using Argon2id.
+	SecretSchemeArgon2id = "argon2id" // #nosec
+	// SecretSchemeBcrypt hashes secrets using bcrypt.
+	SecretSchemeBcrypt = "bcrypt" // #nosec
+	// SecretSchemePBKDF2SHA256 hashes secrets using PBKDF2 with HMAC-SHA256.
+	SecretSchemePBKDF2SHA256 = "pbkdf2-sha256" // #nosec
+)
+
+var (
+	// ErrInvalidSecretHash is returned when a stored secret hash can't be
+	// parsed by the SecretHasher for its scheme.
+	ErrInvalidSecretHash = errors.New("invalid secret hash")
+
+	secretHashersMu     sync.RWMutex
+	defaultSecretScheme = SecretSchemeArgon2id
+	secretHashers       = map[string]SecretHasher{
+		SecretSchemeSHA256:       legacySHA256Hasher{},
+		SecretSchemeArgon2id:     Argon2idHasher{Time: 2, Memory: 19 * 1024, Threads: 1, SaltLength: 16, KeyLength: 32}, //nolint:gomnd // OWASP recommended parameters
+		SecretSchemeBcrypt:       BcryptHasher{Cost: bcrypt.DefaultCost},
+		SecretSchemePBKDF2SHA256: PBKDF2SHA256Hasher{Iterations: 600000, SaltLength: 16, KeyLength: 32}, //nolint:gomnd // OWASP recommended parameters
+	}
+)
+
+// SecretHasher is an implementation of a scheme for hashing and verifying
+// client secrets. Any parameters the scheme needs to verify a secret must be
+// encoded in the hash it produces, so that the parameters can be changed
+// without invalidating existing secrets.
+type SecretHasher interface {
+	// Hash returns the encoded hash of secret, suitable for storing as a
+	// Client's SecretHash.
+	Hash(secret []byte) (string, error)
+
+	// Verify returns nil if attempt matches the encoded hash, or
+	// ErrIncorrectSecret if it does not. Any other error signals that
+	// the hash is corrupt.
+	Verify(hash string, attempt []byte) error
+
+	// NeedsRehash returns true if hash was generated with parameters
+	// other than the ones the SecretHasher is currently configured with.
+	NeedsRehash(hash string) bool
+}
+
+// RegisterSecretHasher makes hasher available for the passed scheme,
+// replacing any SecretHasher already registered for that scheme. It can be
+// used to add new schemes or to tune the parameters of the built-in ones.
+func RegisterSecretHasher(scheme string, hasher SecretHasher) {
+	secretHashersMu.Lock()
+	defer secretHashersMu.Unlock()
+	secretHashers[scheme] = hasher
+}
+
+// SecretHasherFor returns the SecretHasher registered for scheme. If no
+// SecretHasher is registered for scheme, ErrUnsupportedSecretScheme is
+// returned.
+func SecretHasherFor(scheme string) (SecretHasher, error) { //nolint:ireturn // hashers are meant to be pluggable
+	secretHashersMu.RLock()
+	defer secretHashersMu.RUnlock()
+	hasher, ok := secretHashers[scheme]
+	if !ok {
+		return nil, ErrUnsupportedSecretScheme
+	}
+	return hasher, nil
+}
+
+// SetDefaultSecretScheme sets the scheme that ChangeSecret will use to hash
+// new secrets. If no SecretHasher is registered for scheme,
+// ErrUnsupportedSecretScheme is returned and the default is left unchanged.
+func SetDefaultSecretScheme(scheme string) error {
+	secretHashersMu.Lock()
+	defer secretHashersMu.Unlock()
+	if _, ok := secretHashers[scheme]; !ok {
+		return ErrUnsupportedSecretScheme
+	}
+	defaultSecretScheme = scheme
+	return nil
+}
+
+// DefaultSecretScheme returns the scheme that ChangeSecret will use to hash
+// new secrets.
+func DefaultSecretScheme() string {
+	secretHashersMu.RLock()
+	defer secretHashersMu.RUnlock()
+	return defaultSecretScheme
+}
+
+func randomSalt(length uint32) ([]byte, error) {
+	salt := make([]byte, length)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// parsePHC splits a hash in the PHC string format, returning the parameters,
+// salt, and key. The hash is expected to be in the form
+// $id[$v=version]$params$salt$key, with the salt and key base64 encoded
+// without padding. The version, if present, is returned as the "v"
+// parameter.
+func parsePHC(hash, id string) (map[string]string, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) < 5 || len(parts) > 6 || parts[0] != "" || parts[1] != id { //nolint:gomnd // number of PHC string sections
+		return nil, nil, nil, ErrInvalidSecretHash
+	}
+	params := map[string]string{}
+	for _, section := range parts[2 : len(parts)-2] {
+		for _, param := range strings.Split(section, ",") {
+			kv := strings.SplitN(param, "=", 2) //nolint:gomnd // key and value
+			if len(kv) != 2 {                   //nolint:gomnd // key and value
+				return nil, nil, nil, ErrInvalidSecretHash
+			}
+			params[kv[0]] = kv[1]
+		}
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[len(parts)-2])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidSecretHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidSecretHash
+	}
+	return params, salt, key, nil
+}
+
+func phcUint(params map[string]string, name string, bits int) (uint64, error) {
+	val, err := strconv.ParseUint(params[name], 10, bits)
+	if err != nil {
+		return 0, ErrInvalidSecretHash
+	}
+	return val, nil
+}
+
+// legacySHA256Hasher verifies secrets stored by the original "sha256" scheme.
+// That scheme stored the secret followed by the SHA-256 digest of nothing,
+// so it offers no real protection and is only kept for compatibility.
+type legacySHA256Hasher struct{}
+
+func (legacySHA256Hasher) Hash(secret []byte) (string, error) {
+	return hex.EncodeToString(sha256.New().Sum(secret)), nil
+}
+
+func (legacySHA256Hasher) Verify(hash string, attempt []byte) error {
+	hashed, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+	candidate := sha256.New().Sum(attempt)
+	if subtle.ConstantTimeCompare(candidate, hashed) != 1 {
+		return ErrIncorrectSecret
+	}
+	return nil
+}
+
+func (legacySHA256Hasher) NeedsRehash(_ string) bool {
+	return true
+}
+
+// Argon2idHasher is a SecretHasher that uses Argon2id. Hashes are encoded in
+// the PHC string format, so changing the parameters does not invalidate
+// existing hashes.
+type Argon2idHasher struct {
+	Time       uint32 // number of passes over the memory
+	Memory     uint32 // memory to use, in KiB
+	Threads    uint8  // degree of parallelism
+	SaltLength uint32 // length of the random salt, in bytes
+	KeyLength  uint32 // length of the derived key, in bytes
+}
+
+// Hash fills the SecretHasher interface.
+func (h Argon2idHasher) Hash(secret []byte) (string, error) {
+	salt, err := randomSalt(h.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(secret, salt, h.Time, h.Memory, h.Threads, h.KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", SecretSchemeArgon2id,
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (Argon2idHasher) parse(hash string) (Argon2idHasher, []byte, []byte, error) {
+	params, salt, key, err := parsePHC(hash, SecretSchemeArgon2id)
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, err
+	}
+	version, err := phcUint(params, "v", 32) //nolint:gomnd // bit size
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, err
+	}
+	if version != argon2.Version {
+		return Argon2idHasher{}, nil, nil, ErrInvalidSecretHash
+	}
+	memory, err := phcUint(params, "m", 32) //nolint:gomnd // bit size
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, err
+	}
+	time, err := phcUint(params, "t", 32) //nolint:gomnd // bit size
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, err
+	}
+	threads, err := phcUint(params, "p", 8) //nolint:gomnd // bit size
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, err
+	}
+	if time < 1 || threads < 1 || len(key) < 1 {
+		return Argon2idHasher{}, nil, nil, ErrInvalidSecretHash
+	}
+	return Argon2idHasher{
+		Time:       uint32(time),
+		Memory:     uint32(memory),
+		Threads:    uint8(threads),
+		SaltLength: uint32(len(salt)),
+		KeyLength:  uint32(len(key)),
+	}, salt, key, nil
+}
+
+// Verify fills the SecretHasher interface.
+func (h Argon2idHasher) Verify(hash string, attempt []byte) error {
+	params, salt, key, err := h.parse(hash)
+	if err != nil {
+		return err
+	}
+	candidate := argon2.IDKey(attempt, salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrIncorrectSecret
+	}
+	return nil
+}
+
+// NeedsRehash fills the SecretHasher interface.
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := h.parse(hash)
+	if err != nil {
+		return true
+	}
+	return params != h
+}
+
+// BcryptHasher is a SecretHasher that uses bcrypt. Hashes are stored in
+// bcrypt's own encoding, which includes the cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash fills the SecretHasher interface.
+func (h BcryptHasher) Hash(secret []byte) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(secret, h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify fills the SecretHasher interface.
+func (BcryptHasher) Verify(hash string, attempt []byte) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), attempt)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrIncorrectSecret
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSecretHash, err.Error())
+	}
+	return nil
+}
+
+// NeedsRehash fills the SecretHasher interface.
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost != h.Cost
+}
+
+// PBKDF2SHA256Hasher is a SecretHasher that uses PBKDF2 with HMAC-SHA256.
+// Hashes are encoded in the PHC string format, so changing the parameters
+// does not invalidate existing hashes.
+type PBKDF2SHA256Hasher struct {
+	Iterations int    // number of iterations
+	SaltLength uint32 // length of the random salt, in bytes
+	KeyLength  uint32 // length of the derived key, in bytes
+}
+
+// Hash fills the SecretHasher interface.
+func (h PBKDF2SHA256Hasher) Hash(secret []byte) (string, error) {
+	salt, err := randomSalt(h.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key(secret, salt, h.Iterations, int(h.KeyLength), sha256.New)
+	return fmt.Sprintf("$%s$i=%d$%s$%s", SecretSchemePBKDF2SHA256, h.Iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (PBKDF2SHA256Hasher) parse(hash string) (PBKDF2SHA256Hasher, []byte, []byte, error) {
+	params, salt, key, err := parsePHC(hash, SecretSchemePBKDF2SHA256)
+	if err != nil {
+		return PBKDF2SHA256Hasher{}, nil, nil, err
+	}
+	iterations, err := phcUint(params, "i", 31) //nolint:gomnd // bit size, fits in an int
+	if err != nil {
+		return PBKDF2SHA256Hasher{}, nil, nil, err
+	}
+	if iterations < 1 || len(key) < 1 {
+		return PBKDF2SHA256Hasher{}, nil, nil, ErrInvalidSecretHash
+	}
+	return PBKDF2SHA256Hasher{
+		Iterations: int(iterations),
+		SaltLength: uint32(len(salt)),
+		KeyLength:  uint32(len(key)),
+	}, salt, key, nil
+}
+
+// Verify fills the SecretHasher interface.
+func (h PBKDF2SHA256Hasher) Verify(hash string, attempt []byte) error {
+	params, salt, key, err := h.parse(hash)
+	if err != nil {
+		return err
+	}
+	candidate := pbkdf2.Key(attempt, salt, params.Iterations, int(params.KeyLength), sha256.New)
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrIncorrectSecret
+	}
+	return nil
+}
+
+// NeedsRehash fills the SecretHasher interface.
+func (h PBKDF2SHA256Hasher) NeedsRehash(hash string) bool {
+	params, _, _, err := h.parse(hash)
+	if err != nil {
+		return true
+	}
+	return params != h
+}
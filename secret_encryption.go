@@ -0,0 +1,251 @@
+package clients
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// SecretKEKSize is the size, in bytes, of the key-encryption keys
+	// used to encrypt secrets. They're AES-256 keys.
+	SecretKEKSize = 32
+
+	// encryptedSecretVersion prefixes every encrypted secret, so the
+	// format can be changed later.
+	encryptedSecretVersion = "v1"
+	// encryptedSecretParts is the number of colon-separated parts in an
+	// encrypted secret: the version, the KEK ID, the wrapped data key, and
+	// the encrypted secret.
+	encryptedSecretParts = 4
+)
+
+var (
+	// ErrUnknownSecretKEK is returned when an encrypted secret was
+	// encrypted with a key-encryption key that isn't in the
+	// SecretKeyring.
+	ErrUnknownSecretKEK = errors.New("unknown secret key-encryption key")
+	// ErrInvalidEncryptedSecret is returned when an encrypted secret is
+	// malformed or can't be decrypted.
+	ErrInvalidEncryptedSecret = errors.New("invalid encrypted secret")
+	// ErrInvalidSecretKeyring is returned when a SecretKeyring can't be
+	// created from the keys passed to NewSecretKeyring.
+	ErrInvalidSecretKeyring = errors.New("invalid secret keyring")
+)
+
+// SecretKeyring envelope-encrypts the secrets of Clients using
+// AuthMethodClientSecretJWT, which need the raw secret to verify their
+// HMAC-signed assertions and so can't rely on a one-way SecretHash alone.
+//
+// Each secret is encrypted with its own randomly generated data key, which
+// is then encrypted ("wrapped") with a key-encryption key (KEK) held by the
+// server and identified by an ID. The KEK ID is stored with the encrypted
+// secret, so KEKs can be rotated: new secrets are wrapped with the current
+// KEK, secrets wrapped with previous KEKs can still be decrypted as long as
+// those KEKs are in the keyring, and Rewrap re-wraps a secret's data key
+// with the current KEK without needing to re-encrypt the secret itself.
+//
+// Encrypted secrets are bound to the ID of the Client they belong to, and
+// can't be decrypted for any other Client.
+type SecretKeyring struct {
+	current string
+	keks    map[string]cipher.AEAD
+}
+
+// NewSecretKeyring returns a SecretKeyring that encrypts new secrets using
+// the KEK identified by current, and can decrypt secrets encrypted using
+// any of keks. Each KEK must be SecretKEKSize bytes long, and KEK IDs must
+// not be empty or contain colons.
+func NewSecretKeyring(current string, keks map[string][]byte) (*SecretKeyring, error) {
+	if _, ok := keks[current]; !ok {
+		return nil, fmt.Errorf("%w: current KEK %q isn't in the keyring", ErrInvalidSecretKeyring, current)
+	}
+	keyring := &SecretKeyring{current: current, keks: make(map[string]cipher.AEAD, len(keks))}
+	for id, kek := range keks {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: KEK ID %q must be non-empty and not contain colons", ErrInvalidSecretKeyring, id)
+		}
+		if len(kek) != SecretKEKSize {
+			return nil, fmt.Errorf("%w: KEK %q must be %d bytes", ErrInvalidSecretKeyring, id, SecretKEKSize)
+		}
+		aead, err := newAEAD(kek)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keks[id] = aead
+	}
+	return keyring, nil
+}
+
+// CurrentKEK returns the ID of the KEK new secrets are encrypted with.
+func (k *SecretKeyring) CurrentKEK() string {
+	return k.current
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with aead, prepending the random nonce used.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts ciphertext produced by seal.
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidEncryptedSecret
+	}
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidEncryptedSecret
+	}
+	return plaintext, nil
+}
+
+// Encrypt encrypts secret for the Client with the passed clientID, using a
+// new data key wrapped with the current KEK. The result is suitable for
+// storing as the Client's EncryptedSecret.
+func (k *SecretKeyring) Encrypt(clientID string, secret []byte) (string, error) {
+	dataKey := make([]byte, SecretKEKSize)
+	_, err := io.ReadFull(rand.Reader, dataKey)
+	if err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataAEAD, secret, []byte(clientID))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keks[k.current], dataKey, []byte(k.current+":"+clientID))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		encryptedSecretVersion,
+		k.current,
+		base64.RawURLEncoding.EncodeToString(wrapped),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+	}, ":"), nil
+}
+
+// encryptedSecret is a parsed encrypted secret.
+type encryptedSecret struct {
+	kekID      string
+	wrapped    []byte
+	ciphertext []byte
+}
+
+func parseEncryptedSecret(encrypted string) (encryptedSecret, error) {
+	parts := strings.Split(encrypted, ":")
+	if len(parts) != encryptedSecretParts || parts[0] != encryptedSecretVersion {
+		return encryptedSecret{}, ErrInvalidEncryptedSecret
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return encryptedSecret{}, ErrInvalidEncryptedSecret
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return encryptedSecret{}, ErrInvalidEncryptedSecret
+	}
+	return encryptedSecret{kekID: parts[1], wrapped: wrapped, ciphertext: ciphertext}, nil
+}
+
+// unwrap returns the data key of parsed.
+func (k *SecretKeyring) unwrap(clientID string, parsed encryptedSecret) ([]byte, error) {
+	kek, ok := k.keks[parsed.kekID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSecretKEK, parsed.kekID)
+	}
+	return open(kek, parsed.wrapped, []byte(parsed.kekID+":"+clientID))
+}
+
+// Decrypt returns the secret encrypted for the Client with the passed
+// clientID. If the KEK it was encrypted with isn't in the keyring,
+// ErrUnknownSecretKEK is returned. If it's malformed, or was encrypted for
+// another Client, ErrInvalidEncryptedSecret is returned.
+func (k *SecretKeyring) Decrypt(clientID, encrypted string) ([]byte, error) {
+	parsed, err := parseEncryptedSecret(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := k.unwrap(clientID, parsed)
+	if err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(dataAEAD, parsed.ciphertext, []byte(clientID))
+}
+
+// NeedsRewrap returns true if encrypted wasn't wrapped with the current
+// KEK.
+func (k *SecretKeyring) NeedsRewrap(encrypted string) bool {
+	parsed, err := parseEncryptedSecret(encrypted)
+	if err != nil {
+		return false
+	}
+	return parsed.kekID != k.current
+}
+
+// Rewrap re-wraps the data key of the secret encrypted for the Client with
+// the passed clientID using the current KEK. The secret itself isn't
+// decrypted or re-encrypted.
+func (k *SecretKeyring) Rewrap(clientID, encrypted string) (string, error) {
+	parsed, err := parseEncryptedSecret(encrypted)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := k.unwrap(clientID, parsed)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keks[k.current], dataKey, []byte(k.current+":"+clientID))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		encryptedSecretVersion,
+		k.current,
+		base64.RawURLEncoding.EncodeToString(wrapped),
+		base64.RawURLEncoding.EncodeToString(parsed.ciphertext),
+	}, ":"), nil
+}
+
+// ChangeSecret generates a Change that will update the secret of the Client
+// with the passed clientID, both hashed using the scheme returned by
+// DefaultSecretScheme and encrypted using the current KEK. It should be
+// used instead of the package-level ChangeSecret for Clients using
+// AuthMethodClientSecretJWT.
+func (k *SecretKeyring) ChangeSecret(clientID string, newSecret []byte) (Change, error) {
+	change, err := ChangeSecret(newSecret)
+	if err != nil {
+		return Change{}, err
+	}
+	encrypted, err := k.Encrypt(clientID, newSecret)
+	if err != nil {
+		return Change{}, err
+	}
+	change.EncryptedSecret = &encrypted
+	return change, nil
+}
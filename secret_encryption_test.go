@@ -0,0 +1,229 @@
+package clients_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func kekOrFail(t *testing.T) []byte {
+	t.Helper()
+	kek := make([]byte, clients.SecretKEKSize)
+	_, err := rand.Read(kek)
+	if err != nil {
+		t.Fatalf("Error generating KEK: %s", err)
+	}
+	return kek
+}
+
+func TestSecretKeyringRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	keyring, err := clients.NewSecretKeyring("kek-1", map[string][]byte{"kek-1": kekOrFail(t)})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %s", err)
+	}
+	encrypted, err := keyring.Encrypt("client-1", []byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error encrypting secret: %s", err)
+	}
+	if bytes.Contains([]byte(encrypted), []byte("test secret")) {
+		t.Errorf("Encrypted secret contains the plaintext: %s", encrypted)
+	}
+	secret, err := keyring.Decrypt("client-1", encrypted)
+	if err != nil {
+		t.Fatalf("Error decrypting secret: %s", err)
+	}
+	if string(secret) != "test secret" {
+		t.Errorf("Expected %q, got %q", "test secret", secret)
+	}
+	_, err = keyring.Decrypt("client-2", encrypted)
+	if !errors.Is(err, clients.ErrInvalidEncryptedSecret) {
+		t.Errorf("Expected %v decrypting for another client, got %v", clients.ErrInvalidEncryptedSecret, err)
+	}
+	_, err = keyring.Decrypt("client-1", "not an encrypted secret")
+	if !errors.Is(err, clients.ErrInvalidEncryptedSecret) {
+		t.Errorf("Expected %v for a malformed secret, got %v", clients.ErrInvalidEncryptedSecret, err)
+	}
+}
+
+func TestSecretKeyringRotation(t *testing.T) {
+	t.Parallel()
+
+	oldKEK, newKEK := kekOrFail(t), kekOrFail(t)
+	oldKeyring, err := clients.NewSecretKeyring("kek-1", map[string][]byte{"kek-1": oldKEK})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %s", err)
+	}
+	encrypted, err := oldKeyring.Encrypt("client-1", []byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error encrypting secret: %s", err)
+	}
+
+	rotated, err := clients.NewSecretKeyring("kek-2", map[string][]byte{"kek-1": oldKEK, "kek-2": newKEK})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %s", err)
+	}
+	if !rotated.NeedsRewrap(encrypted) {
+		t.Error("Expected secret encrypted with previous KEK to need re-wrapping")
+	}
+	secret, err := rotated.Decrypt("client-1", encrypted)
+	if err != nil || string(secret) != "test secret" {
+		t.Fatalf("Expected previous KEK to still decrypt, got %q, %v", secret, err)
+	}
+	rewrapped, err := rotated.Rewrap("client-1", encrypted)
+	if err != nil {
+		t.Fatalf("Error re-wrapping secret: %s", err)
+	}
+	if rotated.NeedsRewrap(rewrapped) {
+		t.Error("Expected re-wrapped secret not to need re-wrapping")
+	}
+
+	// once the previous KEK is removed, only re-wrapped secrets can be
+	// decrypted
+	retired, err := clients.NewSecretKeyring("kek-2", map[string][]byte{"kek-2": newKEK})
+	if err != nil {
+		t.Fatalf("Error creating keyring: %s", err)
+	}
+	secret, err = retired.Decrypt("client-1", rewrapped)
+	if err != nil || string(secret) != "test secret" {
+		t.Errorf("Expected re-wrapped secret to decrypt, got %q, %v", secret, err)
+	}
+	_, err = retired.Decrypt("client-1", encrypted)
+	if !errors.Is(err, clients.ErrUnknownSecretKEK) {
+		t.Errorf("Expected %v, got %v", clients.ErrUnknownSecretKEK, err)
+	}
+}
+
+func TestNewSecretKeyringValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		current string
+		keks    map[string][]byte
+	}{
+		"missing-current": {current: "kek-2", keks: map[string][]byte{"kek-1": kekOrFail(t)}},
+		"short-kek":       {current: "kek-1", keks: map[string][]byte{"kek-1": []byte("too short")}},
+		"colon-in-id":     {current: "kek:1", keks: map[string][]byte{"kek:1": kekOrFail(t)}},
+	}
+	for name, test := range tests {
+		_, err := clients.NewSecretKeyring(test.current, test.keks)
+		if !errors.Is(err, clients.ErrInvalidSecretKeyring) {
+			t.Errorf("%s: expected %v, got %v", name, clients.ErrInvalidSecretKeyring, err)
+		}
+	}
+}
+
+func signHS256Assertion(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	input := base64.RawURLEncoding.EncodeToString(jsonOrFail(t, map[string]string{"alg": "HS256"})) + "." +
+		base64.RawURLEncoding.EncodeToString(jsonOrFail(t, claims))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAssertionVerifierClientSecretJWT(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		oldKEK := kekOrFail(t)
+		oldKeyring, err := clients.NewSecretKeyring("kek-1", map[string][]byte{"kek-1": oldKEK})
+		if err != nil {
+			t.Fatalf("Error creating keyring: %s", err)
+		}
+		client := clients.Client{
+			ID:                      uuidOrFail(t),
+			Name:                    "Test Client",
+			Confidential:            true,
+			TokenEndpointAuthMethod: clients.AuthMethodClientSecretJWT,
+			CreatedAt:               time.Now().Round(time.Millisecond),
+			CreatedBy:               "test",
+			CreatedByIP:             "127.0.0.1",
+		}
+		secret := []byte("0123456789abcdef0123456789abcdef")
+		change, err := oldKeyring.ChangeSecret(client.ID, secret)
+		if err != nil {
+			t.Fatalf("Error changing secret: %s", err)
+		}
+		client = clients.Apply(change, client)
+		err = client.ValidateAuthMethod()
+		if err != nil {
+			t.Fatalf("Unexpected error validating auth method: %s", err)
+		}
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+
+		// the KEK is rotated before the client next authenticates
+		keyring, err := clients.NewSecretKeyring("kek-2", map[string][]byte{"kek-1": oldKEK, "kek-2": kekOrFail(t)})
+		if err != nil {
+			t.Fatalf("Error creating keyring: %s", err)
+		}
+		verifier := clients.AssertionVerifier{
+			Storer:        storer,
+			SecretKeyring: keyring,
+			ReplayCache:   &clients.MemoryReplayCache{},
+			Audiences:     []string{"https://auth.example.com/token"},
+		}
+		claims := func() map[string]interface{} {
+			return map[string]interface{}{
+				"iss": client.ID,
+				"sub": client.ID,
+				"aud": "https://auth.example.com/token",
+				"exp": time.Now().Add(time.Minute).Unix(),
+				"jti": uuidOrFail(t),
+			}
+		}
+
+		res, err := verifier.Verify(ctx, signHS256Assertion(t, secret, claims()))
+		if err != nil {
+			t.Fatalf("Unexpected error verifying assertion: %s", err)
+		}
+		if keyring.NeedsRewrap(res.EncryptedSecret) {
+			t.Error("Expected returned client's secret to be re-wrapped")
+		}
+		stored, err := storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.EncryptedSecret != res.EncryptedSecret {
+			t.Error("Expected re-wrapped secret to be stored")
+		}
+
+		_, err = verifier.Verify(ctx, signHS256Assertion(t, []byte("wrong secret"), claims()))
+		if !errors.Is(err, clients.ErrInvalidAssertion) {
+			t.Errorf("Expected %v for the wrong secret, got %v", clients.ErrInvalidAssertion, err)
+		}
+		verifier.SecretKeyring = nil
+		_, err = verifier.Verify(ctx, signHS256Assertion(t, secret, claims()))
+		if !errors.Is(err, clients.ErrInvalidAssertion) {
+			t.Errorf("Expected %v without a keyring, got %v", clients.ErrInvalidAssertion, err)
+		}
+	})
+}
+
+func TestValidateAuthMethodEncryptedSecret(t *testing.T) {
+	t.Parallel()
+
+	jwtClient := clients.Client{Confidential: true, TokenEndpointAuthMethod: clients.AuthMethodClientSecretJWT, SecretHash: "hash"}
+	err := jwtClient.ValidateAuthMethod()
+	var authMethodErr clients.AuthMethodError
+	if !errors.As(err, &authMethodErr) {
+		t.Errorf("Expected AuthMethodError for client_secret_jwt client without encrypted secret, got %v", err)
+	}
+	basicClient := clients.Client{Confidential: true, TokenEndpointAuthMethod: clients.AuthMethodClientSecretBasic, SecretHash: "hash", EncryptedSecret: "encrypted"}
+	err = basicClient.ValidateAuthMethod()
+	if !errors.As(err, &authMethodErr) {
+		t.Errorf("Expected AuthMethodError for client_secret_basic client with encrypted secret, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package clients
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultSecretPrefix is the prefix of generated secrets if
+	// SecretFormat.Prefix isn't set.
+	DefaultSecretPrefix = "lbcs"
+	// DefaultSecretEnvironment is the environment encoded in generated
+	// secrets if SecretFormat.Environment isn't set.
+	DefaultSecretEnvironment = "live"
+
+	// secretRandomLength is the number of random base62 characters in a
+	// generated secret, giving about 190 bits of entropy.
+	secretRandomLength = 32
+	// secretChecksumLength is the number of base62 characters the CRC32
+	// checksum of a secret is encoded as, which is enough for any uint32.
+	secretChecksumLength = 6
+	// secretSeparator separates the prefix, environment, and body of a
+	// secret.
+	secretSeparator = "_"
+
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	// ErrInvalidSecretFormat is returned when a SecretFormat's prefix or
+	// environment can't be used.
+	ErrInvalidSecretFormat = errors.New("invalid secret format")
+	// ErrMalformedSecret is returned when a secret doesn't match a
+	// SecretFormat, or its checksum is wrong.
+	ErrMalformedSecret = errors.New("malformed secret")
+
+	secretFormatPartPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+)
+
+// SecretFormat describes the format of generated secrets, which makes them
+// identifiable so leaked secrets can be found by secret scanners.
+//
+// Secrets are made up of the Prefix, the Environment, and a body of random
+// base62 characters followed by a base62-encoded CRC32 checksum of
+// everything before it, separated by underscores:
+//
+//	lbcs_live_<32 random characters><6 checksum characters>
+//
+// The checksum lets typos and truncated secrets be rejected without looking
+// anything up.
+type SecretFormat struct {
+	// Prefix identifies secrets as client secrets. It must only contain
+	// lowercase letters and digits. If unset, DefaultSecretPrefix is used.
+	Prefix string
+
+	// Environment identifies the environment the secret's client belongs
+	// to, like "live" or "test". It must only contain lowercase letters
+	// and digits. If unset, DefaultSecretEnvironment is used.
+	Environment string
+}
+
+func (f SecretFormat) prefix() string {
+	if f.Prefix != "" {
+		return f.Prefix
+	}
+	return DefaultSecretPrefix
+}
+
+func (f SecretFormat) environment() string {
+	if f.Environment != "" {
+		return f.Environment
+	}
+	return DefaultSecretEnvironment
+}
+
+// Check returns an ErrInvalidSecretFormat error if the SecretFormat's prefix
+// or environment can't be used.
+func (f SecretFormat) Check() error {
+	if !secretFormatPartPattern.MatchString(f.prefix()) {
+		return fmt.Errorf("%w: prefix %q must only contain lowercase letters and digits", ErrInvalidSecretFormat, f.prefix())
+	}
+	if !secretFormatPartPattern.MatchString(f.environment()) {
+		return fmt.Errorf("%w: environment %q must only contain lowercase letters and digits", ErrInvalidSecretFormat, f.environment())
+	}
+	return nil
+}
+
+// Generate returns a new random secret in the SecretFormat.
+func (f SecretFormat) Generate() (string, error) {
+	err := f.Check()
+	if err != nil {
+		return "", err
+	}
+	random := make([]byte, secretRandomLength)
+	max := big.NewInt(int64(len(base62Alphabet)))
+	for i := range random {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		random[i] = base62Alphabet[n.Int64()]
+	}
+	unchecked := f.prefix() + secretSeparator + f.environment() + secretSeparator + string(random)
+	return unchecked + secretChecksum(unchecked), nil
+}
+
+// Validate returns nil if secret was generated in the SecretFormat and its
+// checksum is correct, or an ErrMalformedSecret error otherwise.
+func (f SecretFormat) Validate(secret string) error {
+	parsed, err := ParseSecret(secret)
+	if err != nil {
+		return err
+	}
+	if parsed.Prefix != f.prefix() {
+		return fmt.Errorf("%w: expected prefix %q", ErrMalformedSecret, f.prefix())
+	}
+	if parsed.Environment != f.environment() {
+		return fmt.Errorf("%w: expected environment %q", ErrMalformedSecret, f.environment())
+	}
+	return nil
+}
+
+// HasPrefix returns true if secret starts with the SecretFormat's prefix,
+// meaning it's expected to be in the SecretFormat. Secrets generated before
+// the SecretFormat was used won't have the prefix.
+func (f SecretFormat) HasPrefix(secret string) bool {
+	return strings.HasPrefix(secret, f.prefix()+secretSeparator)
+}
+
+// Pattern returns a regular expression matching secrets in the
+// SecretFormat, suitable for configuring secret scanners. Matches still
+// need to be checked with Validate.
+func (f SecretFormat) Pattern() string {
+	return `\b` + regexp.QuoteMeta(f.prefix()+secretSeparator+f.environment()+secretSeparator) +
+		fmt.Sprintf("[0-9A-Za-z]{%d}", secretRandomLength+secretChecksumLength) + `\b`
+}
+
+// ParsedSecret describes a secret parsed by ParseSecret.
+type ParsedSecret struct {
+	Prefix      string // the prefix identifying the secret as a client secret
+	Environment string // the environment the secret's client belongs to
+}
+
+// ParseSecret parses a secret generated by a SecretFormat, without needing
+// to know the SecretFormat's prefix or environment. If secret isn't in the
+// format, or its checksum is wrong, an ErrMalformedSecret error is returned.
+func ParseSecret(secret string) (ParsedSecret, error) {
+	parts := strings.Split(secret, secretSeparator)
+	if len(parts) != 3 { //nolint:gomnd // prefix, environment, and body
+		return ParsedSecret{}, fmt.Errorf("%w: expected a prefix, environment, and body", ErrMalformedSecret)
+	}
+	prefix, environment, body := parts[0], parts[1], parts[2]
+	if !secretFormatPartPattern.MatchString(prefix) || !secretFormatPartPattern.MatchString(environment) {
+		return ParsedSecret{}, fmt.Errorf("%w: invalid prefix or environment", ErrMalformedSecret)
+	}
+	if len(body) != secretRandomLength+secretChecksumLength {
+		return ParsedSecret{}, fmt.Errorf("%w: body must be %d characters", ErrMalformedSecret, secretRandomLength+secretChecksumLength)
+	}
+	for _, c := range body {
+		if !strings.ContainsRune(base62Alphabet, c) {
+			return ParsedSecret{}, fmt.Errorf("%w: body must only contain letters and digits", ErrMalformedSecret)
+		}
+	}
+	split := len(secret) - secretChecksumLength
+	if secretChecksum(secret[:split]) != secret[split:] {
+		return ParsedSecret{}, fmt.Errorf("%w: checksum mismatch", ErrMalformedSecret)
+	}
+	return ParsedSecret{Prefix: prefix, Environment: environment}, nil
+}
+
+// secretChecksum returns the CRC32 checksum of unchecked, encoded as
+// secretChecksumLength base62 characters.
+func secretChecksum(unchecked string) string {
+	sum := crc32.ChecksumIEEE([]byte(unchecked))
+	encoded := make([]byte, secretChecksumLength)
+	for i := secretChecksumLength - 1; i >= 0; i-- {
+		encoded[i] = base62Alphabet[sum%uint32(len(base62Alphabet))]
+		sum /= uint32(len(base62Alphabet))
+	}
+	return string(encoded)
+}
@@ -0,0 +1,160 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func TestSecretFormatRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	format := clients.SecretFormat{Prefix: "test", Environment: "staging"}
+	secret, err := format.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	if !strings.HasPrefix(secret, "test_staging_") {
+		t.Errorf("Expected secret to start with %q, got %q", "test_staging_", secret)
+	}
+	if err := format.Validate(secret); err != nil {
+		t.Errorf("Expected generated secret to be valid, got %v", err)
+	}
+	parsed, err := clients.ParseSecret(secret)
+	if err != nil {
+		t.Fatalf("Error parsing secret: %s", err)
+	}
+	if parsed.Prefix != "test" || parsed.Environment != "staging" {
+		t.Errorf("Expected prefix %q and environment %q, got %+v", "test", "staging", parsed)
+	}
+	if !regexp.MustCompile(format.Pattern()).MatchString("leaked: " + secret + "\n") {
+		t.Errorf("Expected pattern %q to match %q", format.Pattern(), secret)
+	}
+
+	other, err := format.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	if other == secret {
+		t.Errorf("Expected generated secrets to differ, got %q twice", secret)
+	}
+}
+
+func TestSecretFormatDefaults(t *testing.T) {
+	t.Parallel()
+
+	secret, err := clients.SecretFormat{}.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	want := clients.DefaultSecretPrefix + "_" + clients.DefaultSecretEnvironment + "_"
+	if !strings.HasPrefix(secret, want) {
+		t.Errorf("Expected secret to start with %q, got %q", want, secret)
+	}
+}
+
+func TestSecretFormatRejectsMalformed(t *testing.T) {
+	t.Parallel()
+
+	format := clients.SecretFormat{}
+	secret, err := format.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	// change one character of the random part
+	idx := len(secret) - 10
+	replacement := "A"
+	if secret[idx] == 'A' {
+		replacement = "B"
+	}
+	typo := secret[:idx] + replacement + secret[idx+1:]
+
+	for name, candidate := range map[string]string{
+		"typo":             typo,
+		"truncated":        secret[:len(secret)-1],
+		"extended":         secret + "0",
+		"invalidChar":      secret[:idx] + "-" + secret[idx+1:],
+		"missingPrefix":    strings.TrimPrefix(secret, clients.DefaultSecretPrefix+"_"),
+		"wrongPrefix":      "other" + strings.TrimPrefix(secret, clients.DefaultSecretPrefix),
+		"wrongEnvironment": strings.Replace(secret, "_"+clients.DefaultSecretEnvironment+"_", "_test_", 1),
+		"legacy":           "0123456789abcdef0123456789abcdef",
+	} {
+		name, candidate := name, candidate
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := format.Validate(candidate)
+			if !errors.Is(err, clients.ErrMalformedSecret) {
+				t.Errorf("Expected %v for %q, got %v", clients.ErrMalformedSecret, candidate, err)
+			}
+		})
+	}
+}
+
+func TestSecretFormatInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []clients.SecretFormat{
+		{Prefix: "Upper"},
+		{Prefix: "under_score"},
+		{Environment: "has space"},
+	} {
+		_, err := format.Generate()
+		if !errors.Is(err, clients.ErrInvalidSecretFormat) {
+			t.Errorf("Expected %v for %+v, got %v", clients.ErrInvalidSecretFormat, format, err)
+		}
+	}
+}
+
+func TestAuthenticatorRejectsMalformedSecret(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		format := clients.SecretFormat{}
+		secret, err := format.Generate()
+		if err != nil {
+			t.Fatalf("Error generating secret: %s", err)
+		}
+		legacy := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Legacy Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		client := legacy
+		client.ID = uuidOrFail(t)
+		client.Name = "Test Client"
+		for c, value := range map[*clients.Client]string{&legacy: "0123456789abcdef0123456789abcdef", &client: secret} {
+			change, err := clients.ChangeSecret([]byte(value))
+			if err != nil {
+				t.Fatalf("Error generating client secret: %s", err)
+			}
+			*c = clients.Apply(change, *c)
+			err = storer.Create(ctx, *c)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+		}
+
+		authenticator := clients.Authenticator{Storer: storer, SecretFormat: &format}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, secret)
+		if err != nil {
+			t.Errorf("Error authenticating client: %s", err)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, secret[:len(secret)-1])
+		if !errors.Is(err, clients.ErrMalformedSecret) {
+			t.Errorf("Expected %v for a truncated secret, got %v", clients.ErrMalformedSecret, err)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, legacy.ID, "0123456789abcdef0123456789abcdef")
+		if err != nil {
+			t.Errorf("Expected legacy secret without the prefix to be accepted, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package clients
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultSecretMaxAge is how long a secret may go without being
+	// rotated if SecretRotationPolicy.MaxAge isn't set.
+	DefaultSecretMaxAge = 90 * 24 * time.Hour
+	// DefaultSecretExpiryWindow is how soon before a secret expires it's
+	// due for rotation if SecretRotationPolicy.ExpiryWindow isn't set.
+	DefaultSecretExpiryWindow = 14 * 24 * time.Hour
+)
+
+// SecretRotationPolicy describes when Clients' secrets are due to be
+// rotated: when they haven't been rotated for MaxAge, or when they expire
+// within ExpiryWindow.
+type SecretRotationPolicy struct {
+	// MaxAge is how long a secret may go without being rotated. If
+	// unset, DefaultSecretMaxAge is used.
+	MaxAge time.Duration
+
+	// ExpiryWindow is how soon before a secret expires it's due for
+	// rotation. If unset, DefaultSecretExpiryWindow is used.
+	ExpiryWindow time.Duration
+
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// DueClients returns the Clients in storer whose secrets are due to be
+// rotated under the policy, including Clients whose secrets have already
+// expired and Clients whose secrets were set before rotations were
+// recorded. They're sorted by when their secrets were last rotated, oldest
+// first.
+func (p SecretRotationPolicy) DueClients(ctx context.Context, storer Storer) ([]Client, error) {
+	now := p.now()
+	return storer.ListClientsDueForRotation(ctx, now.Add(-p.maxAge()), now.Add(p.expiryWindow()))
+}
+
+// Due returns true if client's secret is due to be rotated under the
+// policy.
+func (p SecretRotationPolicy) Due(client Client) bool {
+	if !client.HasSecret() {
+		return false
+	}
+	now := p.now()
+	if client.SecretRotatedAt.IsZero() || client.SecretRotatedAt.Before(now.Add(-p.maxAge())) {
+		return true
+	}
+	return !client.SecretExpiresAt.IsZero() && client.SecretExpiresAt.Before(now.Add(p.expiryWindow()))
+}
+
+func (p SecretRotationPolicy) maxAge() time.Duration {
+	if p.MaxAge > 0 {
+		return p.MaxAge
+	}
+	return DefaultSecretMaxAge
+}
+
+func (p SecretRotationPolicy) expiryWindow() time.Duration {
+	if p.ExpiryWindow > 0 {
+		return p.ExpiryWindow
+	}
+	return DefaultSecretExpiryWindow
+}
+
+func (p SecretRotationPolicy) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
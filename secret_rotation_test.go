@@ -0,0 +1,157 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/clients"
+)
+
+func TestCheckSecretExpired(t *testing.T) {
+	t.Parallel()
+
+	change, err := clients.ChangeSecret([]byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error generating client secret: %s", err)
+	}
+	client := clients.Apply(change, clients.Client{ID: "test-client"})
+
+	client.SecretExpiresAt = time.Now().Add(time.Hour)
+	if err := client.CheckSecret("test secret"); err != nil {
+		t.Errorf("Expected unexpired secret to be accepted, got %v", err)
+	}
+
+	client.SecretExpiresAt = time.Now().Add(-time.Hour)
+	if err := client.CheckSecret("test secret"); !errors.Is(err, clients.ErrSecretExpired) {
+		t.Errorf("Expected %v, got %v", clients.ErrSecretExpired, err)
+	}
+	// expiry isn't revealed to callers without the secret
+	if err := client.CheckSecret("wrong secret"); !errors.Is(err, clients.ErrIncorrectSecret) {
+		t.Errorf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+	}
+}
+
+func TestAuthenticatorRejectsExpiredSecret(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		change, err := clients.ChangeSecret([]byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		expiresAt := time.Now().Add(-time.Minute).Round(time.Millisecond)
+		change.SecretExpiresAt = &expiresAt
+		client := clients.Apply(change, clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		})
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		authenticator := clients.Authenticator{Storer: storer}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, "test secret")
+		if !errors.Is(err, clients.ErrSecretExpired) {
+			t.Errorf("Expected %v, got %v", clients.ErrSecretExpired, err)
+		}
+	})
+}
+
+func TestSecretRotationPolicyDueClients(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		now := time.Now().Round(time.Millisecond)
+		policy := clients.SecretRotationPolicy{
+			MaxAge:       30 * 24 * time.Hour,
+			ExpiryWindow: 7 * 24 * time.Hour,
+			Now:          func() time.Time { return now },
+		}
+		secret, err := clients.ChangeSecret([]byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		create := func(name string, hasSecret bool, rotatedAt, expiresAt time.Time) clients.Client {
+			client := clients.Client{
+				ID:              uuidOrFail(t),
+				Name:            name,
+				Confidential:    true,
+				SecretRotatedAt: rotatedAt,
+				SecretExpiresAt: expiresAt,
+				CreatedAt:       now,
+				CreatedBy:       "test",
+				CreatedByIP:     "127.0.0.1",
+			}
+			if hasSecret {
+				client = clients.Apply(secret, client)
+			}
+			err := storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+			return client
+		}
+		old := create("old", true, now.Add(-60*24*time.Hour), time.Time{})
+		unrecorded := create("unrecorded", true, time.Time{}, time.Time{})
+		expiring := create("expiring", true, now.Add(-time.Hour), now.Add(24*time.Hour))
+		expired := create("expired", true, now.Add(-2*time.Hour), now.Add(-time.Hour))
+		fresh := create("fresh", true, now.Add(-time.Hour), now.Add(30*24*time.Hour))
+		public := create("public", false, time.Time{}, time.Time{})
+		// clients that don't use a secret are never due, even if a hash
+		// was stored for them
+		var hashed []clients.Client
+		for _, client := range []clients.Client{
+			{Name: "hashed public"},
+			{Name: "hashed private_key_jwt", Confidential: true, TokenEndpointAuthMethod: clients.AuthMethodPrivateKeyJWT},
+		} {
+			client.ID = uuidOrFail(t)
+			client.CreatedAt = now
+			client.CreatedBy = "test"
+			client.CreatedByIP = "127.0.0.1"
+			client = clients.Apply(secret, client)
+			err := storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+			hashed = append(hashed, client)
+		}
+
+		due, err := policy.DueClients(ctx, storer)
+		if err != nil {
+			t.Fatalf("Error listing clients due for rotation: %s", err)
+		}
+		created := map[string]bool{old.ID: true, unrecorded.ID: true, expiring.ID: true, expired.ID: true, fresh.ID: true, public.ID: true}
+		for _, client := range hashed {
+			created[client.ID] = true
+		}
+		var got []clients.Client
+		for _, client := range due {
+			// other tests may share the Storer
+			if created[client.ID] {
+				got = append(got, client)
+			}
+		}
+		want := []clients.Client{unrecorded, old, expired, expiring}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+		for _, client := range []clients.Client{old, unrecorded, expiring, expired} {
+			if !policy.Due(client) {
+				t.Errorf("Expected %s client to be due for rotation", client.Name)
+			}
+		}
+		for _, client := range append([]clients.Client{fresh, public}, hashed...) {
+			if policy.Due(client) {
+				t.Errorf("Expected %s client not to be due for rotation", client.Name)
+			}
+		}
+	})
+}
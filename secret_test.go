@@ -0,0 +1,89 @@
+package clients_test
+
+import (
+	"errors"
+	"testing"
+
+	"lockbox.dev/clients"
+)
+
+func TestSecretHashersRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	hashers := map[string]clients.SecretHasher{
+		"test-argon2id": clients.Argon2idHasher{Time: 1, Memory: 64, Threads: 1, SaltLength: 16, KeyLength: 32},
+		"test-bcrypt":   clients.BcryptHasher{Cost: 4},
+		"test-pbkdf2":   clients.PBKDF2SHA256Hasher{Iterations: 10, SaltLength: 16, KeyLength: 32},
+	}
+	for scheme, hasher := range hashers {
+		scheme, hasher := scheme, hasher
+		clients.RegisterSecretHasher(scheme, hasher)
+		t.Run(scheme, func(t *testing.T) {
+			t.Parallel()
+
+			change, err := clients.ChangeSecretWithScheme(scheme, []byte("test secret"))
+			if err != nil {
+				t.Fatalf("Error generating client secret: %s", err)
+			}
+			client := clients.Apply(change, clients.Client{ID: uuidOrFail(t)})
+			if client.SecretScheme != scheme {
+				t.Errorf("Expected scheme %q, got %q", scheme, client.SecretScheme)
+			}
+			if err := client.CheckSecret("test secret"); err != nil {
+				t.Errorf("Expected correct secret to be accepted, got %v", err)
+			}
+			if err := client.CheckSecret("wrong secret"); !errors.Is(err, clients.ErrIncorrectSecret) {
+				t.Errorf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+			}
+			if hasher.NeedsRehash(client.SecretHash) {
+				t.Errorf("Expected hash %q not to need rehashing", client.SecretHash)
+			}
+		})
+	}
+}
+
+func TestSecretHasherNeedsRehashOnParameterChange(t *testing.T) {
+	t.Parallel()
+
+	old := clients.PBKDF2SHA256Hasher{Iterations: 10, SaltLength: 16, KeyLength: 32}
+	hash, err := old.Hash([]byte("test secret"))
+	if err != nil {
+		t.Fatalf("Error hashing secret: %s", err)
+	}
+	updated := clients.PBKDF2SHA256Hasher{Iterations: 20, SaltLength: 16, KeyLength: 32}
+	if !updated.NeedsRehash(hash) {
+		t.Errorf("Expected hash %q to need rehashing", hash)
+	}
+	// parameters are read from the hash, not the hasher
+	if err := updated.Verify(hash, []byte("test secret")); err != nil {
+		t.Errorf("Expected secret to verify with new parameters, got %v", err)
+	}
+}
+
+func TestSecretLegacySHA256(t *testing.T) {
+	t.Parallel()
+
+	// generated by the original implementation of ChangeSecret
+	client := clients.Client{
+		SecretHash:   "7465737420736563726574e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		SecretScheme: clients.SecretSchemeSHA256,
+	}
+	if err := client.CheckSecret("test secret"); err != nil {
+		t.Errorf("Expected correct secret to be accepted, got %v", err)
+	}
+	if err := client.CheckSecret("wrong secret"); !errors.Is(err, clients.ErrIncorrectSecret) {
+		t.Errorf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+	}
+}
+
+func TestSecretUnsupportedScheme(t *testing.T) {
+	t.Parallel()
+
+	client := clients.Client{SecretHash: "abc", SecretScheme: "rot13"}
+	if err := client.CheckSecret("abc"); !errors.Is(err, clients.ErrUnsupportedSecretScheme) {
+		t.Errorf("Expected %v, got %v", clients.ErrUnsupportedSecretScheme, err)
+	}
+	if err := clients.SetDefaultSecretScheme("rot13"); !errors.Is(err, clients.ErrUnsupportedSecretScheme) {
+		t.Errorf("Expected %v, got %v", clients.ErrUnsupportedSecretScheme, err)
+	}
+}
@@ -0,0 +1,14 @@
+// Package secretscanning provides a net/http handler that revokes client
+// secrets reported leaked by secret scanners.
+//
+// The handler accepts the payload format used by secret-scanning partner
+// programs: a JSON array of reported tokens, each with the token itself,
+// its type, and where it was found. Requests must be signed by the
+// reporter, with the ID of the signing key and a base64-encoded ECDSA
+// P-256 SHA-256 signature of the body in request headers, and are rejected
+// unless the signature verifies against one of the configured public keys.
+//
+// Reported tokens that are client secrets are revoked, and the handler
+// responds with a JSON array labelling each token as a true_positive or
+// false_positive, identified by the SHA-256 hash of the token.
+package secretscanning
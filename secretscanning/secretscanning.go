@@ -0,0 +1,202 @@
+package secretscanning
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	yall "yall.in"
+
+	"lockbox.dev/clients"
+)
+
+const (
+	// DefaultKeyIDHeader is the header the ID of the key a report was
+	// signed with is read from, if Handler.KeyIDHeader isn't set.
+	DefaultKeyIDHeader = "Github-Public-Key-Identifier"
+	// DefaultSignatureHeader is the header the signature of a report is
+	// read from, if Handler.SignatureHeader isn't set.
+	DefaultSignatureHeader = "Github-Public-Key-Signature"
+	// DefaultMaxBodyBytes is the largest report accepted, if
+	// Handler.MaxBodyBytes isn't set.
+	DefaultMaxBodyBytes = 1 << 20
+
+	// LabelTruePositive labels a reported token that was a client secret
+	// and has been revoked.
+	LabelTruePositive = "true_positive"
+	// LabelFalsePositive labels a reported token that isn't a known client
+	// secret.
+	LabelFalsePositive = "false_positive"
+)
+
+// Report is a single token reported leaked.
+type Report struct {
+	Token  string `json:"token"`  // the leaked token
+	Type   string `json:"type"`   // the type of token, as identified by the scanner
+	URL    string `json:"url"`    // where the token was found
+	Source string `json:"source"` // the kind of place the token was found, like "content" or "commit"
+}
+
+// Result describes what happened to a reported token.
+type Result struct {
+	TokenHash string `json:"token_hash"` // the hex-encoded SHA-256 hash of the token
+	TokenType string `json:"token_type"` // the type of token, as reported
+	Label     string `json:"label"`      // LabelTruePositive or LabelFalsePositive
+}
+
+// Handler is an http.Handler that revokes client secrets reported leaked.
+// Reported tokens that are one of a Client's additional Secrets have that
+// Secret removed; reported tokens that are a Client's own secret have the
+// Client suspended. See clients.RevokeLeakedSecret.
+type Handler struct {
+	// Storer is used to find and revoke reported secrets.
+	Storer clients.Storer
+
+	// PublicKeys are the keys reports may be signed with, keyed by their
+	// ID. Reports that aren't signed by one of them are rejected.
+	PublicKeys map[string]*ecdsa.PublicKey
+
+	// KeyIDHeader is the header the ID of the key a report was signed
+	// with is read from. If unset, DefaultKeyIDHeader is used.
+	KeyIDHeader string
+
+	// SignatureHeader is the header the signature of a report is read
+	// from. If unset, DefaultSignatureHeader is used.
+	SignatureHeader string
+
+	// MaxBodyBytes is the largest report accepted. If unset,
+	// DefaultMaxBodyBytes is used.
+	MaxBodyBytes int64
+
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// ServeHTTP verifies the signature of the report in r, revokes each of the
+// client secrets reported in it, and writes a Result for each reported
+// token, in the order they were reported.
+//
+// If any reported secret can't be checked or revoked, a 500 is returned
+// without any Results, so the reporter retries the whole report. Revoking
+// a secret more than once is harmless, so retries are safe.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := yall.FromContext(r.Context())
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, h.maxBodyBytes()+1))
+	if err != nil {
+		log.WithError(err).Error("error reading secret scanning report")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if int64(len(body)) > h.maxBodyBytes() {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	keyID := r.Header.Get(h.keyIDHeader())
+	if !h.verify(keyID, r.Header.Get(h.signatureHeader()), body) {
+		log.WithField("key_id", keyID).Debug("rejected secret scanning report with invalid signature")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var reports []Report
+	err = json.Unmarshal(body, &reports)
+	if err != nil {
+		log.WithError(err).Debug("error decoding secret scanning report")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	results := make([]Result, 0, len(reports))
+	for _, report := range reports {
+		result, err := h.revoke(r, keyID, report)
+		if err != nil {
+			log.WithError(err).Error("error revoking leaked client secret")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		results = append(results, result)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results) //nolint:errcheck // the status has already been written, nothing else can be done
+}
+
+// verify returns true if signature is a valid signature of body by the
+// public key identified by keyID.
+func (h Handler) verify(keyID, signature string, body []byte) bool {
+	key, ok := h.PublicKeys[keyID]
+	if keyID == "" || !ok || key == nil {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256(body)
+	return ecdsa.VerifyASN1(key, digest[:], sig)
+}
+
+// revoke revokes the secret in report, if it's a client secret.
+func (h Handler) revoke(r *http.Request, keyID string, report Report) (Result, error) {
+	tokenHash := sha256.Sum256([]byte(report.Token))
+	result := Result{
+		TokenHash: hex.EncodeToString(tokenHash[:]),
+		TokenType: report.Type,
+		Label:     LabelFalsePositive,
+	}
+	// secrets that fail their checksum can't be client secrets, and
+	// don't need to be looked up
+	if _, err := clients.ParseSecret(report.Token); err != nil {
+		return result, nil
+	}
+	owner, err := clients.RevokeLeakedSecret(r.Context(), h.Storer, report.Token, "secret-scanning:"+keyID, h.now())
+	if errors.Is(err, clients.ErrSecretNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	yall.FromContext(r.Context()).WithField("client_id", owner.ClientID).WithField("secret_id", owner.SecretID).
+		WithField("url", report.URL).WithField("source", report.Source).
+		Warn("revoked client secret reported leaked")
+	result.Label = LabelTruePositive
+	return result, nil
+}
+
+func (h Handler) keyIDHeader() string {
+	if h.KeyIDHeader != "" {
+		return h.KeyIDHeader
+	}
+	return DefaultKeyIDHeader
+}
+
+func (h Handler) signatureHeader() string {
+	if h.SignatureHeader != "" {
+		return h.SignatureHeader
+	}
+	return DefaultSignatureHeader
+}
+
+func (h Handler) maxBodyBytes() int64 {
+	if h.MaxBodyBytes > 0 {
+		return h.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+func (h Handler) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
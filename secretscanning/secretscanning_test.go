@@ -0,0 +1,212 @@
+package secretscanning_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+	"lockbox.dev/clients/secretscanning"
+	"lockbox.dev/clients/storers/memory"
+)
+
+func TestMain(m *testing.M) {
+	err := clients.SetFingerprintKey([]byte("lockbox.dev/clients secretscanning test key"))
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+type fixture struct {
+	storer       *memory.Storer
+	handler      secretscanning.Handler
+	key          *ecdsa.PrivateKey
+	client       clients.Client
+	clientSecret string
+	secret       clients.Secret
+	secretValue  string
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %s", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	f := fixture{
+		storer: storer,
+		key:    key,
+		handler: secretscanning.Handler{
+			Storer:     storer,
+			PublicKeys: map[string]*ecdsa.PublicKey{"test-key": &key.PublicKey},
+		},
+	}
+	f.clientSecret, err = clients.SecretFormat{}.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	change, err := clients.ChangeSecret([]byte(f.clientSecret))
+	if err != nil {
+		t.Fatalf("Error hashing secret: %s", err)
+	}
+	f.client = clients.Apply(change, clients.Client{
+		ID:           "test-client",
+		Name:         "Test Client",
+		Confidential: true,
+		CreatedAt:    time.Now().Round(time.Millisecond),
+		CreatedBy:    "test",
+		CreatedByIP:  "127.0.0.1",
+	})
+	err = storer.Create(context.Background(), f.client)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err)
+	}
+	f.secretValue, err = clients.SecretFormat{}.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	f.secret, err = clients.Secret{
+		ID:          "test-secret",
+		ClientID:    f.client.ID,
+		CreatedAt:   time.Now().Round(time.Millisecond),
+		CreatedBy:   "test",
+		CreatedByIP: "127.0.0.1",
+	}.HashSecret([]byte(f.secretValue))
+	if err != nil {
+		t.Fatalf("Error hashing secret: %s", err)
+	}
+	err = storer.AddSecrets(context.Background(), []clients.Secret{f.secret})
+	if err != nil {
+		t.Fatalf("Error storing secret: %s", err)
+	}
+	return f
+}
+
+func (f fixture) request(t *testing.T, keyID string, reports []secretscanning.Report) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(reports)
+	if err != nil {
+		t.Fatalf("Error encoding reports: %s", err)
+	}
+	digest := sha256.Sum256(body)
+	sig, err := ecdsa.SignASN1(rand.Reader, f.key, digest[:])
+	if err != nil {
+		t.Fatalf("Error signing reports: %s", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set(secretscanning.DefaultKeyIDHeader, keyID)
+	r.Header.Set(secretscanning.DefaultSignatureHeader, base64.StdEncoding.EncodeToString(sig))
+	return r
+}
+
+func TestHandlerRevokesReportedSecrets(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	unknown, err := clients.SecretFormat{}.Generate()
+	if err != nil {
+		t.Fatalf("Error generating secret: %s", err)
+	}
+	reports := []secretscanning.Report{
+		{Token: f.clientSecret, Type: "lockbox_client_secret", URL: "https://example.com/1", Source: "content"},
+		{Token: f.secretValue, Type: "lockbox_client_secret", URL: "https://example.com/2", Source: "commit"},
+		{Token: unknown, Type: "lockbox_client_secret", URL: "https://example.com/3", Source: "content"},
+		{Token: "not a secret", Type: "lockbox_client_secret", URL: "https://example.com/4", Source: "content"},
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, f.request(t, "test-key", reports))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var results []secretscanning.Result
+	err = json.Unmarshal(w.Body.Bytes(), &results)
+	if err != nil {
+		t.Fatalf("Error decoding results: %s", err)
+	}
+	want := []string{
+		secretscanning.LabelTruePositive,
+		secretscanning.LabelTruePositive,
+		secretscanning.LabelFalsePositive,
+		secretscanning.LabelFalsePositive,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), results)
+	}
+	for pos, result := range results {
+		if result.Label != want[pos] {
+			t.Errorf("Expected result %d to be %q, got %q", pos, want[pos], result.Label)
+		}
+		tokenHash := sha256.Sum256([]byte(reports[pos].Token))
+		if result.TokenHash != hex.EncodeToString(tokenHash[:]) {
+			t.Errorf("Expected result %d to have the token's hash, got %q", pos, result.TokenHash)
+		}
+		if result.TokenType != reports[pos].Type {
+			t.Errorf("Expected result %d to have type %q, got %q", pos, reports[pos].Type, result.TokenType)
+		}
+	}
+
+	client, err := f.storer.Get(context.Background(), f.client.ID)
+	if err != nil {
+		t.Fatalf("Error retrieving client: %s", err)
+	}
+	if client.Status != clients.StatusSuspended {
+		t.Errorf("Expected client with leaked secret to be %q, got %q", clients.StatusSuspended, client.Status)
+	}
+	secrets, err := f.storer.ListSecrets(context.Background(), f.client.ID)
+	if err != nil {
+		t.Fatalf("Error listing secrets: %s", err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("Expected leaked secret to be removed, got %+v", secrets)
+	}
+}
+
+func TestHandlerRejectsInvalidSignatures(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	reports := []secretscanning.Report{{Token: f.clientSecret, Type: "lockbox_client_secret"}}
+
+	unknownKey := f.request(t, "other-key", reports)
+
+	badSignature := f.request(t, "test-key", reports)
+	badSignature.Header.Set(secretscanning.DefaultSignatureHeader, base64.StdEncoding.EncodeToString([]byte("not a signature")))
+
+	tampered := f.request(t, "test-key", reports)
+	tampered.Body = http.NoBody
+
+	for name, r := range map[string]*http.Request{
+		"unknownKey":   unknownKey,
+		"badSignature": badSignature,
+		"tampered":     tampered,
+	} {
+		w := httptest.NewRecorder()
+		f.handler.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected status %d, got %d", name, http.StatusUnauthorized, w.Code)
+		}
+	}
+	client, err := f.storer.Get(context.Background(), f.client.ID)
+	if err != nil {
+		t.Fatalf("Error retrieving client: %s", err)
+	}
+	if !client.IsActive() {
+		t.Errorf("Expected client to stay active, got %q", client.Status)
+	}
+}
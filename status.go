@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// StatusActive is the status of a Client that may authenticate. A
+	// Client with an empty Status is considered active.
+	StatusActive = "active"
+	// StatusDisabled is the status of a Client that has been turned off and
+	// may not authenticate until it's re-enabled.
+	StatusDisabled = "disabled"
+	// StatusSuspended is the status of a Client that has been temporarily
+	// prevented from authenticating, usually pending investigation.
+	StatusSuspended = "suspended"
+)
+
+var (
+	// ErrClientDisabled is returned when a Client that is disabled or
+	// suspended tries to authenticate.
+	ErrClientDisabled = errors.New("client is disabled")
+	// ErrInvalidStatus is returned when a Client is set to a status that
+	// isn't StatusActive, StatusDisabled, or StatusSuspended.
+	ErrInvalidStatus = errors.New("invalid client status")
+)
+
+// IsValidStatus returns true if status is one of StatusActive,
+// StatusDisabled, or StatusSuspended.
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusActive, StatusDisabled, StatusSuspended:
+		return true
+	}
+	return false
+}
+
+// IsActive returns true if the Client's Status allows it to authenticate.
+func (c Client) IsActive() bool {
+	return c.Status == "" || c.Status == StatusActive
+}
+
+// CheckStatus returns ErrClientDisabled if the Client is not allowed to
+// authenticate because of its Status, and nil otherwise. Authentication
+// helpers should call CheckStatus before accepting a Client's credentials.
+func (c Client) CheckStatus() error {
+	if !c.IsActive() {
+		return ErrClientDisabled
+	}
+	return nil
+}
+
+// ChangeStatus generates a Change that will set a Client's status, recording
+// why it was changed and the HMAC key that changed it at the passed time. If
+// status isn't valid, ErrInvalidStatus is returned.
+func ChangeStatus(status, reason, changedBy string, changedAt time.Time) (Change, error) {
+	if !IsValidStatus(status) {
+		return Change{}, ErrInvalidStatus
+	}
+	return Change{
+		Status:          &status,
+		StatusReason:    &reason,
+		StatusChangedAt: &changedAt,
+		StatusChangedBy: &changedBy,
+	}, nil
+}
@@ -2,16 +2,40 @@ package clients
 
 import (
 	"context"
+	"time"
 )
 
 // Storer is an interface for storing, retrieving, and modifying Clients and
-// the metadata surrounding them.
+// the metadata surrounding them. Deleting a Client also deletes its
+// RedirectURIs, Scopes, Secrets, and Keys, atomically. Updating or deleting
+// a Client only succeeds if the Client's Version is still the expected
+// version, and updating increments it; otherwise, ErrVersionConflict is
+// returned. Updating a Client that doesn't exist returns ErrClientNotFound.
+// Updating a Client with an empty Change only increments its Version.
+// ResetSecret updates a Client like Update and adds Secrets like AddSecrets
+// atomically, so a Client's previous secret is kept if and only if the new
+// one is stored.
 type Storer interface {
 	Create(ctx context.Context, client Client) error
 	Get(ctx context.Context, id string) (Client, error)
+	ListClients(ctx context.Context, opts ListClientsOptions) (clients []Client, nextCursor string, err error)
 	ListRedirectURIs(ctx context.Context, clientID string) ([]RedirectURI, error)
-	Update(ctx context.Context, id string, change Change) error
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, id string, version int64, change Change) error
+	ResetSecret(ctx context.Context, id string, version int64, change Change, previous []Secret) error
+	Delete(ctx context.Context, id string, version int64) error
 	AddRedirectURIs(ctx context.Context, uris []RedirectURI) error
 	RemoveRedirectURIs(ctx context.Context, ids []string) error
+	CountSecretSchemes(ctx context.Context) (map[string]int64, error)
+	ListClientsDueForRotation(ctx context.Context, rotatedBefore, expiresBefore time.Time) ([]Client, error)
+	ListScopes(ctx context.Context, clientID string) ([]Scope, error)
+	AddScopes(ctx context.Context, scopes []Scope) error
+	RemoveScopes(ctx context.Context, clientID string, ids []string) error
+	ListSecrets(ctx context.Context, clientID string) ([]Secret, error)
+	AddSecrets(ctx context.Context, secrets []Secret) error
+	RemoveSecrets(ctx context.Context, clientID string, ids []string) error
+	UseSecret(ctx context.Context, clientID, id string, usedAt time.Time) error
+	FindSecretOwner(ctx context.Context, fingerprint string) (SecretOwner, error)
+	ListKeys(ctx context.Context, clientID string) ([]Key, error)
+	AddKeys(ctx context.Context, keys []Key) error
+	RemoveKeys(ctx context.Context, clientID string, ids []string) error
 }
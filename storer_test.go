@@ -22,6 +22,8 @@ import (
 const (
 	changeSecret = 1 << iota
 	changeName
+	changeGrantTypes
+	changeStatus
 	changeVariations
 )
 
@@ -41,9 +43,18 @@ func uuidOrFail(t *testing.T) string {
 	return id
 }
 
+// testFingerprintKey is the fingerprint key set for every test; tests that
+// change it must restore it.
+var testFingerprintKey = []byte("lockbox.dev/clients test fingerprint key")
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 
+	err := clients.SetFingerprintKey(testFingerprintKey)
+	if err != nil {
+		panic(err)
+	}
+
 	// set up our test storers
 	factories = append(factories, memory.Factory{})
 	if os.Getenv(postgres.TestConnStringEnvVar) != "" {
@@ -69,6 +80,24 @@ func TestMain(m *testing.M) {
 	os.Exit(result)
 }
 
+// createClientOrFail stores a new Client in storer for tests that need one
+// to exist, and returns its ID.
+func createClientOrFail(t *testing.T, ctx context.Context, storer clients.Storer) string {
+	t.Helper()
+	client := clients.Client{
+		ID:          uuidOrFail(t),
+		Name:        "Test Client",
+		CreatedAt:   time.Now().Round(time.Millisecond),
+		CreatedBy:   "test",
+		CreatedByIP: "127.0.0.1",
+	}
+	err := storer.Create(ctx, client)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err)
+	}
+	return client.ID
+}
+
 func runTest(t *testing.T, testFunc func(*testing.T, clients.Storer, context.Context)) {
 	t.Helper()
 	for _, factory := range factories {
@@ -89,12 +118,22 @@ func TestClientCreateGetDelete(t *testing.T) {
 
 	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
 		client := clients.Client{
-			ID:           uuidOrFail(t),
-			Name:         "Test Client",
-			Confidential: true,
-			CreatedAt:    time.Now().Round(time.Millisecond),
-			CreatedBy:    "test",
-			CreatedByIP:  "127.0.0.1",
+			ID:                      uuidOrFail(t),
+			Name:                    "Test Client",
+			Confidential:            true,
+			GrantTypes:              []string{clients.GrantTypeAuthorizationCode, clients.GrantTypeRefreshToken},
+			ResponseTypes:           []string{clients.ResponseTypeCode},
+			TokenEndpointAuthMethod: clients.AuthMethodClientSecretPost,
+			ClientURI:               "https://client.example.com",
+			LogoURI:                 "https://client.example.com/logo.png",
+			PolicyURI:               "https://client.example.com/privacy",
+			TOSURI:                  "https://client.example.com/terms",
+			Contacts:                []string{"admin@client.example.com", "security@client.example.com"},
+			SoftwareID:              "test-software",
+			SoftwareVersion:         "1.0.0",
+			CreatedAt:               time.Now().Round(time.Millisecond),
+			CreatedBy:               "test",
+			CreatedByIP:             "127.0.0.1",
 		}
 		ch, err := clients.ChangeSecret([]byte("test secret"))
 		if err != nil {
@@ -112,7 +151,7 @@ func TestClientCreateGetDelete(t *testing.T) {
 		if diff := cmp.Diff(client, res); diff != "" {
 			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
 		}
-		err = storer.Delete(ctx, client.ID)
+		err = storer.Delete(ctx, client.ID, client.Version)
 		if err != nil {
 			t.Errorf("Error deleting client: %s", err)
 		}
@@ -179,13 +218,36 @@ func TestClientUpdateOneOfMany(t *testing.T) {
 					}
 					change.SecretHash = secretChange.SecretHash
 					change.SecretScheme = secretChange.SecretScheme
+					change.SecretPepperID = secretChange.SecretPepperID
+					rotatedAt := time.Now().Round(time.Millisecond)
+					expiresAt := rotatedAt.Add(time.Hour)
+					change.SecretRotatedAt = &rotatedAt
+					change.SecretExpiresAt = &expiresAt
 				}
 				if variation&changeName != 0 {
 					name := fmt.Sprintf("Updated Test Client %d", variation)
 					change.Name = &name
 				}
+				if variation&changeStatus != 0 {
+					var statusChange clients.Change
+					statusChange, err = clients.ChangeStatus(clients.StatusSuspended, fmt.Sprintf("Test suspension %d", variation), "test", time.Now().Round(time.Millisecond))
+					if err != nil {
+						t.Fatalf("Error generating status change: %s", err)
+					}
+					change.Status = statusChange.Status
+					change.StatusReason = statusChange.StatusReason
+					change.StatusChangedAt = statusChange.StatusChangedAt
+					change.StatusChangedBy = statusChange.StatusChangedBy
+				}
+				if variation&changeGrantTypes != 0 {
+					grantTypes := []string{clients.GrantTypeClientCredentials}
+					var responseTypes []string
+					change.GrantTypes = &grantTypes
+					change.ResponseTypes = &responseTypes
+				}
 				expectation := clients.Apply(change, client)
-				err = storer.Update(ctx, client.ID, change)
+				expectation.Version++
+				err = storer.Update(ctx, client.ID, client.Version, change)
 				if err != nil {
 					t.Errorf("Unexpected error updating client: %v", err)
 				}
@@ -210,10 +272,185 @@ func TestClientUpdateOneOfMany(t *testing.T) {
 	})
 }
 
+func TestClientUpdateVersionConflict(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		clientID := createClientOrFail(t, ctx, storer)
+		client, err := storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		first, second := "First Update", "Second Update"
+		err = storer.Update(ctx, clientID, client.Version, clients.Change{Name: &first})
+		if err != nil {
+			t.Fatalf("Unexpected error updating client: %s", err)
+		}
+		// the second update is based on the same version as the first
+		err = storer.Update(ctx, clientID, client.Version, clients.Change{Name: &second})
+		if !errors.Is(err, clients.ErrVersionConflict) {
+			t.Errorf("Expected %v, got %v", clients.ErrVersionConflict, err)
+		}
+		result, err := storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if result.Name != first {
+			t.Errorf("Expected name %q, got %q", first, result.Name)
+		}
+		if result.Version != client.Version+1 {
+			t.Errorf("Expected version %d, got %d", client.Version+1, result.Version)
+		}
+		err = storer.Update(ctx, clientID, result.Version, clients.Change{Name: &second})
+		if err != nil {
+			t.Fatalf("Unexpected error updating client with current version: %s", err)
+		}
+		result, err = storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if result.Name != second || result.Version != client.Version+2 {
+			t.Errorf("Expected name %q at version %d, got %q at version %d", second, client.Version+2, result.Name, result.Version)
+		}
+	})
+}
+
+func TestClientDeleteVersionConflict(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		clientID := createClientOrFail(t, ctx, storer)
+		client, err := storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		err = storer.AddScopes(ctx, []clients.Scope{{
+			ID:          "https://scopes.example.com/read",
+			ClientID:    clientID,
+			CreatedAt:   time.Now().Round(time.Millisecond),
+			CreatedBy:   "test",
+			CreatedByIP: "127.0.0.1",
+		}})
+		if err != nil {
+			t.Fatalf("Error storing scope: %s", err)
+		}
+		name := "Updated Name"
+		err = storer.Update(ctx, clientID, client.Version, clients.Change{Name: &name})
+		if err != nil {
+			t.Fatalf("Unexpected error updating client: %s", err)
+		}
+		// the delete is based on the version from before the update
+		err = storer.Delete(ctx, clientID, client.Version)
+		if !errors.Is(err, clients.ErrVersionConflict) {
+			t.Errorf("Expected %v, got %v", clients.ErrVersionConflict, err)
+		}
+		_, err = storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Expected client to survive a conflicting delete, got %v", err)
+		}
+		scopes, err := storer.ListScopes(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error listing scopes: %s", err)
+		}
+		if len(scopes) != 1 {
+			t.Errorf("Expected scope to survive a conflicting delete, got %+v", scopes)
+		}
+		err = storer.Delete(ctx, clientID, client.Version+1)
+		if err != nil {
+			t.Fatalf("Unexpected error deleting client with current version: %s", err)
+		}
+		_, err = storer.Get(ctx, clientID)
+		if !errors.Is(err, clients.ErrClientNotFound) {
+			t.Errorf("Expected %v, got %v", clients.ErrClientNotFound, err)
+		}
+	})
+}
+
+func TestClientResetSecret(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		clientID := createClientOrFail(t, ctx, storer)
+		client, err := storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		newSecret := func() clients.Secret {
+			secret, err := clients.Secret{
+				ID:          uuidOrFail(t),
+				ClientID:    clientID,
+				CreatedAt:   time.Now().Round(time.Millisecond),
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}.HashSecret([]byte("previous secret"))
+			if err != nil {
+				t.Fatalf("Error hashing secret: %s", err)
+			}
+			return secret
+		}
+		existing := newSecret()
+		err = storer.AddSecrets(ctx, []clients.Secret{existing})
+		if err != nil {
+			t.Fatalf("Error storing secret: %s", err)
+		}
+		name := "Reset Client"
+		change := clients.Change{Name: &name}
+
+		err = storer.ResetSecret(ctx, uuidOrFail(t), 0, change, nil)
+		if !errors.Is(err, clients.ErrClientNotFound) {
+			t.Errorf("Expected %v for a missing client, got %v", clients.ErrClientNotFound, err)
+		}
+		err = storer.ResetSecret(ctx, clientID, client.Version+1, change, []clients.Secret{newSecret()})
+		if !errors.Is(err, clients.ErrVersionConflict) {
+			t.Errorf("Expected %v for a stale version, got %v", clients.ErrVersionConflict, err)
+		}
+		// a secret that can't be stored stops the change being stored too
+		err = storer.ResetSecret(ctx, clientID, client.Version, change, []clients.Secret{existing})
+		if !errors.Is(err, clients.ErrSecretAlreadyExists) {
+			t.Errorf("Expected %v for a duplicate secret, got %v", clients.ErrSecretAlreadyExists, err)
+		}
+		stored, err := storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.Name != client.Name || stored.Version != client.Version {
+			t.Errorf("Expected failed resets to store nothing, got name %q at version %d", stored.Name, stored.Version)
+		}
+		secrets, err := storer.ListSecrets(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error listing secrets: %s", err)
+		}
+		if len(secrets) != 1 {
+			t.Errorf("Expected failed resets to store no secrets, got %+v", secrets)
+		}
+
+		previous := newSecret()
+		err = storer.ResetSecret(ctx, clientID, client.Version, change, []clients.Secret{previous})
+		if err != nil {
+			t.Fatalf("Error resetting secret: %s", err)
+		}
+		stored, err = storer.Get(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error retrieving client: %s", err)
+		}
+		if stored.Name != name || stored.Version != client.Version+1 {
+			t.Errorf("Expected name %q at version %d, got %q at version %d", name, client.Version+1, stored.Name, stored.Version)
+		}
+		secrets, err = storer.ListSecrets(ctx, clientID)
+		if err != nil {
+			t.Fatalf("Error listing secrets: %s", err)
+		}
+		if len(secrets) != 2 {
+			t.Errorf("Expected previous secret to be stored, got %+v", secrets)
+		}
+	})
+}
+
 func TestClientUpdateNoChange(t *testing.T) {
 	t.Parallel()
 
-	// updating an account with an empty change should not error
+	// updating an account with an empty change should not error, but should
+	// increment its version
 	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
 		client := clients.Client{
 			ID:           uuidOrFail(t),
@@ -233,10 +470,23 @@ func TestClientUpdateNoChange(t *testing.T) {
 			t.Errorf("Error creating client: %s", err)
 		}
 		var change clients.Change
-		err = storer.Update(ctx, client.ID, change)
+		err = storer.Update(ctx, client.ID, client.Version, change)
 		if err != nil {
 			t.Fatalf("Unexpected error updating client: %+v\n", err)
 		}
+
+		// an empty change still increments the version
+		result, err := storer.Get(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error retrieving client: %+v\n", err)
+		}
+		if result.Version != client.Version+1 {
+			t.Errorf("Expected version %d, got %d", client.Version+1, result.Version)
+		}
+		err = storer.Update(ctx, client.ID, client.Version, change)
+		if !errors.Is(err, clients.ErrVersionConflict) {
+			t.Errorf("Expected %v updating with a stale version, got %v", clients.ErrVersionConflict, err)
+		}
 	})
 }
 
@@ -287,9 +537,9 @@ func TestClientUpdateNonexistent(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error generating client secret: %s", err)
 		}
-		err = storer.Update(ctx, uuidOrFail(t), ch)
-		if err != nil {
-			t.Fatalf("Expected %v, got %v instead", nil, err)
+		err = storer.Update(ctx, uuidOrFail(t), 0, ch)
+		if !errors.Is(err, clients.ErrClientNotFound) {
+			t.Fatalf("Expected %v, got %v instead", clients.ErrClientNotFound, err)
 		}
 	})
 }
@@ -298,13 +548,163 @@ func TestClientDeleteNonexistent(t *testing.T) {
 	t.Parallel()
 
 	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
-		err := storer.Delete(ctx, uuidOrFail(t))
+		err := storer.Delete(ctx, uuidOrFail(t), 0)
 		if err != nil {
 			t.Fatalf("Expected %v, got %v instead", nil, err)
 		}
 	})
 }
 
+func TestClientDeleteRemovesDependents(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		clientID := createClientOrFail(t, ctx, storer)
+		otherID := createClientOrFail(t, ctx, storer)
+		createdAt := time.Now().Round(time.Millisecond)
+		for _, id := range []string{clientID, otherID} {
+			err := storer.AddRedirectURIs(ctx, []clients.RedirectURI{{
+				ID:          uuidOrFail(t),
+				URI:         "https://" + id + ".example.com/callback",
+				ClientID:    id,
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if err != nil {
+				t.Fatalf("Error storing redirect URI: %s", err)
+			}
+			err = storer.AddScopes(ctx, []clients.Scope{{
+				ID:          "https://scopes.example.com/read",
+				ClientID:    id,
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if err != nil {
+				t.Fatalf("Error storing scope: %s", err)
+			}
+			err = storer.AddSecrets(ctx, []clients.Secret{{
+				ID:          uuidOrFail(t),
+				ClientID:    id,
+				Hash:        "hash",
+				Scheme:      "test",
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if err != nil {
+				t.Fatalf("Error storing secret: %s", err)
+			}
+			err = storer.AddKeys(ctx, []clients.Key{{
+				ID:          "test-key",
+				ClientID:    id,
+				KeyType:     clients.KeyTypeEC,
+				Algorithm:   "ES256",
+				JWK:         `{"kty":"EC","kid":"test-key"}`,
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if err != nil {
+				t.Fatalf("Error storing key: %s", err)
+			}
+		}
+
+		err := storer.Delete(ctx, clientID, 0)
+		if err != nil {
+			t.Fatalf("Error deleting client: %s", err)
+		}
+		_, err = storer.Get(ctx, clientID)
+		if !errors.Is(err, clients.ErrClientNotFound) {
+			t.Errorf("Expected %v, got %v", clients.ErrClientNotFound, err)
+		}
+
+		for id, expected := range map[string]int{clientID: 0, otherID: 1} {
+			uris, err := storer.ListRedirectURIs(ctx, id)
+			if err != nil {
+				t.Fatalf("Error listing redirect URIs: %s", err)
+			}
+			scopes, err := storer.ListScopes(ctx, id)
+			if err != nil {
+				t.Fatalf("Error listing scopes: %s", err)
+			}
+			secrets, err := storer.ListSecrets(ctx, id)
+			if err != nil {
+				t.Fatalf("Error listing secrets: %s", err)
+			}
+			keys, err := storer.ListKeys(ctx, id)
+			if err != nil {
+				t.Fatalf("Error listing keys: %s", err)
+			}
+			if len(uris) != expected || len(scopes) != expected || len(secrets) != expected || len(keys) != expected {
+				t.Errorf("Expected %d of each for client %s, got %d redirect URIs, %d scopes, %d secrets, and %d keys", expected, id, len(uris), len(scopes), len(secrets), len(keys))
+			}
+		}
+	})
+}
+
+func TestAddDependentsMissingClient(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		deletedID := createClientOrFail(t, ctx, storer)
+		err := storer.Delete(ctx, deletedID, 0)
+		if err != nil {
+			t.Fatalf("Error deleting client: %s", err)
+		}
+		createdAt := time.Now().Round(time.Millisecond)
+		for _, id := range []string{uuidOrFail(t), deletedID} {
+			err := storer.AddRedirectURIs(ctx, []clients.RedirectURI{{
+				ID:          uuidOrFail(t),
+				URI:         "https://" + id + ".example.com/callback",
+				ClientID:    id,
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if !errors.Is(err, clients.ErrClientNotFound) {
+				t.Errorf("Expected %v adding a redirect URI to %s, got %v", clients.ErrClientNotFound, id, err)
+			}
+			err = storer.AddScopes(ctx, []clients.Scope{{
+				ID:          "https://scopes.example.com/read",
+				ClientID:    id,
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if !errors.Is(err, clients.ErrClientNotFound) {
+				t.Errorf("Expected %v adding a scope to %s, got %v", clients.ErrClientNotFound, id, err)
+			}
+			err = storer.AddSecrets(ctx, []clients.Secret{{
+				ID:          uuidOrFail(t),
+				ClientID:    id,
+				Hash:        "hash",
+				Scheme:      "test",
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if !errors.Is(err, clients.ErrClientNotFound) {
+				t.Errorf("Expected %v adding a secret to %s, got %v", clients.ErrClientNotFound, id, err)
+			}
+			err = storer.AddKeys(ctx, []clients.Key{{
+				ID:          "test-key",
+				ClientID:    id,
+				KeyType:     clients.KeyTypeEC,
+				Algorithm:   "ES256",
+				JWK:         `{"kty":"EC","kid":"test-key"}`,
+				CreatedAt:   createdAt,
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}})
+			if !errors.Is(err, clients.ErrClientNotFound) {
+				t.Errorf("Expected %v adding a key to %s, got %v", clients.ErrClientNotFound, id, err)
+			}
+		}
+	})
+}
+
 func TestRedirectURIsCreateListDelete(t *testing.T) {
 	t.Parallel()
 
@@ -562,3 +962,688 @@ func TestRedirectURIDeleteNonexistent(t *testing.T) {
 		}
 	})
 }
+
+func TestClientCountSecretSchemes(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		schemes := []string{
+			clients.SecretSchemeSHA256,
+			clients.SecretSchemeBcrypt,
+			clients.SecretSchemeSHA256,
+		}
+		for _, scheme := range schemes {
+			client := clients.Client{
+				ID:           uuidOrFail(t),
+				Name:         "Test Client",
+				Confidential: true,
+				CreatedAt:    time.Now().Round(time.Millisecond),
+				CreatedBy:    "test",
+				CreatedByIP:  "127.0.0.1",
+			}
+			ch, err := clients.ChangeSecretWithScheme(scheme, []byte("test secret"))
+			if err != nil {
+				t.Fatalf("Error generating client secret: %s", err)
+			}
+			client = clients.Apply(ch, client)
+			err = storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+		}
+		counts, err := storer.CountSecretSchemes(ctx)
+		if err != nil {
+			t.Fatalf("Error counting secret schemes: %s", err)
+		}
+		expected := map[string]int64{
+			clients.SecretSchemeSHA256: 2,
+			clients.SecretSchemeBcrypt: 1,
+		}
+		if diff := cmp.Diff(expected, counts); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+	})
+}
+
+func TestClientsListFilters(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		creator := uuidOrFail(t)
+		start := time.Now().Round(time.Millisecond)
+		fixtures := []clients.Client{
+			{Name: "Alpha Web", Confidential: true, CreatedBy: creator},
+			{Name: "Alpha Mobile", Confidential: false, CreatedBy: creator},
+			{Name: "Beta Web", Confidential: true, CreatedBy: creator},
+			{Name: "Alpha_Other", Confidential: true, CreatedBy: creator},
+			{Name: "Alpha Web", Confidential: true, CreatedBy: "someone-else"},
+		}
+		for pos := range fixtures {
+			fixtures[pos].ID = uuidOrFail(t)
+			fixtures[pos].CreatedAt = start.Add(time.Duration(pos) * time.Minute)
+			fixtures[pos].CreatedByIP = "127.0.0.1"
+			err := storer.Create(ctx, fixtures[pos])
+			if err != nil {
+				t.Fatalf("Error creating client %+v: %s", fixtures[pos], err)
+			}
+		}
+		confidential, public := true, false
+		cases := map[string]struct {
+			opts     clients.ListClientsOptions
+			expected []clients.Client
+		}{
+			"createdBy": {
+				opts:     clients.ListClientsOptions{CreatedBy: creator},
+				expected: fixtures[:4],
+			},
+			"confidential": {
+				opts:     clients.ListClientsOptions{CreatedBy: creator, Confidential: &confidential},
+				expected: []clients.Client{fixtures[0], fixtures[2], fixtures[3]},
+			},
+			"public": {
+				opts:     clients.ListClientsOptions{CreatedBy: creator, Confidential: &public},
+				expected: []clients.Client{fixtures[1]},
+			},
+			"namePrefix": {
+				opts:     clients.ListClientsOptions{CreatedBy: creator, NamePrefix: "Alpha "},
+				expected: []clients.Client{fixtures[0], fixtures[1]},
+			},
+			"namePrefixWildcards": {
+				opts:     clients.ListClientsOptions{CreatedBy: creator, NamePrefix: "Alpha_"},
+				expected: []clients.Client{fixtures[3]},
+			},
+			"createdRange": {
+				opts: clients.ListClientsOptions{
+					CreatedBy:     creator,
+					CreatedSince:  fixtures[1].CreatedAt,
+					CreatedBefore: fixtures[3].CreatedAt,
+				},
+				expected: []clients.Client{fixtures[1], fixtures[2]},
+			},
+			"noMatches": {
+				opts: clients.ListClientsOptions{CreatedBy: creator, NamePrefix: "Gamma"},
+			},
+		}
+		for name, tc := range cases {
+			name, tc := name, tc
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				results, cursor, err := storer.ListClients(ctx, tc.opts)
+				if err != nil {
+					t.Fatalf("Error listing clients: %s", err)
+				}
+				if cursor != "" {
+					t.Errorf("Expected no cursor, got %q", cursor)
+				}
+				if diff := cmp.Diff(tc.expected, results); diff != "" {
+					t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestClientsListPagination(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		creator := uuidOrFail(t)
+		createdAt := time.Now().Round(time.Millisecond)
+		var expected []clients.Client
+		for i := 0; i < 7; i++ {
+			client := clients.Client{
+				ID:          uuidOrFail(t),
+				Name:        fmt.Sprintf("Test Client %d", i),
+				CreatedAt:   createdAt,
+				CreatedBy:   creator,
+				CreatedByIP: "127.0.0.1",
+			}
+			// some clients share a creation time, so the ID breaks ties
+			if i%3 == 0 {
+				createdAt = createdAt.Add(time.Second)
+			}
+			err := storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client %+v: %s", client, err)
+			}
+			expected = append(expected, client)
+		}
+		clients.ClientsByCreatedAt(expected)
+
+		var results []clients.Client
+		var cursor string
+		var pages int
+		for {
+			page, next, err := storer.ListClients(ctx, clients.ListClientsOptions{
+				CreatedBy: creator,
+				Limit:     3,
+				Cursor:    cursor,
+			})
+			if err != nil {
+				t.Fatalf("Error listing clients: %s", err)
+			}
+			if len(page) > 3 {
+				t.Errorf("Expected at most 3 clients in page, got %d", len(page))
+			}
+			pages++
+			results = append(results, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+			if pages > len(expected) {
+				t.Fatalf("Too many pages returned, cursor %q", cursor)
+			}
+		}
+		if pages != 3 {
+			t.Errorf("Expected 3 pages, got %d", pages)
+		}
+		if diff := cmp.Diff(expected, results); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+	})
+}
+
+func TestClientsListInvalidCursor(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		for _, cursor := range []string{"not a cursor", "bm90IGEgY3Vyc29y"} {
+			_, _, err := storer.ListClients(ctx, clients.ListClientsOptions{Cursor: cursor})
+			if !errors.Is(err, clients.ErrInvalidCursor) {
+				t.Errorf("Expected %v for cursor %q, got %v", clients.ErrInvalidCursor, cursor, err)
+			}
+		}
+	})
+}
+
+func TestClientCountSecretSchemesSkipsPublicClients(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		// public clients have no secret, so there's nothing to migrate
+		createClientOrFail(t, ctx, storer)
+		ch, err := clients.ChangeSecretWithScheme(clients.SecretSchemeSHA256, []byte("stray secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		// neither do clients that don't use a secret, even if a hash was
+		// stored for them
+		for _, client := range []clients.Client{
+			{Name: "Hashed Public Client"},
+			{Name: "Hashed Key Client", Confidential: true, TokenEndpointAuthMethod: clients.AuthMethodPrivateKeyJWT},
+		} {
+			client.ID = uuidOrFail(t)
+			client.CreatedAt = time.Now().Round(time.Millisecond)
+			client.CreatedBy = "test"
+			client.CreatedByIP = "127.0.0.1"
+			err = storer.Create(ctx, clients.Apply(ch, client))
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+		}
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		ch, err = clients.ChangeSecretWithScheme(clients.SecretSchemeBcrypt, []byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		err = storer.Create(ctx, clients.Apply(ch, client))
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		counts, err := storer.CountSecretSchemes(ctx)
+		if err != nil {
+			t.Fatalf("Error counting secret schemes: %s", err)
+		}
+		expected := map[string]int64{clients.SecretSchemeBcrypt: 1}
+		if diff := cmp.Diff(expected, counts); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+		report := clients.SecretSchemeReport{DefaultScheme: clients.SecretSchemeBcrypt, Counts: counts}
+		if remaining := report.Remaining(); remaining != 0 {
+			t.Errorf("Expected no secrets remaining, got %d", remaining)
+		}
+	})
+}
+
+func TestScopesCreateListDelete(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		ch, err := clients.ChangeSecret([]byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		client = clients.Apply(ch, client)
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+
+		scopes := []clients.Scope{}
+		// add scopes in 4 separate groups, with 1, 2, 3, and 4 scopes in each group
+		// this checks that listing scopes when they're added over time works
+		for group := 1; group < 5; group++ {
+			newScopes := []clients.Scope{}
+			for scope := 0; scope < group; scope++ {
+				newScopes = append(newScopes, clients.Scope{
+					ID:          fmt.Sprintf("https://scopes.impractical.services/test-%d-%d", group, scope),
+					ClientID:    client.ID,
+					IsDefault:   (group+scope)%2 == 0,
+					CreatedAt:   time.Now().Round(time.Millisecond),
+					CreatedBy:   "test",
+					CreatedByIP: "127.0.0.1",
+				})
+			}
+			err = storer.AddScopes(ctx, newScopes)
+			if err != nil {
+				t.Errorf("Error storing scopes: %s", err)
+			}
+			scopes = append(scopes, newScopes...)
+
+			var res []clients.Scope
+			res, err = storer.ListScopes(ctx, client.ID)
+			if err != nil {
+				t.Errorf("Error retrieving scopes: %s", err)
+			}
+			clients.ScopesByID(scopes)
+			if diff := cmp.Diff(scopes, res); diff != "" {
+				t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+			}
+		}
+		err = storer.RemoveScopes(ctx, client.ID, []string{scopes[0].ID})
+		if err != nil {
+			t.Errorf("Error removing scopes: %s", err)
+		}
+		res, err := storer.ListScopes(ctx, client.ID)
+		if err != nil {
+			t.Errorf("Error retrieving scopes: %s", err)
+		}
+		if diff := cmp.Diff(scopes[1:], res); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+		ids := make([]string, 0, len(scopes[1:]))
+		for _, scope := range scopes[1:] {
+			ids = append(ids, scope.ID)
+		}
+		err = storer.RemoveScopes(ctx, client.ID, ids)
+		if err != nil {
+			t.Errorf("Error removing scopes: %v", err)
+		}
+		res, err = storer.ListScopes(ctx, client.ID)
+		if err != nil {
+			t.Errorf("Error retrieving scopes: %s", err)
+		}
+		if len(res) != 0 {
+			t.Errorf("Expected no results, got %v", res)
+		}
+	})
+}
+
+func TestScopesSameIDDifferentClients(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		var scopes []clients.Scope
+		for i := 0; i < 2; i++ {
+			client := clients.Client{
+				ID:           uuidOrFail(t),
+				Name:         "Test Client",
+				Confidential: true,
+				CreatedAt:    time.Now().Round(time.Millisecond),
+				CreatedBy:    "test",
+				CreatedByIP:  "127.0.0.1",
+			}
+			err := storer.Create(ctx, client)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err)
+			}
+			scope := clients.Scope{
+				ID:          "https://scopes.impractical.services/shared",
+				ClientID:    client.ID,
+				IsDefault:   i == 0,
+				CreatedAt:   time.Now().Round(time.Millisecond),
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			}
+			err = storer.AddScopes(ctx, []clients.Scope{scope})
+			if err != nil {
+				t.Fatalf("Error adding scope: %s", err)
+			}
+			scopes = append(scopes, scope)
+		}
+		err := storer.RemoveScopes(ctx, scopes[0].ClientID, []string{scopes[0].ID})
+		if err != nil {
+			t.Fatalf("Error removing scope: %s", err)
+		}
+		res, err := storer.ListScopes(ctx, scopes[1].ClientID)
+		if err != nil {
+			t.Fatalf("Error retrieving scopes: %s", err)
+		}
+		if diff := cmp.Diff(scopes[1:], res); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+	})
+}
+
+func TestScopesListNone(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		res, err := storer.ListScopes(ctx, uuidOrFail(t))
+		if err != nil {
+			t.Errorf("Error retrieving scopes: %s", err)
+		}
+		if len(res) != 0 {
+			t.Errorf("Expected no results, got %v", res)
+		}
+	})
+}
+
+func TestScopeAlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		err := storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+		scope := clients.Scope{
+			ID:          "https://scopes.impractical.services/test",
+			ClientID:    client.ID,
+			CreatedAt:   time.Now().Round(time.Millisecond),
+			CreatedBy:   "test",
+			CreatedByIP: "127.0.0.1",
+		}
+		err = storer.AddScopes(ctx, []clients.Scope{scope})
+		if err != nil {
+			t.Fatalf("Error adding scope: %s", err)
+		}
+		scope2 := scope
+		scope2.ID = "https://scopes.impractical.services/test-2"
+		scope.IsDefault = true
+		err = storer.AddScopes(ctx, []clients.Scope{scope2, scope})
+		var scopeErr clients.ScopeAlreadyExistsError
+		if ok := errors.As(err, &scopeErr); !ok {
+			t.Errorf("Expected %T, got %v", clients.ScopeAlreadyExistsError{}, err)
+		} else if scope.ID != scopeErr.ID || client.ID != scopeErr.ClientID {
+			t.Errorf("Expected ScopeAlreadyExistsError to be for %s/%s, was for %s/%s", client.ID, scope.ID, scopeErr.ClientID, scopeErr.ID)
+		}
+		res, err := storer.ListScopes(ctx, client.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving scopes: %s", err)
+		}
+		if len(res) != 1 {
+			t.Errorf("Expected failed insert to add no scopes, got %v", res)
+		}
+	})
+}
+
+func TestScopeDeleteNonexistent(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		err := storer.RemoveScopes(ctx, uuidOrFail(t), []string{"https://scopes.impractical.services/test"})
+		if err != nil {
+			t.Fatalf("Expected %v, got %v instead", nil, err)
+		}
+	})
+}
+
+func TestSecretsCreateListUseDelete(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		clientID := createClientOrFail(t, ctx, storer)
+		createdAt := time.Now().Round(time.Millisecond)
+		secrets := []clients.Secret{
+			{
+				ID:          uuidOrFail(t),
+				ClientID:    clientID,
+				Hash:        "hash-1",
+				Scheme:      "test",
+				CreatedAt:   createdAt.Add(-time.Hour),
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			},
+			{
+				ID:          uuidOrFail(t),
+				ClientID:    clientID,
+				Hash:        "hash-2",
+				Scheme:      "test",
+				CreatedAt:   createdAt,
+				ExpiresAt:   createdAt.Add(time.Hour),
+				CreatedBy:   "test",
+				CreatedByIP: "127.0.0.1",
+			},
+		}
+		// add them newest first, to make sure they're listed oldest first
+		err := storer.AddSecrets(ctx, []clients.Secret{secrets[1], secrets[0]})
+		if err != nil {
+			t.Fatalf("Error storing secrets: %s", err)
+		}
+		res, err := storer.ListSecrets(ctx, clientID)
+		if err != nil {
+			t.Errorf("Error retrieving secrets: %s", err)
+		}
+		if diff := cmp.Diff(secrets, res); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+
+		secrets[1].LastUsedAt = createdAt.Add(time.Minute)
+		err = storer.UseSecret(ctx, clientID, secrets[1].ID, secrets[1].LastUsedAt)
+		if err != nil {
+			t.Errorf("Error using secret: %s", err)
+		}
+		res, err = storer.ListSecrets(ctx, clientID)
+		if err != nil {
+			t.Errorf("Error retrieving secrets: %s", err)
+		}
+		if diff := cmp.Diff(secrets, res); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+
+		// removing a secret from the wrong client should do nothing
+		err = storer.RemoveSecrets(ctx, uuidOrFail(t), []string{secrets[0].ID})
+		if err != nil {
+			t.Errorf("Error removing secrets: %s", err)
+		}
+		err = storer.RemoveSecrets(ctx, clientID, []string{secrets[1].ID})
+		if err != nil {
+			t.Errorf("Error removing secrets: %s", err)
+		}
+		res, err = storer.ListSecrets(ctx, clientID)
+		if err != nil {
+			t.Errorf("Error retrieving secrets: %s", err)
+		}
+		if diff := cmp.Diff(secrets[:1], res); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+	})
+}
+
+func TestSecretsListNone(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		res, err := storer.ListSecrets(ctx, uuidOrFail(t))
+		if err != nil {
+			t.Errorf("Error retrieving secrets: %s", err)
+		}
+		if len(res) != 0 {
+			t.Errorf("Expected no secrets, got %+v", res)
+		}
+	})
+}
+
+func TestSecretAlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		secret := clients.Secret{
+			ID:          uuidOrFail(t),
+			ClientID:    createClientOrFail(t, ctx, storer),
+			Hash:        "hash",
+			Scheme:      "test",
+			CreatedAt:   time.Now().Round(time.Millisecond),
+			CreatedBy:   "test",
+			CreatedByIP: "127.0.0.1",
+		}
+		err := storer.AddSecrets(ctx, []clients.Secret{secret})
+		if err != nil {
+			t.Fatalf("Error storing secret: %s", err)
+		}
+		err = storer.AddSecrets(ctx, []clients.Secret{secret})
+		if !errors.Is(err, clients.ErrSecretAlreadyExists) {
+			t.Errorf("Expected %v, got %v", clients.ErrSecretAlreadyExists, err)
+		}
+	})
+}
+
+func TestSecretUseNonexistent(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		err := storer.UseSecret(ctx, uuidOrFail(t), uuidOrFail(t), time.Now())
+		if !errors.Is(err, clients.ErrSecretNotFound) {
+			t.Errorf("Expected %v, got %v", clients.ErrSecretNotFound, err)
+		}
+	})
+}
+
+func TestSecretDeleteNonexistent(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		err := storer.RemoveSecrets(ctx, uuidOrFail(t), []string{uuidOrFail(t)})
+		if err != nil {
+			t.Errorf("Unexpected error removing secrets: %s", err)
+		}
+	})
+}
+
+func TestKeysCreateListDelete(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		clientID := createClientOrFail(t, ctx, storer)
+		keys := []clients.Key{}
+		// add keys in 3 separate groups, with 1, 2, and 3 keys in each group
+		// this checks that listing keys when they're added over time works
+		for group := 1; group < 4; group++ {
+			newKeys := []clients.Key{}
+			for key := 0; key < group; key++ {
+				kid := fmt.Sprintf("test-%d-%d", group, key)
+				newKeys = append(newKeys, clients.Key{
+					ID:          kid,
+					ClientID:    clientID,
+					KeyType:     clients.KeyTypeEC,
+					Algorithm:   "ES256",
+					JWK:         fmt.Sprintf(`{"kty":"EC","kid":%q}`, kid),
+					CreatedAt:   time.Now().Round(time.Millisecond),
+					CreatedBy:   "test",
+					CreatedByIP: "127.0.0.1",
+				})
+			}
+			err := storer.AddKeys(ctx, newKeys)
+			if err != nil {
+				t.Errorf("Error storing keys: %s", err)
+			}
+			keys = append(keys, newKeys...)
+
+			res, err := storer.ListKeys(ctx, clientID)
+			if err != nil {
+				t.Errorf("Error retrieving keys: %s", err)
+			}
+			clients.KeysByID(keys)
+			if diff := cmp.Diff(keys, res); diff != "" {
+				t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+			}
+		}
+		err := storer.RemoveKeys(ctx, clientID, []string{keys[0].ID, keys[1].ID})
+		if err != nil {
+			t.Errorf("Error removing keys: %s", err)
+		}
+		res, err := storer.ListKeys(ctx, clientID)
+		if err != nil {
+			t.Errorf("Error retrieving keys: %s", err)
+		}
+		if diff := cmp.Diff(keys[2:], res); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+	})
+}
+
+func TestKeysListNone(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		res, err := storer.ListKeys(ctx, uuidOrFail(t))
+		if err != nil {
+			t.Errorf("Error retrieving keys: %s", err)
+		}
+		if len(res) != 0 {
+			t.Errorf("Expected no keys, got %+v", res)
+		}
+	})
+}
+
+func TestKeyAlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		key := clients.Key{
+			ID:          "test-key",
+			ClientID:    createClientOrFail(t, ctx, storer),
+			KeyType:     clients.KeyTypeEC,
+			Algorithm:   "ES256",
+			JWK:         `{"kty":"EC","kid":"test-key"}`,
+			CreatedAt:   time.Now().Round(time.Millisecond),
+			CreatedBy:   "test",
+			CreatedByIP: "127.0.0.1",
+		}
+		err := storer.AddKeys(ctx, []clients.Key{key})
+		if err != nil {
+			t.Fatalf("Error storing key: %s", err)
+		}
+		err = storer.AddKeys(ctx, []clients.Key{key})
+		var keyErr clients.KeyAlreadyExistsError
+		if !errors.As(err, &keyErr) {
+			t.Fatalf("Expected a KeyAlreadyExistsError, got %v", err)
+		}
+		if keyErr.ID != key.ID || keyErr.ClientID != key.ClientID {
+			t.Errorf("Expected error for key %q on client %q, got %q on %q", key.ID, key.ClientID, keyErr.ID, keyErr.ClientID)
+		}
+
+		// the same key ID can be used by another client
+		key.ClientID = createClientOrFail(t, ctx, storer)
+		err = storer.AddKeys(ctx, []clients.Key{key})
+		if err != nil {
+			t.Errorf("Unexpected error storing key for another client: %s", err)
+		}
+	})
+}
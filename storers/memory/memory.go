@@ -3,6 +3,8 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	memdb "github.com/hashicorp/go-memdb"
 
@@ -20,6 +22,11 @@ var (
 						Unique:  true,
 						Indexer: &memdb.StringFieldIndex{Field: "ID", Lowercase: true},
 					},
+					"secret_fingerprint": {
+						Name:         "secret_fingerprint",
+						AllowMissing: true,
+						Indexer:      &memdb.StringFieldIndex{Field: "SecretFingerprint"},
+					},
 				},
 			},
 			"redirect_uri": {
@@ -41,6 +48,63 @@ var (
 					},
 				},
 			},
+			"scope": {
+				Name: "scope",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:   "id",
+						Unique: true,
+						Indexer: &memdb.CompoundIndex{
+							Indexes: []memdb.Indexer{
+								&memdb.StringFieldIndex{Field: "ClientID"},
+								&memdb.StringFieldIndex{Field: "ID"},
+							},
+						},
+					},
+					"client_id": {
+						Name:    "client_id",
+						Indexer: &memdb.StringFieldIndex{Field: "ClientID"},
+					},
+				},
+			},
+			"key": {
+				Name: "key",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:   "id",
+						Unique: true,
+						Indexer: &memdb.CompoundIndex{
+							Indexes: []memdb.Indexer{
+								&memdb.StringFieldIndex{Field: "ClientID"},
+								&memdb.StringFieldIndex{Field: "ID"},
+							},
+						},
+					},
+					"client_id": {
+						Name:    "client_id",
+						Indexer: &memdb.StringFieldIndex{Field: "ClientID"},
+					},
+				},
+			},
+			"secret": {
+				Name: "secret",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "ID", Lowercase: true},
+					},
+					"client_id": {
+						Name:    "client_id",
+						Indexer: &memdb.StringFieldIndex{Field: "ClientID"},
+					},
+					"fingerprint": {
+						Name:         "fingerprint",
+						AllowMissing: true,
+						Indexer:      &memdb.StringFieldIndex{Field: "Fingerprint"},
+					},
+				},
+			},
 		},
 	}
 )
@@ -105,37 +169,119 @@ func (s Storer) Get(_ context.Context, id string) (clients.Client, error) {
 	return *res, nil
 }
 
-// Update apples the suppled clients.Change to any clients.Client in the
-// in-memory database that has an ID property matching the passed id. If no
-// clients.Client in the database has an ID property matching the passed id, no
+// ListClients returns a page of the clients.Clients in the in-memory
+// database that match the filters in opts, sorted by their CreatedAt
+// property and then their ID property. If there are more clients.Clients
+// after the page, a cursor to pass in opts to retrieve them is returned.
+// If opts has a cursor that can't be parsed, a clients.ErrInvalidCursor
 // error is returned.
-func (s Storer) Update(_ context.Context, id string, change clients.Change) error {
+func (s Storer) ListClients(_ context.Context, opts clients.ListClientsOptions) ([]clients.Client, string, error) {
+	var cursor *clients.ClientCursor
+	if opts.Cursor != "" {
+		parsed, err := clients.ParseClientCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor = &parsed
+	}
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+	iter, err := txn.Get("client", "id")
+	if err != nil {
+		return nil, "", err
+	}
+	var list []clients.Client
+	for {
+		client := iter.Next()
+		if client == nil {
+			break
+		}
+		res, ok := client.(*clients.Client)
+		if !ok || res == nil {
+			return nil, "", fmt.Errorf("unexpected response type %T, expected %T", client, new(clients.Client)) //nolint:goerr113 // there is no recovering from this
+		}
+		if !opts.Matches(*res) || (cursor != nil && !cursor.After(*res)) {
+			continue
+		}
+		list = append(list, *res)
+	}
+	clients.ClientsByCreatedAt(list)
+	if len(list) <= opts.PageSize() {
+		return list, "", nil
+	}
+	list = list[:opts.PageSize()]
+	return list, clients.CursorFor(list[len(list)-1]).String(), nil
+}
+
+// Update apples the suppled clients.Change to any clients.Client in the
+// in-memory database that has an ID property matching the passed id,
+// incrementing its Version property. If the clients.Client's Version
+// property doesn't match the passed version, a clients.ErrVersionConflict
+// error is returned. If no clients.Client in the database has an ID property
+// matching the passed id, a clients.ErrClientNotFound error is returned. An
+// empty clients.Change only increments the Version property.
+func (s Storer) Update(_ context.Context, id string, version int64, change clients.Change) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	err := updateClient(txn, id, version, change)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// ResetSecret applies the supplied clients.Change to the clients.Client in
+// the in-memory database that has an ID property matching the passed id,
+// like Update, and persists the supplied clients.Secrets, like AddSecrets,
+// in a single transaction. If either fails, neither is stored.
+func (s Storer) ResetSecret(_ context.Context, id string, version int64, change clients.Change, previous []clients.Secret) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
+	err := updateClient(txn, id, version, change)
+	if err != nil {
+		return err
+	}
+	err = addSecrets(txn, previous)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// updateClient applies change to the clients.Client in txn with an ID
+// property matching id, incrementing its Version property, as described
+// on Update.
+func updateClient(txn *memdb.Txn, id string, version int64, change clients.Change) error {
 	client, err := txn.First("client", "id", id)
 	if err != nil {
 		return err
 	}
 	if client == nil {
-		return nil
+		return clients.ErrClientNotFound
 	}
 	res, ok := client.(*clients.Client)
 	if !ok || res == nil {
 		return fmt.Errorf("unexpected response type %T, expected %T", res, new(clients.Client)) //nolint:goerr113 // there is no recovering from this
 	}
-	updated := clients.Apply(change, *res)
-	err = txn.Insert("client", &updated)
-	if err != nil {
-		return err
+	if res.Version != version {
+		return clients.ErrVersionConflict
 	}
-	txn.Commit()
-	return nil
+	updated := clients.Apply(change, *res)
+	updated.Version++
+	return txn.Insert("client", &updated)
 }
 
 // Delete removes any clients.Client in the in-memory database that has an ID
-// property that matches the passed id. If no clients.Client in the database
-// has an ID property that matches the passed id, no error is returned.
-func (s Storer) Delete(_ context.Context, id string) error {
+// property that matches the passed id, along with every clients.RedirectURI,
+// clients.Scope, clients.Secret, and clients.Key with a ClientID property
+// that matches it, in a single transaction. If the clients.Client's Version
+// property doesn't match the passed version, nothing is removed and a
+// clients.ErrVersionConflict error is returned. If no clients.Client in the
+// database has an ID property that matches the passed id, no error is
+// returned.
+func (s Storer) Delete(_ context.Context, id string, version int64) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 	exists, err := txn.First("client", "id", id)
@@ -145,6 +291,19 @@ func (s Storer) Delete(_ context.Context, id string) error {
 	if exists == nil {
 		return nil
 	}
+	client, ok := exists.(*clients.Client)
+	if !ok || client == nil {
+		return fmt.Errorf("unexpected response type %T, expected %T", exists, new(clients.Client)) //nolint:goerr113 // there is no recovering from this
+	}
+	if client.Version != version {
+		return clients.ErrVersionConflict
+	}
+	for _, table := range []string{"redirect_uri", "scope", "secret", "key"} {
+		_, err = txn.DeleteAll(table, "client_id", id)
+		if err != nil {
+			return err
+		}
+	}
 	err = txn.Delete("client", exists)
 	if err != nil {
 		return err
@@ -187,12 +346,17 @@ func (s Storer) ListRedirectURIs(_ context.Context, clientID string) ([]clients.
 // set. If a clients.RedirectURI already exists in the database that has the
 // same URI as one of the specified clients.RedirectURIs, a
 // clients.RedirectURIAlreadyExistsError will be returned with the URI property
-// set. No validation is done that the ClientID property of the passed
-// clients.RedirectURIs refers to a clients.Client in the database.
+// set. If the ClientID property of one of the passed clients.RedirectURIs
+// doesn't refer to a clients.Client in the database,
+// clients.ErrClientNotFound will be returned.
 func (s Storer) AddRedirectURIs(_ context.Context, uris []clients.RedirectURI) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 	for _, uri := range uris {
+		err := requireClient(txn, uri.ClientID)
+		if err != nil {
+			return err
+		}
 		exists, err := txn.First("redirect_uri", "id", uri.ID)
 		if err != nil {
 			return err
@@ -239,3 +403,413 @@ func (s Storer) RemoveRedirectURIs(_ context.Context, ids []string) error {
 	txn.Commit()
 	return nil
 }
+
+// ListScopes returns a []clients.Scope containing all the clients.Scopes in
+// the in-memory database that have a ClientID property that matches clientID.
+// If no clients.Scopes in the database have a ClientID property that matches
+// the passed clientID, an empty slice and nil error are returned. The slice is
+// always sorted lexicographically by the ID.
+func (s Storer) ListScopes(_ context.Context, clientID string) ([]clients.Scope, error) {
+	txn := s.db.Txn(false)
+	var scopes []clients.Scope
+	scopeIter, err := txn.Get("scope", "client_id", clientID)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		scope := scopeIter.Next()
+		if scope == nil {
+			break
+		}
+		res, ok := scope.(*clients.Scope)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T, expected %T", scope, new(clients.Scope)) //nolint:goerr113 // there is no recovering from this
+		}
+		scopes = append(scopes, *res)
+	}
+	clients.ScopesByID(scopes)
+	return scopes, nil
+}
+
+// AddScopes persists the supplied clients.Scopes in the in-memory database.
+// If a clients.Scope already exists in the database that has the same ID and
+// ClientID properties as one of the specified clients.Scopes, a
+// clients.ScopeAlreadyExistsError will be returned. If the ClientID
+// property of one of the passed clients.Scopes doesn't refer to a
+// clients.Client in the database, clients.ErrClientNotFound will be
+// returned.
+func (s Storer) AddScopes(_ context.Context, scopes []clients.Scope) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	for _, scope := range scopes {
+		err := requireClient(txn, scope.ClientID)
+		if err != nil {
+			return err
+		}
+		exists, err := txn.First("scope", "id", scope.ClientID, scope.ID)
+		if err != nil {
+			return err
+		}
+		if exists != nil {
+			return clients.ScopeAlreadyExistsError{ID: scope.ID, ClientID: scope.ClientID}
+		}
+		sc := scope
+		err = txn.Insert("scope", &sc)
+		if err != nil {
+			return err
+		}
+	}
+	txn.Commit()
+	return nil
+}
+
+// RemoveScopes deletes any clients.Scope in the in-memory database that has
+// a ClientID property matching clientID and an ID property matching one of
+// the passed ids. No error is returned if a passed id doesn't match to a
+// clients.Scope in the database.
+func (s Storer) RemoveScopes(_ context.Context, clientID string, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	for _, id := range ids {
+		exists, err := txn.First("scope", "id", clientID, id)
+		if err != nil {
+			return err
+		}
+		if exists == nil {
+			continue
+		}
+		err = txn.Delete("scope", exists)
+		if err != nil {
+			return err
+		}
+	}
+	txn.Commit()
+	return nil
+}
+
+// ListSecrets returns a []clients.Secret containing all the clients.Secrets
+// in the in-memory database that have a ClientID property that matches
+// clientID. If no clients.Secrets in the database have a ClientID property
+// that matches the passed clientID, an empty slice and nil error are
+// returned. The slice is always sorted by CreatedAt, oldest first.
+func (s Storer) ListSecrets(_ context.Context, clientID string) ([]clients.Secret, error) {
+	txn := s.db.Txn(false)
+	var secrets []clients.Secret
+	secretIter, err := txn.Get("secret", "client_id", clientID)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		secret := secretIter.Next()
+		if secret == nil {
+			break
+		}
+		res, ok := secret.(*clients.Secret)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T, expected %T", secret, new(clients.Secret)) //nolint:goerr113 // there is no recovering from this
+		}
+		secrets = append(secrets, *res)
+	}
+	clients.SecretsByCreatedAt(secrets)
+	return secrets, nil
+}
+
+// AddSecrets persists the supplied clients.Secrets in the in-memory
+// database. If a clients.Secret already exists in the database that has the
+// same ID as one of the specified clients.Secrets, a
+// clients.ErrSecretAlreadyExists error will be returned. If the ClientID
+// property of one of the passed clients.Secrets doesn't refer to a
+// clients.Client in the database, clients.ErrClientNotFound will be
+// returned.
+func (s Storer) AddSecrets(_ context.Context, secrets []clients.Secret) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	err := addSecrets(txn, secrets)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// addSecrets inserts secrets into txn, as described on AddSecrets.
+func addSecrets(txn *memdb.Txn, secrets []clients.Secret) error {
+	for _, secret := range secrets {
+		err := requireClient(txn, secret.ClientID)
+		if err != nil {
+			return err
+		}
+		exists, err := txn.First("secret", "id", secret.ID)
+		if err != nil {
+			return err
+		}
+		if exists != nil {
+			return clients.ErrSecretAlreadyExists
+		}
+		sec := secret
+		err = txn.Insert("secret", &sec)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveSecrets deletes any clients.Secret in the in-memory database that
+// has a ClientID property matching clientID and an ID property matching one
+// of the passed ids. No error is returned if a passed id doesn't match to a
+// clients.Secret for the clients.Client in the database.
+func (s Storer) RemoveSecrets(_ context.Context, clientID string, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	for _, id := range ids {
+		exists, err := txn.First("secret", "id", id)
+		if err != nil {
+			return err
+		}
+		if exists == nil {
+			continue
+		}
+		secret, ok := exists.(*clients.Secret)
+		if !ok || secret == nil {
+			return fmt.Errorf("unexpected response type %T, expected %T", exists, new(clients.Secret)) //nolint:goerr113 // there is no recovering from this
+		}
+		if secret.ClientID != clientID {
+			continue
+		}
+		err = txn.Delete("secret", exists)
+		if err != nil {
+			return err
+		}
+	}
+	txn.Commit()
+	return nil
+}
+
+// UseSecret sets the LastUsedAt property of the clients.Secret in the
+// in-memory database with an ID property matching id and a ClientID property
+// matching clientID to usedAt. If no such clients.Secret exists, a
+// clients.ErrSecretNotFound error is returned.
+func (s Storer) UseSecret(_ context.Context, clientID, id string, usedAt time.Time) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	exists, err := txn.First("secret", "id", id)
+	if err != nil {
+		return err
+	}
+	if exists == nil {
+		return clients.ErrSecretNotFound
+	}
+	secret, ok := exists.(*clients.Secret)
+	if !ok || secret == nil {
+		return fmt.Errorf("unexpected response type %T, expected %T", exists, new(clients.Secret)) //nolint:goerr113 // there is no recovering from this
+	}
+	if secret.ClientID != clientID {
+		return clients.ErrSecretNotFound
+	}
+	updated := *secret
+	updated.LastUsedAt = usedAt
+	err = txn.Insert("secret", &updated)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// FindSecretOwner returns the clients.SecretOwner of the secret with the
+// passed fingerprint, looking first for a clients.Client with a
+// SecretFingerprint property matching fingerprint, then for a
+// clients.Secret with a matching Fingerprint property. If neither can be
+// found, a clients.ErrSecretNotFound error is returned.
+func (s Storer) FindSecretOwner(_ context.Context, fingerprint string) (clients.SecretOwner, error) {
+	if fingerprint == "" {
+		return clients.SecretOwner{}, clients.ErrSecretNotFound
+	}
+	txn := s.db.Txn(false)
+	client, err := txn.First("client", "secret_fingerprint", fingerprint)
+	if err != nil {
+		return clients.SecretOwner{}, err
+	}
+	if client != nil {
+		res, ok := client.(*clients.Client)
+		if !ok || res == nil {
+			return clients.SecretOwner{}, fmt.Errorf("unexpected response type %T, expected %T", client, new(clients.Client)) //nolint:goerr113 // there is no recovering from this
+		}
+		return clients.SecretOwner{ClientID: res.ID}, nil
+	}
+	secret, err := txn.First("secret", "fingerprint", fingerprint)
+	if err != nil {
+		return clients.SecretOwner{}, err
+	}
+	if secret == nil {
+		return clients.SecretOwner{}, clients.ErrSecretNotFound
+	}
+	res, ok := secret.(*clients.Secret)
+	if !ok || res == nil {
+		return clients.SecretOwner{}, fmt.Errorf("unexpected response type %T, expected %T", secret, new(clients.Secret)) //nolint:goerr113 // there is no recovering from this
+	}
+	return clients.SecretOwner{ClientID: res.ClientID, SecretID: res.ID}, nil
+}
+
+// ListKeys returns a []clients.Key containing all the clients.Keys in the
+// in-memory database that have a ClientID property that matches clientID. If
+// no clients.Keys in the database have a ClientID property that matches the
+// passed clientID, an empty slice and nil error are returned. The slice is
+// always sorted lexicographically by the ID.
+func (s Storer) ListKeys(_ context.Context, clientID string) ([]clients.Key, error) {
+	txn := s.db.Txn(false)
+	var keys []clients.Key
+	keyIter, err := txn.Get("key", "client_id", clientID)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		key := keyIter.Next()
+		if key == nil {
+			break
+		}
+		res, ok := key.(*clients.Key)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T, expected %T", key, new(clients.Key)) //nolint:goerr113 // there is no recovering from this
+		}
+		keys = append(keys, *res)
+	}
+	clients.KeysByID(keys)
+	return keys, nil
+}
+
+// AddKeys persists the supplied clients.Keys in the in-memory database. If a
+// clients.Key already exists in the database that has the same ID and
+// ClientID properties as one of the specified clients.Keys, a
+// clients.KeyAlreadyExistsError will be returned. If the ClientID property
+// of one of the passed clients.Keys doesn't refer to a clients.Client in the
+// database, clients.ErrClientNotFound will be returned.
+func (s Storer) AddKeys(_ context.Context, keys []clients.Key) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	for _, key := range keys {
+		err := requireClient(txn, key.ClientID)
+		if err != nil {
+			return err
+		}
+		exists, err := txn.First("key", "id", key.ClientID, key.ID)
+		if err != nil {
+			return err
+		}
+		if exists != nil {
+			return clients.KeyAlreadyExistsError{ID: key.ID, ClientID: key.ClientID}
+		}
+		k := key
+		err = txn.Insert("key", &k)
+		if err != nil {
+			return err
+		}
+	}
+	txn.Commit()
+	return nil
+}
+
+// RemoveKeys deletes any clients.Key in the in-memory database that has a
+// ClientID property matching clientID and an ID property matching one of the
+// passed ids. No error is returned if a passed id doesn't match to a
+// clients.Key in the database.
+func (s Storer) RemoveKeys(_ context.Context, clientID string, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	for _, id := range ids {
+		exists, err := txn.First("key", "id", clientID, id)
+		if err != nil {
+			return err
+		}
+		if exists == nil {
+			continue
+		}
+		err = txn.Delete("key", exists)
+		if err != nil {
+			return err
+		}
+	}
+	txn.Commit()
+	return nil
+}
+
+// CountSecretSchemes returns the number of clients.Clients in the in-memory
+// database that use each secret scheme, keyed by the scheme. Only
+// clients.Clients whose HasSecret method returns true are counted.
+func (s Storer) CountSecretSchemes(_ context.Context) (map[string]int64, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+	iter, err := txn.Get("client", "id")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for {
+		client := iter.Next()
+		if client == nil {
+			break
+		}
+		res, ok := client.(*clients.Client)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T, expected %T", client, new(clients.Client)) //nolint:goerr113 // there is no recovering from this
+		}
+		if !res.HasSecret() {
+			continue
+		}
+		counts[res.SecretScheme]++
+	}
+	return counts, nil
+}
+
+// ListClientsDueForRotation returns the clients.Clients in the in-memory
+// database whose HasSecret method returns true and either have a SecretRotatedAt property
+// before rotatedBefore, or a SecretExpiresAt property before expiresBefore.
+// Clients whose SecretRotatedAt property is the zero value are always
+// returned. The clients.Clients are sorted by their SecretRotatedAt
+// property, oldest first, then by their ID property.
+func (s Storer) ListClientsDueForRotation(_ context.Context, rotatedBefore, expiresBefore time.Time) ([]clients.Client, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+	iter, err := txn.Get("client", "id")
+	if err != nil {
+		return nil, err
+	}
+	var due []clients.Client
+	for {
+		client := iter.Next()
+		if client == nil {
+			break
+		}
+		res, ok := client.(*clients.Client)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T, expected %T", client, new(clients.Client)) //nolint:goerr113 // there is no recovering from this
+		}
+		if !res.HasSecret() {
+			continue
+		}
+		if res.SecretRotatedAt.IsZero() || res.SecretRotatedAt.Before(rotatedBefore) || (!res.SecretExpiresAt.IsZero() && res.SecretExpiresAt.Before(expiresBefore)) {
+			due = append(due, *res)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].SecretRotatedAt.Equal(due[j].SecretRotatedAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].SecretRotatedAt.Before(due[j].SecretRotatedAt)
+	})
+	return due, nil
+}
+
+// requireClient returns clients.ErrClientNotFound if no clients.Client with
+// an ID property matching id exists in txn.
+func requireClient(txn *memdb.Txn, id string) error {
+	client, err := txn.First("client", "id", id)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return clients.ErrClientNotFound
+	}
+	return nil
+}
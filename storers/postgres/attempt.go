@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+
+	"lockbox.dev/clients"
+)
+
+// Attempts is a representation of the clients.Attempts type that is
+// suitable to be stored in a PostgreSQL database.
+type Attempts struct {
+	Key           string      `sql_column:"key"`
+	Failures      int         `sql_column:"failures"`
+	LastFailureAt time.Time   `sql_column:"last_failure_at"`
+	LockedUntil   pq.NullTime `sql_column:"locked_until"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (Attempts) GetSQLTableName() string {
+	return "client_auth_attempts"
+}
+
+func attemptsFromPostgres(attempts Attempts) clients.Attempts {
+	return clients.Attempts{
+		Failures:      attempts.Failures,
+		LastFailureAt: attempts.LastFailureAt,
+		LockedUntil:   fromNullTime(attempts.LockedUntil),
+	}
+}
@@ -3,20 +3,49 @@ package postgres
 import (
 	"time"
 
+	"github.com/lib/pq"
+
 	"lockbox.dev/clients"
 )
 
 // Client is a representation of the clients.Client type that is suitable to be
 // stored in a PostgreSQL database.
 type Client struct {
-	ID           string    `sql_column:"id"`
-	Name         string    `sql_column:"name"`
-	SecretHash   string    `sql_column:"secret_hash"`
-	SecretScheme string    `sql_column:"secret_scheme"`
-	Confidential bool      `sql_column:"confidential"`
-	CreatedAt    time.Time `sql_column:"created_at"`
-	CreatedBy    string    `sql_column:"created_by"`
-	CreatedByIP  string    `sql_column:"created_by_ip"`
+	ID                      string         `sql_column:"id"`
+	Name                    string         `sql_column:"name"`
+	SecretHash              string         `sql_column:"secret_hash"`
+	SecretScheme            string         `sql_column:"secret_scheme"`
+	SecretPepperID          string         `sql_column:"secret_pepper_id"`
+	SecretFingerprint       string         `sql_column:"secret_fingerprint"`
+	EncryptedSecret         string         `sql_column:"encrypted_secret"`
+	SecretRotatedAt         pq.NullTime    `sql_column:"secret_rotated_at"`
+	SecretExpiresAt         pq.NullTime    `sql_column:"secret_expires_at"`
+	Confidential            bool           `sql_column:"confidential"`
+	GrantTypes              pq.StringArray `sql_column:"grant_types"`
+	ResponseTypes           pq.StringArray `sql_column:"response_types"`
+	TokenEndpointAuthMethod string         `sql_column:"token_endpoint_auth_method"`
+	JWKSURI                 string         `sql_column:"jwks_uri"`
+	TLSClientAuthSubjectDN  string         `sql_column:"tls_client_auth_subject_dn"`
+	TLSClientAuthSANDNS     string         `sql_column:"tls_client_auth_san_dns"`
+	TLSClientAuthSANURI     string         `sql_column:"tls_client_auth_san_uri"`
+	TLSClientAuthSANIP      string         `sql_column:"tls_client_auth_san_ip"`
+	TLSClientAuthSANEmail   string         `sql_column:"tls_client_auth_san_email"`
+	TLSClientCertThumbprint string         `sql_column:"tls_client_cert_thumbprint"`
+	Status                  string         `sql_column:"status"`
+	StatusReason            string         `sql_column:"status_reason"`
+	StatusChangedAt         pq.NullTime    `sql_column:"status_changed_at"`
+	StatusChangedBy         string         `sql_column:"status_changed_by"`
+	ClientURI               string         `sql_column:"client_uri"`
+	LogoURI                 string         `sql_column:"logo_uri"`
+	PolicyURI               string         `sql_column:"policy_uri"`
+	TOSURI                  string         `sql_column:"tos_uri"`
+	Contacts                pq.StringArray `sql_column:"contacts"`
+	SoftwareID              string         `sql_column:"software_id"`
+	SoftwareVersion         string         `sql_column:"software_version"`
+	CreatedAt               time.Time      `sql_column:"created_at"`
+	CreatedBy               string         `sql_column:"created_by"`
+	CreatedByIP             string         `sql_column:"created_by_ip"`
+	Version                 int64          `sql_column:"version"`
 }
 
 // GetSQLTableName returns the name of the SQL table that the data for this
@@ -27,26 +56,98 @@ func (Client) GetSQLTableName() string {
 
 func fromPostgres(client Client) clients.Client {
 	return clients.Client{
-		ID:           client.ID,
-		Name:         client.Name,
-		SecretHash:   client.SecretHash,
-		SecretScheme: client.SecretScheme,
-		Confidential: client.Confidential,
-		CreatedAt:    client.CreatedAt,
-		CreatedBy:    client.CreatedBy,
-		CreatedByIP:  client.CreatedByIP,
+		ID:                      client.ID,
+		Name:                    client.Name,
+		SecretHash:              client.SecretHash,
+		SecretScheme:            client.SecretScheme,
+		SecretPepperID:          client.SecretPepperID,
+		SecretFingerprint:       client.SecretFingerprint,
+		EncryptedSecret:         client.EncryptedSecret,
+		SecretRotatedAt:         fromNullTime(client.SecretRotatedAt),
+		SecretExpiresAt:         fromNullTime(client.SecretExpiresAt),
+		Confidential:            client.Confidential,
+		GrantTypes:              fromStringArray(client.GrantTypes),
+		ResponseTypes:           fromStringArray(client.ResponseTypes),
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		JWKSURI:                 client.JWKSURI,
+		TLSClientAuthSubjectDN:  client.TLSClientAuthSubjectDN,
+		TLSClientAuthSANDNS:     client.TLSClientAuthSANDNS,
+		TLSClientAuthSANURI:     client.TLSClientAuthSANURI,
+		TLSClientAuthSANIP:      client.TLSClientAuthSANIP,
+		TLSClientAuthSANEmail:   client.TLSClientAuthSANEmail,
+		TLSClientCertThumbprint: client.TLSClientCertThumbprint,
+		Status:                  client.Status,
+		StatusReason:            client.StatusReason,
+		StatusChangedAt:         fromNullTime(client.StatusChangedAt),
+		StatusChangedBy:         client.StatusChangedBy,
+		ClientURI:               client.ClientURI,
+		LogoURI:                 client.LogoURI,
+		PolicyURI:               client.PolicyURI,
+		TOSURI:                  client.TOSURI,
+		Contacts:                fromStringArray(client.Contacts),
+		SoftwareID:              client.SoftwareID,
+		SoftwareVersion:         client.SoftwareVersion,
+		CreatedAt:               client.CreatedAt,
+		CreatedBy:               client.CreatedBy,
+		CreatedByIP:             client.CreatedByIP,
+		Version:                 client.Version,
 	}
 }
 
 func toPostgres(client clients.Client) Client {
 	return Client{
-		ID:           client.ID,
-		Name:         client.Name,
-		SecretHash:   client.SecretHash,
-		SecretScheme: client.SecretScheme,
-		Confidential: client.Confidential,
-		CreatedAt:    client.CreatedAt,
-		CreatedBy:    client.CreatedBy,
-		CreatedByIP:  client.CreatedByIP,
+		ID:                      client.ID,
+		Name:                    client.Name,
+		SecretHash:              client.SecretHash,
+		SecretScheme:            client.SecretScheme,
+		SecretPepperID:          client.SecretPepperID,
+		SecretFingerprint:       client.SecretFingerprint,
+		EncryptedSecret:         client.EncryptedSecret,
+		SecretRotatedAt:         toNullTime(client.SecretRotatedAt),
+		SecretExpiresAt:         toNullTime(client.SecretExpiresAt),
+		Confidential:            client.Confidential,
+		GrantTypes:              toStringArray(client.GrantTypes),
+		ResponseTypes:           toStringArray(client.ResponseTypes),
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		JWKSURI:                 client.JWKSURI,
+		TLSClientAuthSubjectDN:  client.TLSClientAuthSubjectDN,
+		TLSClientAuthSANDNS:     client.TLSClientAuthSANDNS,
+		TLSClientAuthSANURI:     client.TLSClientAuthSANURI,
+		TLSClientAuthSANIP:      client.TLSClientAuthSANIP,
+		TLSClientAuthSANEmail:   client.TLSClientAuthSANEmail,
+		TLSClientCertThumbprint: client.TLSClientCertThumbprint,
+		Status:                  client.Status,
+		StatusReason:            client.StatusReason,
+		StatusChangedAt:         toNullTime(client.StatusChangedAt),
+		StatusChangedBy:         client.StatusChangedBy,
+		ClientURI:               client.ClientURI,
+		LogoURI:                 client.LogoURI,
+		PolicyURI:               client.PolicyURI,
+		TOSURI:                  client.TOSURI,
+		Contacts:                toStringArray(client.Contacts),
+		SoftwareID:              client.SoftwareID,
+		SoftwareVersion:         client.SoftwareVersion,
+		CreatedAt:               client.CreatedAt,
+		CreatedBy:               client.CreatedBy,
+		CreatedByIP:             client.CreatedByIP,
+		Version:                 client.Version,
+	}
+}
+
+// toStringArray converts a []string to a pq.StringArray, making sure nil
+// slices are stored as empty arrays instead of NULL.
+func toStringArray(in []string) pq.StringArray {
+	if in == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(in)
+}
+
+// fromStringArray converts a pq.StringArray to a []string, making sure empty
+// arrays are returned as nil slices, to match how they're passed in.
+func fromStringArray(in pq.StringArray) []string {
+	if len(in) < 1 {
+		return nil
 	}
+	return []string(in)
 }
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+// Key is a representation of the clients.Key type that is suitable to be
+// stored in a PostgreSQL database.
+type Key struct {
+	ID          string    `sql_column:"kid"`
+	ClientID    string    `sql_column:"client_id"`
+	KeyType     string    `sql_column:"kty"`
+	Algorithm   string    `sql_column:"alg"`
+	JWK         string    `sql_column:"jwk"`
+	CreatedAt   time.Time `sql_column:"created_at"`
+	CreatedBy   string    `sql_column:"created_by"`
+	CreatedByIP string    `sql_column:"created_by_ip"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (Key) GetSQLTableName() string {
+	return "client_keys"
+}
+
+func keyFromPostgres(key Key) clients.Key {
+	return clients.Key{
+		ID:          key.ID,
+		ClientID:    key.ClientID,
+		KeyType:     key.KeyType,
+		Algorithm:   key.Algorithm,
+		JWK:         key.JWK,
+		CreatedAt:   key.CreatedAt,
+		CreatedBy:   key.CreatedBy,
+		CreatedByIP: key.CreatedByIP,
+	}
+}
+
+func keyToPostgres(key clients.Key) Key {
+	return Key{
+		ID:          key.ID,
+		ClientID:    key.ClientID,
+		KeyType:     key.KeyType,
+		Algorithm:   key.Algorithm,
+		JWK:         key.JWK,
+		CreatedAt:   key.CreatedAt,
+		CreatedBy:   key.CreatedBy,
+		CreatedByIP: key.CreatedByIP,
+	}
+}
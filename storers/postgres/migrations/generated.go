@@ -3,6 +3,21 @@
 // sql/clients_20181208_1_init.sql
 // sql/clients_20190816_1_add_name.sql
 // sql/clients_20190920_1_unique_uris.sql
+// sql/clients_20261017_1_scopes.sql
+// sql/clients_20261017_2_grant_types.sql
+// sql/clients_20261017_3_secrets.sql
+// sql/clients_20261017_4_status.sql
+// sql/clients_20261017_5_metadata.sql
+// sql/clients_20261017_6_auth_method.sql
+// sql/clients_20261017_7_keys.sql
+// sql/clients_20261017_8_tls_client_auth.sql
+// sql/clients_20261017_9_auth_attempts.sql
+// sql/clients_20261018_1_encrypted_secret.sql
+// sql/clients_20261018_2_secret_pepper.sql
+// sql/clients_20261018_3_secret_fingerprint.sql
+// sql/clients_20261018_4_secret_rotation.sql
+// sql/clients_20261018_5_cascade_delete.sql
+// sql/clients_20261018_6_version.sql
 // DO NOT EDIT!
 
 package migrations
@@ -130,6 +145,306 @@ func sqlClients_20190920_1_unique_urisSql() (*asset, error) {
 	return a, nil
 }
 
+var _sqlClients_20261017_1_scopesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\xcf\x4a\xc3\x40\x18\xc4\xcf\xf9\x9e\x62\x6e\x4d\x30\x3d\x29\xbd\xf4\xb4\x6d\x56\x2c\x6e\xfe\xb0\x7e\x11\xeb\x25\xac\xc9\x56\x16\x62\x1b\xba\x2b\xe2\xdb\x0b\x45\x43\x8a\xb9\xce\xfc\x18\x66\x66\xb9\xc4\xcd\x87\x7b\x3f\x9b\x60\x51\x0f\xb4\xd5\x52\xb0\x04\x8b\x8d\x92\x68\x7b\x67\x8f\xa1\xf1\xed\x69\xb0\x1e\x31\x45\xbf\x82\xeb\xf0\x2c\xf4\xf6\x41\xe8\xf8\x76\x95\xa0\x28\x19\x45\xad\x54\x4a\xd1\x05\x05\xcb\x17\x9e\xaa\xce\x37\x9d\x3d\x98\xcf\x3e\x60\x53\x96\x4a\x8a\x62\x74\x91\xc9\x7b\x51\x2b\xc6\xc1\xf4\xde\xa6\x14\xb5\x67\x6b\x82\xed\x1a\x13\xc0\xbb\x5c\x3e\xb1\xc8\x2b\x7e\x9d\xa6\xfd\x11\x6f\xdf\x63\x8b\xd5\x5d\xf2\x3f\x71\xb1\xb8\x82\x1b\x37\xcc\xb6\xbe\xe6\x2b\xbd\xcb\x85\xde\xe3\x51\xee\x11\x8f\x73\x53\x5c\x86\x25\x94\xac\x89\xa6\x8f\x65\xa7\xaf\x23\x65\xba\xac\xe6\x1e\x5b\xd3\xcf\x00\x5e\xad\xbc\x0b\x5d\x01\x00\x00")
+
+func sqlClients_20261017_1_scopesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_1_scopesSql,
+		"sql/clients_20261017_1_scopes.sql",
+	)
+}
+
+func sqlClients_20261017_1_scopesSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_1_scopesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_1_scopes.sql", size: 349, mode: os.FileMode(436), modTime: time.Unix(1792264087, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_2_grant_typesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc4\x92\xc1\x4a\xc3\x40\x10\x86\xcf\xdd\xa7\x98\x5b\x0e\x26\x2f\x90\xd0\x43\x34\x11\x0f\xb1\x2d\x75\x83\x82\x48\x58\x92\x49\xb3\x74\xb3\x1b\x76\x67\xad\xb5\xf4\xdd\x25\xad\x68\x91\x42\xc5\x8b\xf7\x6f\xbe\x99\xf9\xf9\xa3\x08\xae\x7a\xb9\xb2\x82\x10\xca\x81\xa5\x05\xcf\x97\xc0\xd3\xeb\x22\x87\x5a\x49\xd4\xe4\x20\xcd\x32\xb8\x99\x17\xe5\xfd\x0c\x56\x56\x68\xaa\x68\x3b\xa0\x03\x9e\x3f\xf1\xe7\x17\x98\xcd\x39\xcc\xca\xa2\x80\x2c\xbf\x4d\xcb\x82\x43\xb0\xdb\x07\xc9\x25\x91\x45\x37\x18\xed\xf0\x57\x2e\x16\x45\x80\x6f\xd2\x91\xd4\xab\x2f\x59\x6d\xbc\x6a\xc0\x3b\x04\xa1\xb7\xd0\x2a\xb3\x09\xc1\x19\x58\x23\x0e\x20\x94\x32\x9b\x11\xa6\x0e\x7b\x20\x03\x5e\x93\x54\xa3\x86\x3a\xdc\x06\x16\xc1\x0f\x8d\x20\x6c\x58\xb9\xc8\x52\xfe\x7d\xe1\x43\xce\xd9\xe4\xf4\xc9\x29\x04\x3b\xe1\xa9\x33\x56\xbe\x0b\x92\x46\x57\xb5\x69\x30\xb4\xd8\x5a\x74\x5d\x45\x66\x8d\x3a\xf4\x56\xc7\x12\xa9\x8d\x07\x61\x45\xef\x62\x33\x4e\xc4\x07\x4d\x34\x6a\xe2\x06\x5f\x65\x8d\xc7\x51\xd9\x0f\x4a\xd6\x92\xf6\x41\xc8\x26\x3f\x62\x18\xb7\x1d\xa0\x83\x77\x1f\xb0\xc7\xbb\x7c\x99\x43\x6d\x74\x2b\x1b\xd4\x24\x85\x82\x29\xb4\x42\x39\x4c\xfe\x7a\xfa\x91\xaf\x6a\x8b\x9f\x46\xf7\xbf\xdf\x90\xf5\x98\x30\x76\xda\xc3\xcc\x6c\xf4\xd9\x02\x65\xcb\xf9\xe2\x7c\x83\x92\x8b\xfc\x49\x34\x09\xfb\x18\x00\xa9\x09\xd0\x8a\xf6\x02\x00\x00")
+
+func sqlClients_20261017_2_grant_typesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_2_grant_typesSql,
+		"sql/clients_20261017_2_grant_types.sql",
+	)
+}
+
+func sqlClients_20261017_2_grant_typesSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_2_grant_typesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_2_grant_types.sql", size: 758, mode: os.FileMode(436), modTime: time.Unix(1792264205, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_3_secretsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x91\xcd\x4e\xc3\x30\x10\x84\xcf\xd9\xa7\xd8\x5b\x53\x91\x5e\x00\xf5\xd2\x93\x69\x8c\x88\xc8\x9f\xcc\x06\xb5\x5c\x2c\x93\xae\x88\xa5\xb6\x44\xb1\x11\xf0\xf6\x08\x01\x4d\x1a\x72\x9e\xcf\xdf\xca\x33\x8b\x05\x5e\x1c\xec\x4b\x67\x3c\x63\xd5\xc2\x5a\x49\x41\x12\x49\xdc\xa4\x12\xeb\xbd\xe5\xa3\xd7\x8e\xeb\x8e\xbd\xc3\x10\x02\xbb\xc3\x47\xa1\xd6\x77\x42\x85\x57\xcb\x39\x96\x2a\xc9\x84\xda\xe2\xbd\xdc\x46\x10\xfc\xe2\x23\x26\x2f\x08\xf3\x2a\x4d\x23\x08\x7e\x44\xba\x31\xae\x41\x92\x1b\x9a\xc8\x5c\xdd\xf0\x81\x7b\xc1\xe5\x99\xa0\xee\xd8\x78\xde\x69\xe3\x91\x92\x4c\x3e\x90\xc8\x4a\x7a\x1a\x12\xfc\xd1\xda\x8e\xdd\x88\x88\x20\xd8\x1b\xe7\xf5\x9b\xfb\xf7\x78\x60\x7d\xfe\x3c\xdd\x5d\x5e\xf7\x77\x31\x96\xb7\xa2\x4a\x09\x67\xb3\x33\x58\xdb\x76\xf2\xa3\x03\x1e\xe6\x2b\xf8\xab\x34\xc9\x63\xb9\x19\x55\xaa\xfb\xca\x8a\x7c\x94\x61\x78\x0a\xbf\x2d\xc3\x9d\xe2\xd7\xf7\x23\xc4\xaa\x28\x27\x77\x5a\xc1\xd7\x00\x62\x8d\xf8\x4c\xd4\x01\x00\x00")
+
+func sqlClients_20261017_3_secretsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_3_secretsSql,
+		"sql/clients_20261017_3_secrets.sql",
+	)
+}
+
+func sqlClients_20261017_3_secretsSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_3_secretsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_3_secrets.sql", size: 468, mode: os.FileMode(436), modTime: time.Unix(1792264442, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_4_statusSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x90\x4d\x8b\xc2\x30\x18\x84\xef\xf9\x15\xef\xad\xbb\x2c\x3d\x2c\x2c\xbd\xe4\x94\x6d\xb2\xac\x90\x7e\x10\xdf\x88\x78\x29\xb1\x86\x5a\xd0\x54\x9a\xa8\xf8\xef\x3d\x08\xe2\xa1\x42\x7a\x9f\xe7\x19\x66\xd2\x14\xbe\x8e\x7d\x37\x9a\x60\x41\x9f\x08\x93\x28\x14\x20\xfb\x95\x02\xda\x43\x6f\x5d\xf0\xc0\x38\x87\xbc\x92\xba\x28\xc1\x07\x13\xce\x1e\x56\x4c\xe5\xff\x4c\x7d\x7c\x67\x9f\x50\x56\x08\xa5\x96\x12\xb8\xf8\x63\x5a\x22\x24\xa6\x0d\xfd\xc5\x26\x34\x4e\xd6\x8c\xd6\xf8\xc1\x01\x8a\x35\x4e\xc8\xa2\x35\xed\xde\xb8\xce\xee\x1a\x13\x00\x17\x85\x58\x22\x2b\x6a\xdc\xcc\xa5\xb7\xb7\xe7\xb8\xec\x67\x6a\x5c\x42\x09\x79\xfd\x8c\x0f\x57\x37\xd9\xc1\x55\x55\xbf\x2d\xa1\x73\x11\x13\xa2\x91\xc7\x9f\xb1\x71\x4a\xee\x03\x00\x83\xaa\x66\x6c\x02\x02\x00\x00")
+
+func sqlClients_20261017_4_statusSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_4_statusSql,
+		"sql/clients_20261017_4_status.sql",
+	)
+}
+
+func sqlClients_20261017_4_statusSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_4_statusSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_4_status.sql", size: 514, mode: os.FileMode(436), modTime: time.Unix(1792264557, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_5_metadataSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\xd2\x41\x6b\x83\x30\x14\xc0\xf1\xbb\x9f\xe2\xdd\x3c\x0c\x3f\x81\xa7\x6c\xc9\x4e\x99\x0e\x49\x60\x30\x86\x48\xcc\x24\xe0\xf2\x24\xc9\x2a\xa5\xf4\xbb\x17\x8a\xd6\x1e\x6c\x53\xea\x35\xfc\xf3\x23\xe4\xbd\x2c\x83\x97\x3f\xd3\xb9\x26\x68\x90\x43\x42\xb8\x60\x15\x08\xf2\xca\x19\xa8\xde\x68\x1b\x3c\x10\x4a\xe1\xad\xe4\xf2\xa3\x98\x8e\xea\x7f\x67\x40\xb0\x2f\x01\x45\x29\xa0\x90\x9c\x03\x65\xef\x44\x72\x01\x69\x9a\xc7\x8c\x1e\x3b\xdc\x26\x0c\xd8\x1b\xb5\xdf\x66\x04\xf4\xdb\x00\x85\x36\x34\x2a\xf8\xb3\xf0\xfd\xb3\x62\x1c\x8e\x71\xc5\xe3\x6f\x18\x1b\xa7\x6b\xd3\x3e\xff\x94\x0b\xb2\xd3\xce\x1b\xb4\x77\xa4\xe4\x7a\xde\x14\x47\xbb\x6a\xd3\xaa\xfc\xbc\x85\xe7\x8f\xdf\x30\x6d\x3c\x9e\xbf\x31\x5e\x4e\x13\x8b\x87\xcb\x7a\xc4\xdb\x79\x19\xe3\xe5\xb2\xfa\x79\x72\x1a\x00\xf8\x16\xda\x0f\x35\x03\x00\x00")
+
+func sqlClients_20261017_5_metadataSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_5_metadataSql,
+		"sql/clients_20261017_5_metadata.sql",
+	)
+}
+
+func sqlClients_20261017_5_metadataSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_5_metadataSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_5_metadata.sql", size: 821, mode: os.FileMode(436), modTime: time.Unix(1792264622, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_6_auth_methodSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\xd0\xbd\x4e\xc3\x30\x14\xc5\xf1\xdd\x4f\x71\xb6\x80\x50\x17\x18\xa3\x0e\xa6\x36\xea\x60\x92\xca\xd8\x30\x5a\x26\xb9\xa5\x16\xe9\x75\x95\xdc\x8a\xd7\x67\x60\x80\x81\x0f\xb1\x1f\xfd\x8f\xf4\x5b\xad\x70\x75\x2c\x2f\x73\x16\x42\x3c\x29\xed\x82\xf5\x08\xfa\xd6\x59\x0c\x53\x21\x96\x05\xda\x18\x6c\x7a\x17\xef\x3b\x48\x7d\x25\x4e\xc4\xe3\xa9\x16\x96\x94\xcf\x72\x48\x47\x92\x43\x1d\xf1\xa8\xfd\x66\xab\xfd\xc5\xcd\xf5\x25\xba\x3e\xa0\x8b\xce\xc1\xd8\x3b\x1d\x5d\x40\xd3\xb4\x4a\xc5\x9d\xd1\xe1\x33\xfb\x60\xc3\x6f\xbd\x35\x9a\x8f\x65\x5a\x68\x98\x49\xd2\x73\x5e\xca\xd0\xe0\x69\x6b\xbd\xc5\x50\x79\x5f\x46\x62\x29\x79\xc2\x1a\x32\x9f\xa9\xfd\xff\x01\x57\xa6\x1f\x8a\xfb\x3c\x2d\xd4\x2a\xf5\xd5\xc7\xd4\x37\xfe\x56\xc8\xf8\x7e\xf7\x37\x51\xab\xde\x07\x00\xd7\x32\x7d\x92\x6d\x01\x00\x00")
+
+func sqlClients_20261017_6_auth_methodSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_6_auth_methodSql,
+		"sql/clients_20261017_6_auth_method.sql",
+	)
+}
+
+func sqlClients_20261017_6_auth_methodSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_6_auth_methodSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_6_auth_method.sql", size: 365, mode: os.FileMode(436), modTime: time.Unix(1792264693, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_7_keysSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x91\x5f\x4b\xc3\x30\x14\xc5\x9f\x77\x3f\xc5\x7d\xdb\x8a\xdb\x83\x28\x43\xe8\xd3\xb5\x89\x38\x4c\xff\x10\x6f\xc5\xf9\x52\xea\x1a\x46\x96\x39\x47\x1b\x29\xfb\xf6\xa2\x48\x35\xae\x8f\x49\x7e\xfc\xc2\x39\x67\xb1\xc0\x8b\x37\xbb\x6d\x6b\x6f\xb0\x3c\x02\x29\x96\x1a\x99\x6e\x95\xc4\xcd\xde\x9a\x83\xef\x90\x84\xc0\x24\x57\x65\x9a\xe1\xae\x77\x5d\xf5\xd1\x5a\x64\xf9\xcc\x98\xe5\x8c\x59\xa9\x14\x0a\x79\x47\xa5\x62\x9c\x4e\x63\x80\x44\x4b\x62\x19\x38\x2a\x67\x4e\x1d\xce\x60\xf2\x73\xb4\x0d\x3e\x91\x4e\xee\x49\xcf\xae\x96\xd1\xe0\x99\xc3\xc4\xd9\x26\x74\x7f\xdd\xf9\xd3\x80\xdf\x04\x74\xbd\xdf\x0e\x2f\x97\xa1\x68\xd7\xbb\x33\xd1\xa6\x35\xb5\x37\x4d\x55\x7b\xe4\x55\x2a\x1f\x99\xd2\x82\x5f\xc6\x88\xd7\xdf\x1f\x97\xd7\xd1\x58\xd0\x00\xae\xec\x71\x34\x50\xc8\x17\x7a\x95\x92\x5e\xe3\x83\x5c\xe3\x6c\x68\x62\x8e\xce\x36\x11\x44\x31\xc0\xdf\x2d\xc4\x7b\x7f\x00\xa1\xf3\xe2\xbc\xc8\x78\x74\xa5\x6f\xf6\xdf\x4c\x31\x7c\x0e\x00\xd4\x47\xa5\x06\xdf\x01\x00\x00")
+
+func sqlClients_20261017_7_keysSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_7_keysSql,
+		"sql/clients_20261017_7_keys.sql",
+	)
+}
+
+func sqlClients_20261017_7_keysSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_7_keysSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_7_keys.sql", size: 479, mode: os.FileMode(436), modTime: time.Unix(1792264810, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_8_tls_client_authSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\xd1\xb1\x6a\x04\x21\x10\xc6\xf1\x7e\x9f\x62\xba\x2b\xc2\x3d\xc1\x56\x9b\x68\x2a\xb3\x1b\x0e\x85\x74\xe2\xaa\xe4\xe6\xd8\x9d\x88\x8e\xe4\xf5\x53\xa4\x49\x20\xe4\x58\xb1\x15\xfe\xbf\x0f\x99\xf3\x19\x1e\x76\x7c\xcf\x8e\x23\x98\x34\x4c\x4a\xcb\x0b\xe8\xe9\x51\x49\xf0\x1b\x46\xe2\x02\x93\x10\xf0\xb4\x28\xf3\x32\x03\x6f\xc5\x7e\x3f\x5b\x57\xf9\x6a\x4b\x5d\x6f\xd1\xb3\x0d\x04\x5a\xbe\x69\x98\x17\x0d\xb3\x51\x0a\x84\x7c\x9e\x8c\xd2\x70\x3a\x8d\x87\x4d\x47\x36\x50\xe9\x0b\xd6\x8c\x7d\x41\x4c\x7d\xbd\xb8\x3b\xdc\xba\x90\x3e\x66\xb6\x7c\xad\xfb\x9a\x32\x12\xff\x63\x0e\x3f\x8f\x2f\x3e\x3e\xe9\xcf\x15\x71\x59\x5e\xef\xcf\x8c\x47\xda\xdf\xbf\x6e\x4b\x31\xb5\x75\x35\x63\x5b\x18\xa8\x34\x84\x75\xbd\x45\xcf\x36\xd0\x38\x7c\x0d\x00\x0b\x39\xf0\xea\x6b\x03\x00\x00")
+
+func sqlClients_20261017_8_tls_client_authSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_8_tls_client_authSql,
+		"sql/clients_20261017_8_tls_client_auth.sql",
+	)
+}
+
+func sqlClients_20261017_8_tls_client_authSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_8_tls_client_authSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_8_tls_client_auth.sql", size: 875, mode: os.FileMode(436), modTime: time.Unix(1792265404, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261017_9_auth_attemptsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\xce\xbd\x6e\x83\x30\x10\x00\xe0\x99\x7b\x8a\x1b\x5b\x15\x9e\x80\xc9\x2d\xa7\x0a\x95\x3f\xb9\x87\x54\xba\x58\x16\x75\x13\x0b\xf3\x23\x38\x14\xe5\xed\xb3\x64\x20\x4b\xf6\x6f\xf8\x92\x04\xdf\x46\x7f\x5a\xad\x38\x6c\x17\xf8\xd0\xa4\x98\x90\xd5\x7b\x41\xd8\x07\xef\x26\x31\x76\x97\xb3\xb1\x22\x6e\x5c\x64\xc3\x17\x88\x06\x77\x45\xa6\x1f\xc6\x46\xe7\xa5\xd2\x1d\x7e\x51\x17\x43\xf4\x6f\x7d\xd8\x57\xb7\x61\x5e\x31\x7d\x92\xc6\xaa\x66\xac\xda\xa2\x88\x21\x0a\x76\x13\x73\x07\xc6\x0a\x72\x5e\xd2\x37\xab\xb2\xe1\xdf\x07\x36\xf7\x83\xfb\x33\xfb\x24\x3e\x1c\x0d\xbc\xa6\x00\xc7\x6a\x36\x5f\x26\xc8\x74\xdd\x3c\xa9\xa6\x70\x1b\x00\x71\x6e\x33\xe1\xdd\x00\x00\x00")
+
+func sqlClients_20261017_9_auth_attemptsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261017_9_auth_attemptsSql,
+		"sql/clients_20261017_9_auth_attempts.sql",
+	)
+}
+
+func sqlClients_20261017_9_auth_attemptsSql() (*asset, error) {
+	bytes, err := sqlClients_20261017_9_auth_attemptsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261017_9_auth_attempts.sql", size: 221, mode: os.FileMode(436), modTime: time.Unix(1792265682, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261018_1_encrypted_secretSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\xcc\xb1\x0a\xc2\x30\x10\x06\xe0\x3d\x4f\xf1\x6f\x1d\xa4\x4f\xd0\x29\x7a\x71\x3a\x13\x29\x17\x70\x13\x89\x87\x14\x34\x96\xe4\x40\x7c\x7b\x57\x07\x7d\x81\x6f\x1c\xb1\x79\x2c\xb7\x76\x31\x45\x5e\x9d\x67\x09\x33\xc4\x6f\x39\xa0\xdc\x17\xad\xd6\xe1\x89\xb0\x4b\x9c\x0f\x11\x5a\x4b\x7b\xaf\xa6\xd7\x73\xd7\xd2\xd4\x20\xe1\x24\x88\x49\x10\x33\x33\x28\xec\x7d\x66\xc1\x30\x4c\xce\x7d\xcb\xf4\x7c\xd5\x9f\x36\xcd\xe9\xf8\x0f\x9f\xdc\x67\x00\x96\xa8\xdb\x59\x9d\x00\x00\x00")
+
+func sqlClients_20261018_1_encrypted_secretSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261018_1_encrypted_secretSql,
+		"sql/clients_20261018_1_encrypted_secret.sql",
+	)
+}
+
+func sqlClients_20261018_1_encrypted_secretSql() (*asset, error) {
+	bytes, err := sqlClients_20261018_1_encrypted_secretSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261018_1_encrypted_secret.sql", size: 157, mode: os.FileMode(436), modTime: time.Unix(1792265798, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261018_2_secret_pepperSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xce\xc9\x4c\xcd\x2b\x29\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x4e\x4d\x2e\x4a\x2d\x89\x2f\x48\x2d\x28\x48\x2d\x8a\xcf\x4c\x51\x08\x71\x8d\x08\x51\xf0\xf3\x0f\x51\xf0\x0b\xf5\xf1\x51\x70\x71\x75\x73\x0c\xf5\x09\x51\x50\x57\xb7\xc6\x62\x52\x3c\x44\x37\xb9\x06\x72\x21\x3b\xd5\x25\xbf\x3c\x0f\x9f\x15\x2e\x41\xfe\x01\xb8\xec\xc0\xe6\x36\x42\x3a\x00\x03\x00\x16\x78\x3e\x11\x27\x01\x00\x00")
+
+func sqlClients_20261018_2_secret_pepperSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261018_2_secret_pepperSql,
+		"sql/clients_20261018_2_secret_pepper.sql",
+	)
+}
+
+func sqlClients_20261018_2_secret_pepperSql() (*asset, error) {
+	bytes, err := sqlClients_20261018_2_secret_pepperSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261018_2_secret_pepper.sql", size: 295, mode: os.FileMode(436), modTime: time.Unix(1792266222, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261018_3_secret_fingerprintSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x91\xcd\x8a\xc2\x30\x14\x85\xf7\x7d\x8a\xbb\xeb\x0c\x43\x9f\xa0\x83\x10\x9b\x2b\x0a\x31\x91\x90\x60\x77\x45\x4a\x2c\x01\x8d\x25\x0d\xf8\xfa\x82\x12\x10\x9a\x56\xe9\xfa\xfc\x7c\x07\x4e\x51\xc0\xdf\xd5\x76\xfe\x14\x0c\xe8\x3e\x23\x4c\xa1\x04\x45\xd6\x0c\xa1\xbd\x58\xe3\xc2\x00\x84\x52\xa8\x04\xd3\x7b\x0e\x83\x69\xbd\x09\xcd\xd9\xba\xce\xf8\xde\x5b\x17\x40\x61\xad\x80\x0b\x05\x5c\x33\x06\x14\x37\x44\x33\x05\x79\x5e\x26\xba\x9a\x57\x7e\x79\x65\x25\x91\x28\x84\x1d\xa7\x58\xc7\x7d\x4d\xa2\x41\xf0\xa8\xc2\xcf\x58\xfe\x85\xe3\x16\x25\xa6\xd0\xff\xab\x29\x4e\xdc\x3e\x8b\x8b\xa6\x65\xd4\xec\xfd\x0c\x7a\xbb\xbb\x8c\x4a\x71\xf8\x76\x45\x39\x76\xa7\x6d\x33\xbf\x3c\x1b\x26\x8f\x49\x45\x3f\x67\x1e\x03\x00\xf3\x80\x5c\xa3\x62\x02\x00\x00")
+
+func sqlClients_20261018_3_secret_fingerprintSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261018_3_secret_fingerprintSql,
+		"sql/clients_20261018_3_secret_fingerprint.sql",
+	)
+}
+
+func sqlClients_20261018_3_secret_fingerprintSql() (*asset, error) {
+	bytes, err := sqlClients_20261018_3_secret_fingerprintSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261018_3_secret_fingerprint.sql", size: 610, mode: os.FileMode(436), modTime: time.Unix(1792266498, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261018_4_secret_rotationSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x90\xdf\x6a\x83\x30\x14\x87\xef\xf3\x14\xe7\xce\x8d\xe1\x13\xb8\x0d\x32\x73\x60\x82\xff\x88\x91\x8d\xde\x48\xb0\x87\x2a\xb4\x2a\x49\xa0\x7d\xfc\xd2\x82\xad\xa0\x52\x2f\x43\xce\xf7\x7d\xf0\xf3\x7d\xf8\x38\xb5\x07\xa3\x1d\x41\x39\x30\x1e\x2b\x94\xa0\xf8\x4f\x8c\x50\x1f\x5b\xea\x9c\x05\x2e\x04\x84\x59\x5c\x26\x29\x58\xaa\x0d\xb9\xca\xf4\x4e\x3b\xda\x57\xda\x81\x8a\x12\x2c\x14\x4f\x72\xb5\x0b\x36\xd2\x74\x19\x5a\x43\x76\x46\x97\xb9\xe0\xea\x09\x16\xa8\x16\x7a\x5f\x50\x1b\x1a\x1f\x7f\xbf\x28\x71\x3c\x6a\xb4\x6d\xe0\xf3\x1b\x3c\x2f\x60\xa1\xc4\x9b\x2a\x4a\x05\xfe\x8f\xc2\x6a\x2e\xcb\xd2\x47\xed\x6d\xf6\xfb\xbe\xae\x67\xd3\xd5\x44\x7f\xee\x98\x90\x59\xfe\x2a\xb7\xbc\xcf\x9d\x5c\x1b\x68\x33\x32\xad\x5c\x07\x00\x4b\xdf\x70\x50\xd3\x01\x00\x00")
+
+func sqlClients_20261018_4_secret_rotationSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261018_4_secret_rotationSql,
+		"sql/clients_20261018_4_secret_rotation.sql",
+	)
+}
+
+func sqlClients_20261018_4_secret_rotationSql() (*asset, error) {
+	bytes, err := sqlClients_20261018_4_secret_rotationSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261018_4_secret_rotation.sql", size: 467, mode: os.FileMode(436), modTime: time.Unix(1792266692, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261018_5_cascade_deleteSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x93\xdf\x4a\xc3\x30\x14\xc6\xef\xfb\x14\xe7\x72\x43\xf6\x04\xbd\x8a\xc9\x57\x2d\xd6\x44\xd2\x88\x7a\x55\xa4\x8d\x12\xa6\xdb\x68\x2b\xb2\xb7\x97\xb5\x55\x97\xb2\x94\x21\x73\xb7\xf9\x4e\x7e\xfc\x38\x7f\x16\x0b\xba\x78\x77\xaf\xf5\x73\x6b\xe9\x7e\x13\x09\x64\x30\xa0\x44\xab\x5b\xaa\x6d\xe5\x6a\x5b\xb6\xc5\x47\xed\x1a\x7a\xb8\x86\x06\x95\x6f\xce\xae\xda\xc2\x55\x24\x95\xa1\x54\xd2\x2c\x47\x06\x6e\xc8\x55\xfd\xa7\xbe\xa0\x99\xc7\x1e\x6a\xf8\xd6\x94\xeb\x8d\x3d\x11\xca\x96\xb5\x6d\x4f\xc3\x5a\xda\xed\x1f\x40\x5c\x83\x19\x50\x2a\x05\x1e\xfd\x5e\x15\xbf\x14\x25\x47\x6d\x9c\xfd\x64\xf3\x38\x62\x99\x81\x26\xc3\x2e\x33\x8c\xca\x98\x10\xc4\x95\xcc\x8d\x66\xa9\x34\x21\x7c\xf1\xb2\xb4\x5b\x4a\x94\x46\x7a\x25\xe9\x06\x4f\xfb\x78\xd2\x48\xa0\x21\x39\xf2\x6f\x6b\x9a\xed\xde\x95\xa4\xa1\x09\x9c\xe5\x9c\x09\xf8\x22\xfe\xac\x46\x22\x5e\x78\x26\x91\x61\xd2\x01\x93\x3e\x3d\x8f\x4a\xb7\x28\x87\x3d\x76\xd1\x3f\x48\x44\xfb\xf7\x29\xd6\x9f\xab\xa0\x96\xd0\xea\xee\x38\xaf\xc9\x2e\x07\x30\x43\x7c\x14\xa9\xdb\x8d\x20\xe8\xd0\xe6\x4c\x5d\xc2\x98\x33\x75\x0a\x71\xd4\x55\x4f\x9e\x64\x1c\x7d\x0d\x00\x12\x08\x2d\x75\xf3\x04\x00\x00")
+
+func sqlClients_20261018_5_cascade_deleteSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261018_5_cascade_deleteSql,
+		"sql/clients_20261018_5_cascade_delete.sql",
+	)
+}
+
+func sqlClients_20261018_5_cascade_deleteSql() (*asset, error) {
+	bytes, err := sqlClients_20261018_5_cascade_deleteSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261018_5_cascade_delete.sql", size: 1267, mode: os.FileMode(436), modTime: time.Unix(1792267237, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlClients_20261018_6_versionSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xce\xc9\x4c\xcd\x2b\x29\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x4b\x2d\x2a\xce\xcc\xcf\x53\x70\xf2\x74\xf7\xf4\x0b\x51\xf0\xf3\x0f\x51\xf0\x0b\xf5\xf1\x51\x70\x71\x75\x73\x0c\xf5\x09\x51\x30\xb0\xe6\xe2\x42\x36\xd1\x25\xbf\x3c\x0f\xab\x99\x2e\x41\xfe\x01\x68\x86\x5a\x73\x01\x06\x00\xfb\xe8\xd2\xa1\x8c\x00\x00\x00")
+
+func sqlClients_20261018_6_versionSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlClients_20261018_6_versionSql,
+		"sql/clients_20261018_6_version.sql",
+	)
+}
+
+func sqlClients_20261018_6_versionSql() (*asset, error) {
+	bytes, err := sqlClients_20261018_6_versionSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/clients_20261018_6_version.sql", size: 140, mode: os.FileMode(436), modTime: time.Unix(1792267363, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -182,9 +497,24 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"sql/clients_20181208_1_init.sql":        sqlClients_20181208_1_initSql,
-	"sql/clients_20190816_1_add_name.sql":    sqlClients_20190816_1_add_nameSql,
-	"sql/clients_20190920_1_unique_uris.sql": sqlClients_20190920_1_unique_urisSql,
+	"sql/clients_20181208_1_init.sql":               sqlClients_20181208_1_initSql,
+	"sql/clients_20190816_1_add_name.sql":           sqlClients_20190816_1_add_nameSql,
+	"sql/clients_20190920_1_unique_uris.sql":        sqlClients_20190920_1_unique_urisSql,
+	"sql/clients_20261017_1_scopes.sql":             sqlClients_20261017_1_scopesSql,
+	"sql/clients_20261017_2_grant_types.sql":        sqlClients_20261017_2_grant_typesSql,
+	"sql/clients_20261017_3_secrets.sql":            sqlClients_20261017_3_secretsSql,
+	"sql/clients_20261017_4_status.sql":             sqlClients_20261017_4_statusSql,
+	"sql/clients_20261017_5_metadata.sql":           sqlClients_20261017_5_metadataSql,
+	"sql/clients_20261017_6_auth_method.sql":        sqlClients_20261017_6_auth_methodSql,
+	"sql/clients_20261017_7_keys.sql":               sqlClients_20261017_7_keysSql,
+	"sql/clients_20261017_8_tls_client_auth.sql":    sqlClients_20261017_8_tls_client_authSql,
+	"sql/clients_20261017_9_auth_attempts.sql":      sqlClients_20261017_9_auth_attemptsSql,
+	"sql/clients_20261018_1_encrypted_secret.sql":   sqlClients_20261018_1_encrypted_secretSql,
+	"sql/clients_20261018_2_secret_pepper.sql":      sqlClients_20261018_2_secret_pepperSql,
+	"sql/clients_20261018_3_secret_fingerprint.sql": sqlClients_20261018_3_secret_fingerprintSql,
+	"sql/clients_20261018_4_secret_rotation.sql":    sqlClients_20261018_4_secret_rotationSql,
+	"sql/clients_20261018_5_cascade_delete.sql":     sqlClients_20261018_5_cascade_deleteSql,
+	"sql/clients_20261018_6_version.sql":            sqlClients_20261018_6_versionSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -229,9 +559,24 @@ type bintree struct {
 
 var _bintree = &bintree{nil, map[string]*bintree{
 	"sql": &bintree{nil, map[string]*bintree{
-		"clients_20181208_1_init.sql":        &bintree{sqlClients_20181208_1_initSql, map[string]*bintree{}},
-		"clients_20190816_1_add_name.sql":    &bintree{sqlClients_20190816_1_add_nameSql, map[string]*bintree{}},
-		"clients_20190920_1_unique_uris.sql": &bintree{sqlClients_20190920_1_unique_urisSql, map[string]*bintree{}},
+		"clients_20181208_1_init.sql":               &bintree{sqlClients_20181208_1_initSql, map[string]*bintree{}},
+		"clients_20190816_1_add_name.sql":           &bintree{sqlClients_20190816_1_add_nameSql, map[string]*bintree{}},
+		"clients_20190920_1_unique_uris.sql":        &bintree{sqlClients_20190920_1_unique_urisSql, map[string]*bintree{}},
+		"clients_20261017_1_scopes.sql":             &bintree{sqlClients_20261017_1_scopesSql, map[string]*bintree{}},
+		"clients_20261017_2_grant_types.sql":        &bintree{sqlClients_20261017_2_grant_typesSql, map[string]*bintree{}},
+		"clients_20261017_3_secrets.sql":            &bintree{sqlClients_20261017_3_secretsSql, map[string]*bintree{}},
+		"clients_20261017_4_status.sql":             &bintree{sqlClients_20261017_4_statusSql, map[string]*bintree{}},
+		"clients_20261017_5_metadata.sql":           &bintree{sqlClients_20261017_5_metadataSql, map[string]*bintree{}},
+		"clients_20261017_6_auth_method.sql":        &bintree{sqlClients_20261017_6_auth_methodSql, map[string]*bintree{}},
+		"clients_20261017_7_keys.sql":               &bintree{sqlClients_20261017_7_keysSql, map[string]*bintree{}},
+		"clients_20261017_8_tls_client_auth.sql":    &bintree{sqlClients_20261017_8_tls_client_authSql, map[string]*bintree{}},
+		"clients_20261017_9_auth_attempts.sql":      &bintree{sqlClients_20261017_9_auth_attemptsSql, map[string]*bintree{}},
+		"clients_20261018_1_encrypted_secret.sql":   &bintree{sqlClients_20261018_1_encrypted_secretSql, map[string]*bintree{}},
+		"clients_20261018_2_secret_pepper.sql":      &bintree{sqlClients_20261018_2_secret_pepperSql, map[string]*bintree{}},
+		"clients_20261018_3_secret_fingerprint.sql": &bintree{sqlClients_20261018_3_secret_fingerprintSql, map[string]*bintree{}},
+		"clients_20261018_4_secret_rotation.sql":    &bintree{sqlClients_20261018_4_secret_rotationSql, map[string]*bintree{}},
+		"clients_20261018_5_cascade_delete.sql":     &bintree{sqlClients_20261018_5_cascade_deleteSql, map[string]*bintree{}},
+		"clients_20261018_6_version.sql":            &bintree{sqlClients_20261018_6_versionSql, map[string]*bintree{}},
 	}},
 }}
 
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"time"
 
 	"darlinggo.co/pan"
 	"yall.in"
@@ -84,6 +85,49 @@ func (s Storer) Get(ctx context.Context, id string) (clients.Client, error) {
 	return fromPostgres(client), nil
 }
 
+// ListClients returns a page of the clients in the database that match the
+// filters in opts, sorted by their created_at column and then their id
+// column. If there are more clients after the page, a cursor to pass in
+// opts to retrieve them is returned. If opts has a cursor that can't be
+// parsed, a clients.ErrInvalidCursor error is returned.
+func (s Storer) ListClients(ctx context.Context, opts clients.ListClientsOptions) ([]clients.Client, string, error) {
+	var cursor *clients.ClientCursor
+	if opts.Cursor != "" {
+		parsed, err := clients.ParseClientCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor = &parsed
+	}
+	query := listClientsSQL(ctx, opts, cursor)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, "", err
+	}
+	defer closeRows(ctx, rows)
+	var list []clients.Client
+	for rows.Next() {
+		var client Client
+		err = pan.Unmarshal(rows, &client)
+		if err != nil {
+			return nil, "", err
+		}
+		list = append(list, fromPostgres(client))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(list) <= opts.PageSize() {
+		return list, "", nil
+	}
+	list = list[:opts.PageSize()]
+	return list, clients.CursorFor(list[len(list)-1]).String(), nil
+}
+
 // ListRedirectURIs finds all the clients.RedirectURIs in the PostgreSQL
 // database that have a client_id column that matches the passed clientID. If
 // there are none, an empty slice and a nil error are returned.
@@ -115,43 +159,130 @@ func (s Storer) ListRedirectURIs(ctx context.Context, clientID string) ([]client
 }
 
 // Update applies the passed clients.Change to the clients.Client in the
-// database with an id column matching the passed id. If row matches, no error
-// is returned.
-func (s Storer) Update(ctx context.Context, id string, change clients.Change) error {
-	if change.IsEmpty() {
+// database with an id column matching the passed id, incrementing its
+// version column. If the version column doesn't match the passed version, a
+// clients.ErrVersionConflict error is returned. If no row matches the id, a
+// clients.ErrClientNotFound error is returned. An empty clients.Change only
+// increments the version column.
+func (s Storer) Update(ctx context.Context, id string, version int64, change clients.Change) error {
+	query := updateSQL(ctx, id, version, change)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(queryStr, query.Args()...)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated > 0 {
 		return nil
 	}
-	query := updateSQL(ctx, id, change)
+	return s.updateMissError(ctx, id)
+}
+
+// ResetSecret applies the passed clients.Change to the clients.Client in
+// the database with an id column matching the passed id, like Update, and
+// inserts the passed clients.Secrets, like AddSecrets, in a single
+// transaction. If either fails, neither is stored.
+func (s Storer) ResetSecret(ctx context.Context, id string, version int64, change clients.Change, previous []clients.Secret) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // rolling back after committing fails, and there's nothing to do if it fails otherwise
+	query := updateSQL(ctx, id, version, change)
 	queryStr, err := query.PostgreSQLString()
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(queryStr, query.Args()...)
+	res, err := tx.ExecContext(ctx, queryStr, query.Args()...)
 	if err != nil {
 		return err
 	}
-	return nil
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated < 1 {
+		err = tx.Rollback()
+		if err != nil {
+			return err
+		}
+		return s.updateMissError(ctx, id)
+	}
+	if len(previous) > 0 {
+		pgSecrets := make([]Secret, 0, len(previous))
+		for _, secret := range previous {
+			pgSecrets = append(pgSecrets, secretToPostgres(secret))
+		}
+		query = addSecretsSQL(ctx, pgSecrets)
+		queryStr, err = query.PostgreSQLString()
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, queryStr, query.Args()...)
+		if err != nil {
+			return addSecretsError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// updateMissError returns the error for an update to the client with the
+// passed id that didn't match any rows, either because the client doesn't
+// exist or because it's been updated since the expected version.
+func (s Storer) updateMissError(ctx context.Context, id string) error {
+	_, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return clients.ErrVersionConflict
 }
 
 // Delete removes any rows with an id column matching the passed id from the
-// clients table in the database. If no rows match, no error is returned.
-func (s Storer) Delete(ctx context.Context, id string) error {
-	query := deleteSQL(ctx, id)
+// clients table in the database. The client's redirect URIs, scopes,
+// secrets, and keys are removed in the same statement by the foreign keys
+// referencing the clients table, which cascade deletes. If the version column
+// doesn't match the passed version, nothing is removed and a
+// clients.ErrVersionConflict error is returned. If no rows match the id, no
+// error is returned.
+func (s Storer) Delete(ctx context.Context, id string, version int64) error {
+	query := deleteSQL(ctx, id, version)
 	queryStr, err := query.PostgreSQLString()
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(queryStr, query.Args()...)
+	res, err := s.db.Exec(queryStr, query.Args()...)
 	if err != nil {
 		return err
 	}
-	return nil
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		return nil
+	}
+	// nothing was deleted, either because the client doesn't exist or
+	// because it's been updated since version
+	_, err = s.Get(ctx, id)
+	if errors.Is(err, clients.ErrClientNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return clients.ErrVersionConflict
 }
 
 // AddRedirectURIs inserts a group of clients.RedirectURIs into the database.
-// The clients.RedirectURIs do not need to be for the same clients.Client, and
-// no validation is done that the clients.RedirectURIs are being associated
-// with a clients.Client that exists. If the ID of any clients.RedirectURI is
+// The clients.RedirectURIs do not need to be for the same clients.Client. If
+// any clients.RedirectURI is for a clients.Client that doesn't exist, a
+// clients.ErrClientNotFound error is returned. If the ID of any clients.RedirectURI is
 // already in the database, a clients.RedirectURIAlreadyExistsError with the ID
 // property set is returned. If the URI of any clients.RedirectURI is already
 // in the database, a clients.RedirectURIAlreadyExistsError is returned with
@@ -171,6 +302,9 @@ func (s Storer) AddRedirectURIs(ctx context.Context, uris []clients.RedirectURI)
 	if !errors.As(err, &pqErr) {
 		return err
 	}
+	if pqErr.Constraint == "redirect_uris_client_id_fkey" {
+		return clients.ErrClientNotFound
+	}
 	redErr := clients.RedirectURIAlreadyExistsError{
 		Err: pqErr,
 	}
@@ -216,6 +350,467 @@ func (s Storer) RemoveRedirectURIs(ctx context.Context, ids []string) error {
 	return nil
 }
 
+// ListScopes finds all the clients.Scopes in the PostgreSQL database that
+// have a client_id column that matches the passed clientID. If there are
+// none, an empty slice and a nil error are returned.
+func (s Storer) ListScopes(ctx context.Context, clientID string) ([]clients.Scope, error) {
+	query := listScopesSQL(ctx, clientID)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // it's closed, it's just not picking up the closeRows helper
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	var results []clients.Scope
+	for rows.Next() {
+		var scope Scope
+		err = pan.Unmarshal(rows, &scope)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, scopeFromPostgres(scope))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	clients.ScopesByID(results)
+	return results, nil
+}
+
+// AddScopes inserts a group of clients.Scopes into the database. The
+// clients.Scopes do not need to be for the same clients.Client. If any
+// clients.Scope is for a clients.Client that doesn't exist, a
+// clients.ErrClientNotFound error is returned. If any clients.Scope is already allowed for its
+// clients.Client, a clients.ScopeAlreadyExistsError is returned.
+func (s Storer) AddScopes(ctx context.Context, scopes []clients.Scope) error {
+	if len(scopes) < 1 {
+		return nil
+	}
+	pgScopes := make([]Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		pgScopes = append(pgScopes, scopeToPostgres(scope))
+	}
+	query := addScopesSQL(ctx, pgScopes)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	if pqErr.Constraint == "client_scopes_client_id_fkey" {
+		return clients.ErrClientNotFound
+	}
+	if pqErr.Constraint != "client_scopes_pkey" {
+		return err
+	}
+	scopeErr := clients.ScopeAlreadyExistsError{
+		Err: pqErr,
+	}
+	matches := redirectURIValueRegex.FindStringSubmatch(pqErr.Detail)
+	if len(matches) < redirectURIValueRegexGroups {
+		yall.FromContext(ctx).WithError(err).WithField("matches", len(matches)).Error("unexpected number of scope constraint error matches")
+		return scopeErr
+	}
+	values := strings.SplitN(matches[2], ",", 2)              //nolint:gomnd // the primary key has two columns
+	if matches[1] != "client_id, scope" || len(values) != 2 { //nolint:gomnd // the primary key has two columns
+		yall.FromContext(ctx).WithError(err).WithField("columns", matches[1]).Error("unexpected columns for scope constraint error")
+		return scopeErr
+	}
+	scopeErr.ClientID = strings.TrimSpace(values[0])
+	scopeErr.ID = strings.TrimSpace(values[1])
+	return scopeErr
+}
+
+// RemoveScopes deletes the scopes with the passed IDs for the client with
+// the passed clientID from the database. If an ID is not found, it is
+// ignored.
+func (s Storer) RemoveScopes(ctx context.Context, clientID string, ids []string) error {
+	if len(ids) < 1 {
+		return nil
+	}
+	query := removeScopesSQL(ctx, clientID, ids)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListSecrets finds all the clients.Secrets in the PostgreSQL database that
+// have a client_id column that matches the passed clientID. If there are
+// none, an empty slice and a nil error are returned.
+func (s Storer) ListSecrets(ctx context.Context, clientID string) ([]clients.Secret, error) {
+	query := listSecretsSQL(ctx, clientID)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // it's closed, it's just not picking up the closeRows helper
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	var results []clients.Secret
+	for rows.Next() {
+		var secret Secret
+		err = pan.Unmarshal(rows, &secret)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, secretFromPostgres(secret))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	clients.SecretsByCreatedAt(results)
+	return results, nil
+}
+
+// AddSecrets inserts a group of clients.Secrets into the database. The
+// clients.Secrets do not need to be for the same clients.Client. If any
+// clients.Secret is for a clients.Client that doesn't exist, a
+// clients.ErrClientNotFound error is returned. If any clients.Secret has the same ID as one
+// already in the database, a clients.ErrSecretAlreadyExists error is
+// returned.
+func (s Storer) AddSecrets(ctx context.Context, secrets []clients.Secret) error {
+	if len(secrets) < 1 {
+		return nil
+	}
+	pgSecrets := make([]Secret, 0, len(secrets))
+	for _, secret := range secrets {
+		pgSecrets = append(pgSecrets, secretToPostgres(secret))
+	}
+	query := addSecretsSQL(ctx, pgSecrets)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	return addSecretsError(err)
+}
+
+// addSecretsError maps the constraint violations inserting secrets can
+// cause to the errors AddSecrets is documented to return.
+func addSecretsError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Constraint {
+		case "client_secrets_pkey":
+			return clients.ErrSecretAlreadyExists
+		case "client_secrets_client_id_fkey":
+			return clients.ErrClientNotFound
+		}
+	}
+	return err
+}
+
+// RemoveSecrets deletes the secrets with the passed IDs for the client with
+// the passed clientID from the database. If an ID is not found, it is
+// ignored.
+func (s Storer) RemoveSecrets(ctx context.Context, clientID string, ids []string) error {
+	if len(ids) < 1 {
+		return nil
+	}
+	query := removeSecretsSQL(ctx, clientID, ids)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UseSecret sets the last_used_at column of the secret with the passed id
+// for the client with the passed clientID to usedAt. If no such secret
+// exists, a clients.ErrSecretNotFound error is returned.
+func (s Storer) UseSecret(ctx context.Context, clientID, id string, usedAt time.Time) error {
+	query := useSecretSQL(ctx, clientID, id, usedAt)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(queryStr, query.Args()...)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows < 1 {
+		return clients.ErrSecretNotFound
+	}
+	return nil
+}
+
+// FindSecretOwner returns the clients.SecretOwner of the secret with the
+// passed fingerprint, looking first for a client with a matching
+// secret_fingerprint column, then for a secret with a matching
+// secret_fingerprint column. If neither can be found, a
+// clients.ErrSecretNotFound error is returned.
+func (s Storer) FindSecretOwner(ctx context.Context, fingerprint string) (clients.SecretOwner, error) {
+	if fingerprint == "" {
+		return clients.SecretOwner{}, clients.ErrSecretNotFound
+	}
+	var owner clients.SecretOwner
+	query := findClientSecretOwnerSQL(ctx, fingerprint)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return clients.SecretOwner{}, err
+	}
+	err = s.db.QueryRowContext(ctx, queryStr, query.Args()...).Scan(&owner.ClientID)
+	if err == nil {
+		return owner, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return clients.SecretOwner{}, err
+	}
+	query = findSecretOwnerSQL(ctx, fingerprint)
+	queryStr, err = query.PostgreSQLString()
+	if err != nil {
+		return clients.SecretOwner{}, err
+	}
+	err = s.db.QueryRowContext(ctx, queryStr, query.Args()...).Scan(&owner.ClientID, &owner.SecretID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return clients.SecretOwner{}, clients.ErrSecretNotFound
+	}
+	if err != nil {
+		return clients.SecretOwner{}, err
+	}
+	return owner, nil
+}
+
+// ListKeys finds all the clients.Keys in the PostgreSQL database that have a
+// client_id column that matches the passed clientID. If there are none, an
+// empty slice and a nil error are returned.
+func (s Storer) ListKeys(ctx context.Context, clientID string) ([]clients.Key, error) {
+	query := listKeysSQL(ctx, clientID)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // it's closed, it's just not picking up the closeRows helper
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	var results []clients.Key
+	for rows.Next() {
+		var key Key
+		err = pan.Unmarshal(rows, &key)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, keyFromPostgres(key))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	clients.KeysByID(results)
+	return results, nil
+}
+
+// AddKeys inserts a group of clients.Keys into the database. The
+// clients.Keys do not need to be for the same clients.Client. If any
+// clients.Key is for a clients.Client that doesn't exist, a
+// clients.ErrClientNotFound error is returned. If any clients.Key is already registered for
+// its clients.Client, a clients.KeyAlreadyExistsError is returned.
+func (s Storer) AddKeys(ctx context.Context, keys []clients.Key) error {
+	if len(keys) < 1 {
+		return nil
+	}
+	pgKeys := make([]Key, 0, len(keys))
+	for _, key := range keys {
+		pgKeys = append(pgKeys, keyToPostgres(key))
+	}
+	query := addKeysSQL(ctx, pgKeys)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	if pqErr.Constraint == "client_keys_client_id_fkey" {
+		return clients.ErrClientNotFound
+	}
+	if pqErr.Constraint != "client_keys_pkey" {
+		return err
+	}
+	keyErr := clients.KeyAlreadyExistsError{
+		Err: pqErr,
+	}
+	matches := redirectURIValueRegex.FindStringSubmatch(pqErr.Detail)
+	if len(matches) < redirectURIValueRegexGroups {
+		yall.FromContext(ctx).WithError(err).WithField("matches", len(matches)).Error("unexpected number of key constraint error matches")
+		return keyErr
+	}
+	values := strings.SplitN(matches[2], ",", 2)            //nolint:gomnd // the primary key has two columns
+	if matches[1] != "client_id, kid" || len(values) != 2 { //nolint:gomnd // the primary key has two columns
+		yall.FromContext(ctx).WithError(err).WithField("columns", matches[1]).Error("unexpected columns for key constraint error")
+		return keyErr
+	}
+	keyErr.ClientID = strings.TrimSpace(values[0])
+	keyErr.ID = strings.TrimSpace(values[1])
+	return keyErr
+}
+
+// RemoveKeys deletes the keys with the passed IDs for the client with the
+// passed clientID from the database. If an ID is not found, it is ignored.
+func (s Storer) RemoveKeys(ctx context.Context, clientID string, ids []string) error {
+	if len(ids) < 1 {
+		return nil
+	}
+	query := removeKeysSQL(ctx, clientID, ids)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAttempts retrieves the clients.Attempts recorded for key. If none have
+// been recorded, the zero value is returned. It, RecordFailure, SetLockout,
+// and ResetAttempts make Storer usable as a clients.AttemptStore.
+func (s Storer) GetAttempts(ctx context.Context, key string) (clients.Attempts, error) {
+	query := getAttemptsSQL(ctx, key)
+	return s.queryAttempts(ctx, query)
+}
+
+// RecordFailure records a failed authentication attempt for key, forgetting
+// earlier failures if they happened more than window before at, and
+// returns the updated clients.Attempts.
+func (s Storer) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (clients.Attempts, error) {
+	query := recordFailureSQL(ctx, key, at, window)
+	return s.queryAttempts(ctx, query)
+}
+
+func (s Storer) queryAttempts(ctx context.Context, query *pan.Query) (clients.Attempts, error) {
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return clients.Attempts{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return clients.Attempts{}, err
+	}
+	defer closeRows(ctx, rows)
+	var attempts Attempts
+	for rows.Next() {
+		err = pan.Unmarshal(rows, &attempts)
+		if err != nil {
+			return clients.Attempts{}, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return clients.Attempts{}, err
+	}
+	return attemptsFromPostgres(attempts), nil
+}
+
+// SetLockout sets the locked_until column for key to until.
+func (s Storer) SetLockout(ctx context.Context, key string, until time.Time) error {
+	query := setLockoutSQL(ctx, key, until)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	return err
+}
+
+// ResetAttempts deletes the failed authentication attempts recorded for
+// key.
+func (s Storer) ResetAttempts(ctx context.Context, key string) error {
+	query := resetAttemptsSQL(ctx, key)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(queryStr, query.Args()...)
+	return err
+}
+
+// CountSecretSchemes returns the number of rows in the clients table using
+// each value of the secret_scheme column, keyed by the scheme. Only rows for
+// clients whose HasSecret method would return true are counted.
+func (s Storer) CountSecretSchemes(ctx context.Context) (map[string]int64, error) {
+	query := countSecretSchemesSQL(ctx)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	counts := map[string]int64{}
+	for rows.Next() {
+		var scheme string
+		var count int64
+		err = rows.Scan(&scheme, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[scheme] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// ListClientsDueForRotation returns the clients in the database whose
+// HasSecret method would return true and either have a secret_rotated_at column before rotatedBefore,
+// or a secret_expires_at column before expiresBefore. Clients with a NULL
+// secret_rotated_at column are always returned. The clients are sorted by
+// their secret_rotated_at column, oldest first, then by their id column.
+func (s Storer) ListClientsDueForRotation(ctx context.Context, rotatedBefore, expiresBefore time.Time) ([]clients.Client, error) {
+	query := listClientsDueForRotationSQL(ctx, rotatedBefore, expiresBefore)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	var due []clients.Client
+	for rows.Next() {
+		var client Client
+		err = pan.Unmarshal(rows, &client)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, fromPostgres(client))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
 func closeRows(ctx context.Context, rows *sql.Rows) {
 	if err := rows.Close(); err != nil {
 		yall.FromContext(ctx).WithError(err).Error("failed to close rows")
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+// Scope is a representation of the clients.Scope type that is suitable to be
+// stored in a PostgreSQL database.
+type Scope struct {
+	ID          string    `sql_column:"scope"`
+	ClientID    string    `sql_column:"client_id"`
+	IsDefault   bool      `sql_column:"is_default"`
+	CreatedAt   time.Time `sql_column:"created_at"`
+	CreatedBy   string    `sql_column:"created_by"`
+	CreatedByIP string    `sql_column:"created_by_ip"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (Scope) GetSQLTableName() string {
+	return "client_scopes"
+}
+
+func scopeFromPostgres(scope Scope) clients.Scope {
+	return clients.Scope{
+		ID:          scope.ID,
+		ClientID:    scope.ClientID,
+		IsDefault:   scope.IsDefault,
+		CreatedAt:   scope.CreatedAt,
+		CreatedBy:   scope.CreatedBy,
+		CreatedByIP: scope.CreatedByIP,
+	}
+}
+
+func scopeToPostgres(scope clients.Scope) Scope {
+	return Scope{
+		ID:          scope.ID,
+		ClientID:    scope.ClientID,
+		IsDefault:   scope.IsDefault,
+		CreatedAt:   scope.CreatedAt,
+		CreatedBy:   scope.CreatedBy,
+		CreatedByIP: scope.CreatedByIP,
+	}
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+
+	"lockbox.dev/clients"
+)
+
+// Secret is a representation of the clients.Secret type that is suitable to
+// be stored in a PostgreSQL database.
+type Secret struct {
+	ID          string      `sql_column:"id"`
+	ClientID    string      `sql_column:"client_id"`
+	Hash        string      `sql_column:"secret_hash"`
+	Scheme      string      `sql_column:"secret_scheme"`
+	PepperID    string      `sql_column:"secret_pepper_id"`
+	Fingerprint string      `sql_column:"secret_fingerprint"`
+	CreatedAt   time.Time   `sql_column:"created_at"`
+	ExpiresAt   pq.NullTime `sql_column:"expires_at"`
+	LastUsedAt  pq.NullTime `sql_column:"last_used_at"`
+	CreatedBy   string      `sql_column:"created_by"`
+	CreatedByIP string      `sql_column:"created_by_ip"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (Secret) GetSQLTableName() string {
+	return "client_secrets"
+}
+
+func secretFromPostgres(secret Secret) clients.Secret {
+	return clients.Secret{
+		ID:          secret.ID,
+		ClientID:    secret.ClientID,
+		Hash:        secret.Hash,
+		Scheme:      secret.Scheme,
+		PepperID:    secret.PepperID,
+		Fingerprint: secret.Fingerprint,
+		CreatedAt:   secret.CreatedAt,
+		ExpiresAt:   fromNullTime(secret.ExpiresAt),
+		LastUsedAt:  fromNullTime(secret.LastUsedAt),
+		CreatedBy:   secret.CreatedBy,
+		CreatedByIP: secret.CreatedByIP,
+	}
+}
+
+func secretToPostgres(secret clients.Secret) Secret {
+	return Secret{
+		ID:          secret.ID,
+		ClientID:    secret.ClientID,
+		Hash:        secret.Hash,
+		Scheme:      secret.Scheme,
+		PepperID:    secret.PepperID,
+		Fingerprint: secret.Fingerprint,
+		CreatedAt:   secret.CreatedAt,
+		ExpiresAt:   toNullTime(secret.ExpiresAt),
+		LastUsedAt:  toNullTime(secret.LastUsedAt),
+		CreatedBy:   secret.CreatedBy,
+		CreatedByIP: secret.CreatedByIP,
+	}
+}
+
+// toNullTime converts a time.Time to a pq.NullTime, storing the zero value
+// as NULL.
+func toNullTime(in time.Time) pq.NullTime {
+	if in.IsZero() {
+		return pq.NullTime{}
+	}
+	return pq.NullTime{Time: in, Valid: true}
+}
+
+// fromNullTime converts a pq.NullTime to a time.Time, returning the zero
+// value for NULL.
+func fromNullTime(in pq.NullTime) time.Time {
+	if !in.Valid {
+		return time.Time{}
+	}
+	return in.Time
+}
@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"darlinggo.co/pan"
 
@@ -20,6 +22,51 @@ func getSQL(_ context.Context, id string) *pan.Query {
 	return q.Flush(" ")
 }
 
+// likeEscaper escapes the characters that are special in LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func listClientsSQL(_ context.Context, opts clients.ListClientsOptions, cursor *clients.ClientCursor) *pan.Query {
+	var client Client
+	createdAt, id := pan.Column(client, "CreatedAt"), pan.Column(client, "ID")
+	q := pan.New("SELECT " + pan.Columns(client).String() + " FROM " + pan.Table(client))
+	var conditions int
+	where := func() {
+		if conditions == 0 {
+			q.Where()
+		}
+		conditions++
+	}
+	if opts.CreatedBy != "" {
+		where()
+		q.Comparison(client, "CreatedBy", "=", opts.CreatedBy)
+	}
+	if opts.Confidential != nil {
+		where()
+		q.Comparison(client, "Confidential", "=", *opts.Confidential)
+	}
+	if opts.NamePrefix != "" {
+		where()
+		q.Comparison(client, "Name", "LIKE", likeEscaper.Replace(opts.NamePrefix)+"%")
+	}
+	if !opts.CreatedSince.IsZero() {
+		where()
+		q.Comparison(client, "CreatedAt", ">=", opts.CreatedSince)
+	}
+	if !opts.CreatedBefore.IsZero() {
+		where()
+		q.Comparison(client, "CreatedAt", "<", opts.CreatedBefore)
+	}
+	if cursor != nil {
+		where()
+		q.Expression("("+createdAt+", "+id+") > (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+	q.Flush(" AND ")
+	q.OrderBy(createdAt + ", " + id)
+	// one extra, to know whether there's another page
+	q.Limit(int64(opts.PageSize() + 1))
+	return q.Flush(" ")
+}
+
 func listRedirectURIsSQL(_ context.Context, clientID string) *pan.Query {
 	var redirectURI RedirectURI
 	q := pan.New("SELECT " + pan.Columns(redirectURI).String() + " FROM " + pan.Table(redirectURI))
@@ -29,7 +76,7 @@ func listRedirectURIsSQL(_ context.Context, clientID string) *pan.Query {
 	return q.Flush(" ")
 }
 
-func updateSQL(_ context.Context, id string, change clients.Change) *pan.Query {
+func updateSQL(_ context.Context, id string, version int64, change clients.Change) *pan.Query {
 	var client Client
 	query := pan.New("UPDATE " + pan.Table(client) + " SET ")
 	if change.Name != nil {
@@ -41,18 +88,100 @@ func updateSQL(_ context.Context, id string, change clients.Change) *pan.Query {
 	if change.SecretScheme != nil {
 		query.Assign(client, "SecretScheme", *change.SecretScheme)
 	}
+	if change.SecretPepperID != nil {
+		query.Assign(client, "SecretPepperID", *change.SecretPepperID)
+	}
+	if change.SecretFingerprint != nil {
+		query.Assign(client, "SecretFingerprint", *change.SecretFingerprint)
+	}
+	if change.EncryptedSecret != nil {
+		query.Assign(client, "EncryptedSecret", *change.EncryptedSecret)
+	}
+	if change.SecretRotatedAt != nil {
+		query.Assign(client, "SecretRotatedAt", toNullTime(*change.SecretRotatedAt))
+	}
+	if change.SecretExpiresAt != nil {
+		query.Assign(client, "SecretExpiresAt", toNullTime(*change.SecretExpiresAt))
+	}
+	if change.GrantTypes != nil {
+		query.Assign(client, "GrantTypes", toStringArray(*change.GrantTypes))
+	}
+	if change.ResponseTypes != nil {
+		query.Assign(client, "ResponseTypes", toStringArray(*change.ResponseTypes))
+	}
+	if change.TokenEndpointAuthMethod != nil {
+		query.Assign(client, "TokenEndpointAuthMethod", *change.TokenEndpointAuthMethod)
+	}
+	if change.JWKSURI != nil {
+		query.Assign(client, "JWKSURI", *change.JWKSURI)
+	}
+	if change.TLSClientAuthSubjectDN != nil {
+		query.Assign(client, "TLSClientAuthSubjectDN", *change.TLSClientAuthSubjectDN)
+	}
+	if change.TLSClientAuthSANDNS != nil {
+		query.Assign(client, "TLSClientAuthSANDNS", *change.TLSClientAuthSANDNS)
+	}
+	if change.TLSClientAuthSANURI != nil {
+		query.Assign(client, "TLSClientAuthSANURI", *change.TLSClientAuthSANURI)
+	}
+	if change.TLSClientAuthSANIP != nil {
+		query.Assign(client, "TLSClientAuthSANIP", *change.TLSClientAuthSANIP)
+	}
+	if change.TLSClientAuthSANEmail != nil {
+		query.Assign(client, "TLSClientAuthSANEmail", *change.TLSClientAuthSANEmail)
+	}
+	if change.TLSClientCertThumbprint != nil {
+		query.Assign(client, "TLSClientCertThumbprint", *change.TLSClientCertThumbprint)
+	}
+	if change.Status != nil {
+		query.Assign(client, "Status", *change.Status)
+	}
+	if change.StatusReason != nil {
+		query.Assign(client, "StatusReason", *change.StatusReason)
+	}
+	if change.StatusChangedAt != nil {
+		query.Assign(client, "StatusChangedAt", toNullTime(*change.StatusChangedAt))
+	}
+	if change.StatusChangedBy != nil {
+		query.Assign(client, "StatusChangedBy", *change.StatusChangedBy)
+	}
+	if change.ClientURI != nil {
+		query.Assign(client, "ClientURI", *change.ClientURI)
+	}
+	if change.LogoURI != nil {
+		query.Assign(client, "LogoURI", *change.LogoURI)
+	}
+	if change.PolicyURI != nil {
+		query.Assign(client, "PolicyURI", *change.PolicyURI)
+	}
+	if change.TOSURI != nil {
+		query.Assign(client, "TOSURI", *change.TOSURI)
+	}
+	if change.Contacts != nil {
+		query.Assign(client, "Contacts", toStringArray(*change.Contacts))
+	}
+	if change.SoftwareID != nil {
+		query.Assign(client, "SoftwareID", *change.SoftwareID)
+	}
+	if change.SoftwareVersion != nil {
+		query.Assign(client, "SoftwareVersion", *change.SoftwareVersion)
+	}
+	versionCol := pan.Column(client, "Version")
+	query.Expression(versionCol + " = " + versionCol + " + 1")
 	query.Flush(", ")
 	query.Where()
 	query.Comparison(client, "ID", "=", id)
-	return query.Flush(" ")
+	query.Comparison(client, "Version", "=", version)
+	return query.Flush(" AND ")
 }
 
-func deleteSQL(_ context.Context, id string) *pan.Query {
+func deleteSQL(_ context.Context, id string, version int64) *pan.Query {
 	var client Client
 	q := pan.New("DELETE FROM " + pan.Table(client))
 	q.Where()
 	q.Comparison(client, "ID", "=", id)
-	return q.Flush(" ")
+	q.Comparison(client, "Version", "=", version)
+	return q.Flush(" AND ")
 }
 
 func addRedirectURIsSQL(_ context.Context, uris []RedirectURI) *pan.Query {
@@ -74,3 +203,194 @@ func removeRedirectURIsSQL(_ context.Context, uris []string) *pan.Query {
 	query.In(uri, "ID", interfaces...)
 	return query.Flush(" ")
 }
+
+func countSecretSchemesSQL(_ context.Context) *pan.Query {
+	var client Client
+	q := pan.New("SELECT " + pan.Column(client, "SecretScheme") + ", COUNT(*) FROM " + pan.Table(client))
+	// clients without secrets don't have a scheme to migrate
+	q.Where()
+	whereHasSecret(q)
+	q.Flush(" AND ")
+	q.Expression("GROUP BY " + pan.Column(client, "SecretScheme"))
+	return q.Flush(" ")
+}
+
+func listClientsDueForRotationSQL(_ context.Context, rotatedBefore, expiresBefore time.Time) *pan.Query {
+	var client Client
+	rotatedAt := pan.Column(client, "SecretRotatedAt")
+	q := pan.New("SELECT " + pan.Columns(client).String() + " FROM " + pan.Table(client))
+	q.Where()
+	whereHasSecret(q)
+	q.Expression("("+rotatedAt+" IS NULL OR "+rotatedAt+" < ? OR "+pan.Column(client, "SecretExpiresAt")+" < ?)", rotatedBefore, expiresBefore)
+	q.Flush(" AND ")
+	q.OrderBy(rotatedAt + " NULLS FIRST, " + pan.Column(client, "ID"))
+	return q.Flush(" ")
+}
+
+// whereHasSecret adds the conditions matching clients that have a secret,
+// mirroring clients.Client.HasSecret, to q. Clients without an
+// authentication method set use the default for confidential clients,
+// which uses a secret.
+func whereHasSecret(q *pan.Query) {
+	var client Client
+	q.Comparison(client, "Confidential", "=", true)
+	q.Expression(pan.Column(client, "TokenEndpointAuthMethod")+" IN (?, ?, ?, ?)", "",
+		clients.AuthMethodClientSecretBasic, clients.AuthMethodClientSecretPost, clients.AuthMethodClientSecretJWT)
+	q.Comparison(client, "SecretHash", "<>", "")
+}
+
+func listScopesSQL(_ context.Context, clientID string) *pan.Query {
+	var scope Scope
+	q := pan.New("SELECT " + pan.Columns(scope).String() + " FROM " + pan.Table(scope))
+	q.Where()
+	q.Comparison(scope, "ClientID", "=", clientID)
+	q.OrderBy("scope")
+	return q.Flush(" ")
+}
+
+func addScopesSQL(_ context.Context, scopes []Scope) *pan.Query {
+	tableNamers := make([]pan.SQLTableNamer, 0, len(scopes))
+	for _, scope := range scopes {
+		tableNamers = append(tableNamers, scope)
+	}
+	return pan.Insert(tableNamers...)
+}
+
+func removeScopesSQL(_ context.Context, clientID string, ids []string) *pan.Query {
+	var scope Scope
+	query := pan.New("DELETE FROM " + pan.Table(scope))
+	query.Where()
+	query.Comparison(scope, "ClientID", "=", clientID)
+	interfaces := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		interfaces = append(interfaces, id)
+	}
+	query.In(scope, "ID", interfaces...)
+	return query.Flush(" AND ")
+}
+
+func listSecretsSQL(_ context.Context, clientID string) *pan.Query {
+	var secret Secret
+	q := pan.New("SELECT " + pan.Columns(secret).String() + " FROM " + pan.Table(secret))
+	q.Where()
+	q.Comparison(secret, "ClientID", "=", clientID)
+	q.OrderBy("created_at, id")
+	return q.Flush(" ")
+}
+
+func addSecretsSQL(_ context.Context, secrets []Secret) *pan.Query {
+	tableNamers := make([]pan.SQLTableNamer, 0, len(secrets))
+	for _, secret := range secrets {
+		tableNamers = append(tableNamers, secret)
+	}
+	return pan.Insert(tableNamers...)
+}
+
+func removeSecretsSQL(_ context.Context, clientID string, ids []string) *pan.Query {
+	var secret Secret
+	query := pan.New("DELETE FROM " + pan.Table(secret))
+	query.Where()
+	query.Comparison(secret, "ClientID", "=", clientID)
+	interfaces := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		interfaces = append(interfaces, id)
+	}
+	query.In(secret, "ID", interfaces...)
+	return query.Flush(" AND ")
+}
+
+func useSecretSQL(_ context.Context, clientID, id string, usedAt time.Time) *pan.Query {
+	var secret Secret
+	query := pan.New("UPDATE " + pan.Table(secret) + " SET ")
+	query.Assign(secret, "LastUsedAt", toNullTime(usedAt))
+	query.Flush(", ")
+	query.Where()
+	query.Comparison(secret, "ClientID", "=", clientID)
+	query.Comparison(secret, "ID", "=", id)
+	return query.Flush(" AND ")
+}
+
+func findClientSecretOwnerSQL(_ context.Context, fingerprint string) *pan.Query {
+	var client Client
+	q := pan.New("SELECT " + pan.Column(client, "ID") + " FROM " + pan.Table(client))
+	q.Where()
+	q.Comparison(client, "SecretFingerprint", "=", fingerprint)
+	q.Limit(1)
+	return q.Flush(" ")
+}
+
+func findSecretOwnerSQL(_ context.Context, fingerprint string) *pan.Query {
+	var secret Secret
+	q := pan.New("SELECT " + pan.Column(secret, "ClientID") + ", " + pan.Column(secret, "ID") + " FROM " + pan.Table(secret))
+	q.Where()
+	q.Comparison(secret, "Fingerprint", "=", fingerprint)
+	q.Limit(1)
+	return q.Flush(" ")
+}
+
+func listKeysSQL(_ context.Context, clientID string) *pan.Query {
+	var key Key
+	q := pan.New("SELECT " + pan.Columns(key).String() + " FROM " + pan.Table(key))
+	q.Where()
+	q.Comparison(key, "ClientID", "=", clientID)
+	q.OrderBy("kid")
+	return q.Flush(" ")
+}
+
+func addKeysSQL(_ context.Context, keys []Key) *pan.Query {
+	tableNamers := make([]pan.SQLTableNamer, 0, len(keys))
+	for _, key := range keys {
+		tableNamers = append(tableNamers, key)
+	}
+	return pan.Insert(tableNamers...)
+}
+
+func removeKeysSQL(_ context.Context, clientID string, ids []string) *pan.Query {
+	var key Key
+	query := pan.New("DELETE FROM " + pan.Table(key))
+	query.Where()
+	query.Comparison(key, "ClientID", "=", clientID)
+	interfaces := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		interfaces = append(interfaces, id)
+	}
+	query.In(key, "ID", interfaces...)
+	return query.Flush(" AND ")
+}
+
+func getAttemptsSQL(_ context.Context, key string) *pan.Query {
+	var attempts Attempts
+	q := pan.New("SELECT " + pan.Columns(attempts).String() + " FROM " + pan.Table(attempts))
+	q.Where()
+	q.Comparison(attempts, "Key", "=", key)
+	return q.Flush(" ")
+}
+
+func recordFailureSQL(_ context.Context, key string, at time.Time, window time.Duration) *pan.Query {
+	attempts := Attempts{Key: key, Failures: 1, LastFailureAt: at}
+	table := pan.Table(attempts)
+	query := pan.Insert(attempts)
+	// failures older than the window are forgotten, and counting starts
+	// again
+	query.Expression("ON CONFLICT (key) DO UPDATE SET failures = CASE WHEN "+table+".last_failure_at < ? THEN 1 ELSE "+table+".failures + 1 END, last_failure_at = EXCLUDED.last_failure_at", at.Add(-window))
+	query.Expression("RETURNING " + pan.Columns(attempts).String())
+	return query.Flush(" ")
+}
+
+func setLockoutSQL(_ context.Context, key string, until time.Time) *pan.Query {
+	var attempts Attempts
+	query := pan.New("UPDATE " + pan.Table(attempts) + " SET ")
+	query.Assign(attempts, "LockedUntil", toNullTime(until))
+	query.Flush(", ")
+	query.Where()
+	query.Comparison(attempts, "Key", "=", key)
+	return query.Flush(" ")
+}
+
+func resetAttemptsSQL(_ context.Context, key string) *pan.Query {
+	var attempts Attempts
+	query := pan.New("DELETE FROM " + pan.Table(attempts))
+	query.Where()
+	query.Comparison(attempts, "Key", "=", key)
+	return query.Flush(" ")
+}
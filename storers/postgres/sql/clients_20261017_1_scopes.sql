@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE client_scopes (
+	client_id VARCHAR(36) NOT NULL,
+	scope TEXT NOT NULL,
+	is_default BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMPTZ NOT NULL,
+	created_by VARCHAR(64) NOT NULL DEFAULT '',
+	created_by_ip VARCHAR(36) NOT NULL DEFAULT '',
+	PRIMARY KEY (client_id, scope)
+);
+
+-- +migrate Down
+DROP TABLE client_scopes;
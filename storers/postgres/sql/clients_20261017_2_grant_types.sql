@@ -0,0 +1,18 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN grant_types TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE clients ADD COLUMN response_types TEXT[] NOT NULL DEFAULT '{}';
+
+-- existing clients could use any flow, so keep allowing them to until
+-- they're updated
+UPDATE clients SET
+	grant_types = '{authorization_code,refresh_token,urn:ietf:params:oauth:grant-type:device_code,implicit}',
+	response_types = '{code,token}'
+WHERE confidential = false;
+UPDATE clients SET
+	grant_types = '{authorization_code,client_credentials,refresh_token,urn:ietf:params:oauth:grant-type:device_code,implicit}',
+	response_types = '{code,token}'
+WHERE confidential = true;
+
+-- +migrate Down
+ALTER TABLE clients DROP COLUMN response_types;
+ALTER TABLE clients DROP COLUMN grant_types;
@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE client_secrets (
+	id VARCHAR(36) PRIMARY KEY,
+	client_id VARCHAR(36) NOT NULL,
+	secret_hash TEXT NOT NULL,
+	secret_scheme VARCHAR(32) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	created_by VARCHAR(64) NOT NULL DEFAULT '',
+	created_by_ip VARCHAR(36) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX client_secrets_client_id ON client_secrets (client_id);
+
+-- +migrate Down
+DROP TABLE client_secrets;
@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE clients ADD COLUMN status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN status_changed_at TIMESTAMPTZ;
+ALTER TABLE clients ADD COLUMN status_changed_by VARCHAR(64) NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE clients DROP COLUMN status_changed_by;
+ALTER TABLE clients DROP COLUMN status_changed_at;
+ALTER TABLE clients DROP COLUMN status_reason;
+ALTER TABLE clients DROP COLUMN status;
@@ -0,0 +1,17 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN client_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN logo_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN policy_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN tos_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN contacts TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE clients ADD COLUMN software_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN software_version TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE clients DROP COLUMN software_version;
+ALTER TABLE clients DROP COLUMN software_id;
+ALTER TABLE clients DROP COLUMN contacts;
+ALTER TABLE clients DROP COLUMN tos_uri;
+ALTER TABLE clients DROP COLUMN policy_uri;
+ALTER TABLE clients DROP COLUMN logo_uri;
+ALTER TABLE clients DROP COLUMN client_uri;
@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN token_endpoint_auth_method VARCHAR(32) NOT NULL DEFAULT '';
+
+UPDATE clients SET token_endpoint_auth_method = 'client_secret_basic' WHERE confidential = true;
+UPDATE clients SET token_endpoint_auth_method = 'none' WHERE confidential = false;
+
+-- +migrate Down
+ALTER TABLE clients DROP COLUMN token_endpoint_auth_method;
@@ -0,0 +1,18 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN jwks_uri TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE client_keys (
+	client_id VARCHAR(36) NOT NULL,
+	kid TEXT NOT NULL,
+	kty VARCHAR(8) NOT NULL,
+	alg VARCHAR(16) NOT NULL,
+	jwk TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	created_by VARCHAR(64) NOT NULL DEFAULT '',
+	created_by_ip VARCHAR(36) NOT NULL DEFAULT '',
+	PRIMARY KEY (client_id, kid)
+);
+
+-- +migrate Down
+DROP TABLE client_keys;
+ALTER TABLE clients DROP COLUMN jwks_uri;
@@ -0,0 +1,15 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN tls_client_auth_subject_dn TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN tls_client_auth_san_dns TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN tls_client_auth_san_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN tls_client_auth_san_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN tls_client_auth_san_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN tls_client_cert_thumbprint TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE clients DROP COLUMN tls_client_cert_thumbprint;
+ALTER TABLE clients DROP COLUMN tls_client_auth_san_email;
+ALTER TABLE clients DROP COLUMN tls_client_auth_san_ip;
+ALTER TABLE clients DROP COLUMN tls_client_auth_san_uri;
+ALTER TABLE clients DROP COLUMN tls_client_auth_san_dns;
+ALTER TABLE clients DROP COLUMN tls_client_auth_subject_dn;
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE client_auth_attempts (
+	key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL,
+	last_failure_at TIMESTAMPTZ NOT NULL,
+	locked_until TIMESTAMPTZ
+);
+
+-- +migrate Down
+DROP TABLE client_auth_attempts;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN secret_pepper_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE client_secrets ADD COLUMN secret_pepper_id TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE client_secrets DROP COLUMN secret_pepper_id;
+ALTER TABLE clients DROP COLUMN secret_pepper_id;
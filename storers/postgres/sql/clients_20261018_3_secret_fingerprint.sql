@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN secret_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE client_secrets ADD COLUMN secret_fingerprint TEXT NOT NULL DEFAULT '';
+CREATE INDEX clients_secret_fingerprint ON clients (secret_fingerprint) WHERE secret_fingerprint <> '';
+CREATE INDEX client_secrets_secret_fingerprint ON client_secrets (secret_fingerprint) WHERE secret_fingerprint <> '';
+
+-- +migrate Down
+DROP INDEX client_secrets_secret_fingerprint;
+DROP INDEX clients_secret_fingerprint;
+ALTER TABLE client_secrets DROP COLUMN secret_fingerprint;
+ALTER TABLE clients DROP COLUMN secret_fingerprint;
@@ -0,0 +1,10 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN secret_rotated_at TIMESTAMPTZ;
+ALTER TABLE clients ADD COLUMN secret_expires_at TIMESTAMPTZ;
+UPDATE clients SET secret_rotated_at = created_at WHERE secret_hash <> '';
+CREATE INDEX clients_secret_rotated_at ON clients (secret_rotated_at) WHERE secret_hash <> '';
+
+-- +migrate Down
+DROP INDEX clients_secret_rotated_at;
+ALTER TABLE clients DROP COLUMN secret_expires_at;
+ALTER TABLE clients DROP COLUMN secret_rotated_at;
@@ -0,0 +1,17 @@
+-- +migrate Up
+DELETE FROM redirect_uris WHERE client_id NOT IN (SELECT id FROM clients);
+DELETE FROM client_scopes WHERE client_id NOT IN (SELECT id FROM clients);
+DELETE FROM client_secrets WHERE client_id NOT IN (SELECT id FROM clients);
+DELETE FROM client_keys WHERE client_id NOT IN (SELECT id FROM clients);
+CREATE INDEX redirect_uris_client_id ON redirect_uris (client_id);
+ALTER TABLE redirect_uris ADD CONSTRAINT redirect_uris_client_id_fkey FOREIGN KEY (client_id) REFERENCES clients (id) ON DELETE CASCADE;
+ALTER TABLE client_scopes ADD CONSTRAINT client_scopes_client_id_fkey FOREIGN KEY (client_id) REFERENCES clients (id) ON DELETE CASCADE;
+ALTER TABLE client_secrets ADD CONSTRAINT client_secrets_client_id_fkey FOREIGN KEY (client_id) REFERENCES clients (id) ON DELETE CASCADE;
+ALTER TABLE client_keys ADD CONSTRAINT client_keys_client_id_fkey FOREIGN KEY (client_id) REFERENCES clients (id) ON DELETE CASCADE;
+
+-- +migrate Down
+ALTER TABLE client_keys DROP CONSTRAINT client_keys_client_id_fkey;
+ALTER TABLE client_secrets DROP CONSTRAINT client_secrets_client_id_fkey;
+ALTER TABLE client_scopes DROP CONSTRAINT client_scopes_client_id_fkey;
+ALTER TABLE redirect_uris DROP CONSTRAINT redirect_uris_client_id_fkey;
+DROP INDEX redirect_uris_client_id;
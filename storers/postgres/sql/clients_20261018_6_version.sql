@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE clients ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE clients DROP COLUMN version;
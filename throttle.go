@@ -0,0 +1,253 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	yall "yall.in"
+)
+
+const (
+	// DefaultMaxClientFailures is the number of failed attempts a client
+	// ID may make before it's locked out, if
+	// ThrottledAuthenticator.MaxClientFailures isn't set.
+	DefaultMaxClientFailures = 5
+	// DefaultMaxIPFailures is the number of failed attempts an IP address
+	// may make before it's locked out, if
+	// ThrottledAuthenticator.MaxIPFailures isn't set. It's higher than
+	// DefaultMaxClientFailures because many clients may share an IP
+	// address.
+	DefaultMaxIPFailures = 20
+	// DefaultLockoutBase is how long the first lockout lasts if
+	// ThrottledAuthenticator.LockoutBase isn't set. Each further failure
+	// doubles it.
+	DefaultLockoutBase = time.Second
+	// DefaultMaxLockout is the longest a lockout lasts if
+	// ThrottledAuthenticator.MaxLockout isn't set.
+	DefaultMaxLockout = 15 * time.Minute
+	// DefaultFailureWindow is how long failures are remembered if
+	// ThrottledAuthenticator.FailureWindow isn't set.
+	DefaultFailureWindow = time.Hour
+)
+
+// ErrLockedOut is returned when a client ID or IP address has made too many
+// failed authentication attempts and is temporarily locked out.
+var ErrLockedOut = errors.New("too many failed authentication attempts")
+
+// LockoutError is returned when a client ID or IP address is locked out.
+// It wraps ErrLockedOut.
+type LockoutError struct {
+	ClientID string    // the client ID that is locked out, if it's the client ID
+	IP       string    // the IP address that is locked out, if it's the IP address
+	Until    time.Time // when the lockout ends
+}
+
+// Error fills the error interface for LockoutError.
+func (e LockoutError) Error() string {
+	if e.IP != "" {
+		return fmt.Sprintf("%s from %s, locked out until %s", ErrLockedOut, e.IP, e.Until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s for client %q, locked out until %s", ErrLockedOut, e.ClientID, e.Until.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrLockedOut, so errors.Is can be used to detect a
+// LockoutError.
+func (LockoutError) Unwrap() error {
+	return ErrLockedOut
+}
+
+// ThrottledAuthenticator wraps an Authenticator, protecting secrets from
+// being guessed by tracking failed attempts per client ID and per IP
+// address.
+//
+// Once a client ID or IP address has failed MaxClientFailures or
+// MaxIPFailures times within FailureWindow, every further failure locks it
+// out for an exponentially increasing time, starting at LockoutBase and
+// capped at MaxLockout. While locked out, attempts are rejected with a
+// LockoutError without checking the secret. A successful attempt resets
+// the client ID's failures, but not the IP address's, so attackers can't
+// reset their IP address's failures with a client they control.
+//
+// Attempts for unknown client IDs count against the IP address only, so
+// they can't be used to fill the AttemptStore.
+type ThrottledAuthenticator struct {
+	Authenticator Authenticator
+	Attempts      AttemptStore
+
+	// MaxClientFailures is the number of failures a client ID may have
+	// before it's locked out. If unset, DefaultMaxClientFailures is used.
+	MaxClientFailures int
+
+	// MaxIPFailures is the number of failures an IP address may have
+	// before it's locked out. If unset, DefaultMaxIPFailures is used.
+	MaxIPFailures int
+
+	// LockoutBase is how long the first lockout lasts. If unset,
+	// DefaultLockoutBase is used.
+	LockoutBase time.Duration
+
+	// MaxLockout is the longest a lockout lasts. If unset,
+	// DefaultMaxLockout is used.
+	MaxLockout time.Duration
+
+	// FailureWindow is how long failures are remembered for. If unset,
+	// DefaultFailureWindow is used.
+	FailureWindow time.Duration
+
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// AuthenticateSecret calls Authenticator.AuthenticateSecret, unless clientID
+// or ip is locked out, in which case a LockoutError is returned. ip is the
+// IP address the attempt came from; if it's empty, attempts are only
+// tracked per client ID.
+//
+// If the AttemptStore can't be checked, its error is returned and the
+// secret isn't checked. Failing to record an attempt is logged, but doesn't
+// change the result.
+func (t ThrottledAuthenticator) AuthenticateSecret(ctx context.Context, method, clientID, secret, ip string) (Client, error) {
+	now := t.now()
+	log := yall.FromContext(ctx).WithField("client_id", clientID).WithField("ip", ip)
+	for _, key := range t.keys(clientID, ip) {
+		attempts, err := t.Attempts.GetAttempts(ctx, key.key)
+		if err != nil {
+			return Client{}, err
+		}
+		if attempts.LockedUntil.After(now) {
+			log.WithField("locked_until", attempts.LockedUntil).Debug("rejected authentication attempt during lockout")
+			return Client{}, key.lockoutError(attempts.LockedUntil)
+		}
+	}
+	client, err := t.Authenticator.AuthenticateSecret(ctx, method, clientID, secret)
+	switch {
+	case err == nil:
+		resetErr := t.Attempts.ResetAttempts(ctx, attemptKeyClient(clientID))
+		if resetErr != nil {
+			log.WithError(resetErr).Error("error resetting failed authentication attempts")
+		}
+		return client, nil
+	case errors.Is(err, ErrIncorrectSecret):
+		for _, key := range t.keys(clientID, ip) {
+			t.recordFailure(ctx, key, now)
+		}
+	case errors.Is(err, ErrClientNotFound):
+		if ip != "" {
+			t.recordFailure(ctx, t.ipKey(ip), now)
+		}
+	}
+	return Client{}, err
+}
+
+// attemptKey is a key in the AttemptStore, along with what it identifies.
+type attemptKey struct {
+	key         string
+	clientID    string
+	ip          string
+	maxFailures int
+}
+
+func (k attemptKey) lockoutError(until time.Time) LockoutError {
+	return LockoutError{ClientID: k.clientID, IP: k.ip, Until: until}
+}
+
+func attemptKeyClient(clientID string) string {
+	return "client:" + clientID
+}
+
+func (t ThrottledAuthenticator) clientKey(clientID string) attemptKey {
+	return attemptKey{key: attemptKeyClient(clientID), clientID: clientID, maxFailures: t.maxClientFailures()}
+}
+
+func (t ThrottledAuthenticator) ipKey(ip string) attemptKey {
+	return attemptKey{key: "ip:" + ip, ip: ip, maxFailures: t.maxIPFailures()}
+}
+
+// keys returns the AttemptStore keys an attempt by clientID from ip is
+// tracked under.
+func (t ThrottledAuthenticator) keys(clientID, ip string) []attemptKey {
+	keys := []attemptKey{t.clientKey(clientID)}
+	if ip != "" {
+		keys = append(keys, t.ipKey(ip))
+	}
+	return keys
+}
+
+// recordFailure records a failed attempt for key, locking it out if it has
+// failed too many times.
+func (t ThrottledAuthenticator) recordFailure(ctx context.Context, key attemptKey, now time.Time) {
+	log := yall.FromContext(ctx).WithField("client_id", key.clientID).WithField("ip", key.ip)
+	attempts, err := t.Attempts.RecordFailure(ctx, key.key, now, t.failureWindow())
+	if err != nil {
+		log.WithError(err).Error("error recording failed authentication attempt")
+		return
+	}
+	if attempts.Failures < key.maxFailures {
+		return
+	}
+	until := now.Add(t.lockoutDuration(attempts.Failures - key.maxFailures))
+	err = t.Attempts.SetLockout(ctx, key.key, until)
+	if err != nil {
+		log.WithError(err).Error("error storing authentication lockout")
+		return
+	}
+	log.WithField("failures", attempts.Failures).WithField("locked_until", until).
+		Warn("locked out after too many failed authentication attempts")
+}
+
+// lockoutDuration returns how long to lock out for after excess failures
+// beyond the maximum allowed, doubling LockoutBase for each one.
+func (t ThrottledAuthenticator) lockoutDuration(excess int) time.Duration {
+	duration := t.lockoutBase()
+	for i := 0; i < excess && duration < t.maxLockout(); i++ {
+		duration *= 2
+	}
+	if duration > t.maxLockout() {
+		return t.maxLockout()
+	}
+	return duration
+}
+
+func (t ThrottledAuthenticator) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
+
+func (t ThrottledAuthenticator) maxClientFailures() int {
+	if t.MaxClientFailures > 0 {
+		return t.MaxClientFailures
+	}
+	return DefaultMaxClientFailures
+}
+
+func (t ThrottledAuthenticator) maxIPFailures() int {
+	if t.MaxIPFailures > 0 {
+		return t.MaxIPFailures
+	}
+	return DefaultMaxIPFailures
+}
+
+func (t ThrottledAuthenticator) lockoutBase() time.Duration {
+	if t.LockoutBase > 0 {
+		return t.LockoutBase
+	}
+	return DefaultLockoutBase
+}
+
+func (t ThrottledAuthenticator) maxLockout() time.Duration {
+	if t.MaxLockout > 0 {
+		return t.MaxLockout
+	}
+	return DefaultMaxLockout
+}
+
+func (t ThrottledAuthenticator) failureWindow() time.Duration {
+	if t.FailureWindow > 0 {
+		return t.FailureWindow
+	}
+	return DefaultFailureWindow
+}
@@ -0,0 +1,206 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/clients"
+)
+
+func testAttemptStore(t *testing.T, ctx context.Context, store clients.AttemptStore) {
+	t.Helper()
+
+	key := "client:" + uuidOrFail(t)
+	now := time.Now().Round(time.Millisecond)
+
+	attempts, err := store.GetAttempts(ctx, key)
+	if err != nil {
+		t.Fatalf("Error getting attempts: %s", err)
+	}
+	if attempts.Failures != 0 || !attempts.LockedUntil.IsZero() {
+		t.Errorf("Expected no attempts, got %+v", attempts)
+	}
+	for i := 1; i <= 3; i++ {
+		attempts, err = store.RecordFailure(ctx, key, now, time.Hour)
+		if err != nil {
+			t.Fatalf("Error recording failure: %s", err)
+		}
+		if attempts.Failures != i {
+			t.Errorf("Expected %d failures, got %d", i, attempts.Failures)
+		}
+	}
+	err = store.SetLockout(ctx, key, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Error setting lockout: %s", err)
+	}
+	attempts, err = store.GetAttempts(ctx, key)
+	if err != nil {
+		t.Fatalf("Error getting attempts: %s", err)
+	}
+	if attempts.Failures != 3 || !attempts.LockedUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected 3 failures locked until %s, got %+v", now.Add(time.Minute), attempts)
+	}
+
+	// failures outside the window are forgotten
+	attempts, err = store.RecordFailure(ctx, key, now.Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("Error recording failure: %s", err)
+	}
+	if attempts.Failures != 1 {
+		t.Errorf("Expected failures to restart at 1 after the window, got %d", attempts.Failures)
+	}
+
+	err = store.ResetAttempts(ctx, key)
+	if err != nil {
+		t.Fatalf("Error resetting attempts: %s", err)
+	}
+	attempts, err = store.GetAttempts(ctx, key)
+	if err != nil {
+		t.Fatalf("Error getting attempts: %s", err)
+	}
+	if attempts.Failures != 0 || !attempts.LockedUntil.IsZero() {
+		t.Errorf("Expected attempts to be reset, got %+v", attempts)
+	}
+}
+
+func TestMemoryAttemptStore(t *testing.T) {
+	t.Parallel()
+
+	testAttemptStore(t, context.Background(), &clients.MemoryAttemptStore{})
+}
+
+func TestStorerAttemptStore(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		store, ok := storer.(clients.AttemptStore)
+		if !ok {
+			t.Skip("Storer doesn't implement AttemptStore")
+		}
+		testAttemptStore(t, ctx, store)
+	})
+}
+
+func TestThrottledAuthenticatorLocksOut(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		client := clients.Client{
+			ID:           uuidOrFail(t),
+			Name:         "Test Client",
+			Confidential: true,
+			CreatedAt:    time.Now().Round(time.Millisecond),
+			CreatedBy:    "test",
+			CreatedByIP:  "127.0.0.1",
+		}
+		ch, err := clients.ChangeSecret([]byte("test secret"))
+		if err != nil {
+			t.Fatalf("Error generating client secret: %s", err)
+		}
+		client = clients.Apply(ch, client)
+		err = storer.Create(ctx, client)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err)
+		}
+
+		now := time.Now()
+		authenticator := clients.ThrottledAuthenticator{
+			Authenticator:     clients.Authenticator{Storer: storer},
+			Attempts:          &clients.MemoryAttemptStore{},
+			MaxClientFailures: 3,
+			MaxIPFailures:     10,
+			LockoutBase:       time.Second,
+			MaxLockout:        4 * time.Second,
+			Now:               func() time.Time { return now },
+		}
+		authenticate := func(secret, ip string) error {
+			_, err := authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, client.ID, secret, ip)
+			return err
+		}
+
+		for i := 0; i < 2; i++ {
+			err = authenticate("wrong secret", "192.0.2.1")
+			if !errors.Is(err, clients.ErrIncorrectSecret) {
+				t.Fatalf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+			}
+		}
+		// the third failure triggers a lockout
+		err = authenticate("wrong secret", "192.0.2.1")
+		if !errors.Is(err, clients.ErrIncorrectSecret) {
+			t.Fatalf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+		}
+		// even the correct secret, from another IP, is rejected while
+		// the client is locked out
+		err = authenticate("test secret", "192.0.2.2")
+		var lockoutErr clients.LockoutError
+		if !errors.As(err, &lockoutErr) || !errors.Is(err, clients.ErrLockedOut) {
+			t.Fatalf("Expected LockoutError, got %v", err)
+		}
+		if lockoutErr.ClientID != client.ID || !lockoutErr.Until.Equal(now.Add(time.Second)) {
+			t.Errorf("Expected lockout of %q until %s, got %+v", client.ID, now.Add(time.Second), lockoutErr)
+		}
+
+		// each further failure doubles the lockout, up to the maximum
+		for _, expected := range []time.Duration{2 * time.Second, 4 * time.Second, 4 * time.Second} {
+			now = now.Add(5 * time.Second)
+			err = authenticate("wrong secret", "192.0.2.1")
+			if !errors.Is(err, clients.ErrIncorrectSecret) {
+				t.Fatalf("Expected %v, got %v", clients.ErrIncorrectSecret, err)
+			}
+			err = authenticate("test secret", "192.0.2.2")
+			if !errors.As(err, &lockoutErr) {
+				t.Fatalf("Expected LockoutError, got %v", err)
+			}
+			if !lockoutErr.Until.Equal(now.Add(expected)) {
+				t.Errorf("Expected lockout for %s, got until %s", expected, lockoutErr.Until.Sub(now))
+			}
+		}
+
+		// once the lockout ends, the correct secret works and resets
+		// the client's failures
+		now = now.Add(5 * time.Second)
+		err = authenticate("test secret", "192.0.2.2")
+		if err != nil {
+			t.Fatalf("Unexpected error authenticating after lockout: %s", err)
+		}
+		err = authenticate("wrong secret", "192.0.2.3")
+		if !errors.Is(err, clients.ErrIncorrectSecret) {
+			t.Errorf("Expected failures to be reset, got %v", err)
+		}
+	})
+}
+
+func TestThrottledAuthenticatorLocksOutIPs(t *testing.T) {
+	t.Parallel()
+
+	runTest(t, func(t *testing.T, storer clients.Storer, ctx context.Context) {
+		now := time.Now()
+		authenticator := clients.ThrottledAuthenticator{
+			Authenticator: clients.Authenticator{Storer: storer},
+			Attempts:      &clients.MemoryAttemptStore{},
+			MaxIPFailures: 3,
+			Now:           func() time.Time { return now },
+		}
+		// guessing client IDs counts against the IP address
+		for i := 0; i < 3; i++ {
+			_, err := authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, uuidOrFail(t), "secret", "192.0.2.1")
+			if !errors.Is(err, clients.ErrClientNotFound) {
+				t.Fatalf("Expected %v, got %v", clients.ErrClientNotFound, err)
+			}
+		}
+		_, err := authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, uuidOrFail(t), "secret", "192.0.2.1")
+		var lockoutErr clients.LockoutError
+		if !errors.As(err, &lockoutErr) {
+			t.Fatalf("Expected LockoutError, got %v", err)
+		}
+		if lockoutErr.IP != "192.0.2.1" {
+			t.Errorf("Expected IP lockout, got %+v", lockoutErr)
+		}
+		_, err = authenticator.AuthenticateSecret(ctx, clients.AuthMethodClientSecretBasic, uuidOrFail(t), "secret", "192.0.2.2")
+		if !errors.Is(err, clients.ErrClientNotFound) {
+			t.Errorf("Expected other IPs not to be locked out, got %v", err)
+		}
+	})
+}